@@ -2,6 +2,7 @@ package build
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
@@ -16,7 +17,7 @@ import (
 
 // ImageComponent provides an interface for working with images
 type ImageComponent interface {
-	SquashImage(from string, to string) (string, error)
+	SquashImage(from string, to string, sourceDateEpoch *time.Time) (string, error)
 	TagImageWithReference(image.ID, string, reference.Named) error
 }
 
@@ -30,11 +31,12 @@ type Backend struct {
 	builder        Builder
 	fsCache        *fscache.FSCache
 	imageComponent ImageComponent
+	reports        *reportStore
 }
 
 // NewBackend creates a new build backend from components
 func NewBackend(components ImageComponent, builder Builder, fsCache *fscache.FSCache) (*Backend, error) {
-	return &Backend{imageComponent: components, builder: builder, fsCache: fsCache}, nil
+	return &Backend{imageComponent: components, builder: builder, fsCache: fsCache, reports: newReportStore()}, nil
 }
 
 // Build builds an image from a Source
@@ -44,23 +46,37 @@ func (b *Backend) Build(ctx context.Context, config backend.BuildConfig) (string
 	if err != nil {
 		return "", err
 	}
+	if options.CacheTo != "" {
+		if _, err := sanitizeRepoAndTags([]string{options.CacheTo}); err != nil {
+			return "", errors.Wrap(err, "invalid build cache reference")
+		}
+	}
 
 	build, err := b.builder.Build(ctx, config)
 	if err != nil {
 		return "", err
 	}
+	if options.Check {
+		return "", nil
+	}
 
 	var imageID = build.ImageID
 	if options.Squash {
-		if imageID, err = squashBuild(build, b.imageComponent); err != nil {
+		if imageID, err = squashBuild(build, b.imageComponent, options.SourceDateEpoch); err != nil {
 			return "", err
 		}
+		if build.Report != nil {
+			build.Report.ID = imageID
+		}
 		if config.ProgressWriter.AuxFormatter != nil {
-			if err = config.ProgressWriter.AuxFormatter.Emit(types.BuildResult{ID: imageID}); err != nil {
+			if err = config.ProgressWriter.AuxFormatter.Emit(types.BuildResult{ID: imageID, Report: build.Report}); err != nil {
 				return "", err
 			}
 		}
 	}
+	if build.Report != nil {
+		b.reports.add(build.Report)
+	}
 
 	stdout := config.ProgressWriter.StdoutFormatter
 	fmt.Fprintf(stdout, "Successfully built %s\n", stringid.TruncateID(imageID))
@@ -68,6 +84,22 @@ func (b *Backend) Build(ctx context.Context, config backend.BuildConfig) (string
 	return imageID, err
 }
 
+// BuildReport returns the report of the build of an image
+func (b *Backend) BuildReport(ctx context.Context, imageID string) (*types.BuildReport, error) {
+	return b.reports.get(imageID)
+}
+
+// PrepareContextUpload returns the files of a build context that must be
+// uploaded, the others being reused from the last context with the same
+// shared key
+func (b *Backend) PrepareContextUpload(ctx context.Context, manifest types.BuildContextManifest) (*types.BuildContextUpload, error) {
+	id, missing, err := b.fsCache.PrepareUpload(manifest.SharedKey, manifest.Hashes)
+	if err != nil {
+		return nil, err
+	}
+	return &types.BuildContextUpload{ID: id, Missing: missing}, nil
+}
+
 // PruneCache removes all cached build sources
 func (b *Backend) PruneCache(ctx context.Context) (*types.BuildCachePruneReport, error) {
 	size, err := b.fsCache.Prune(ctx)
@@ -77,12 +109,12 @@ func (b *Backend) PruneCache(ctx context.Context) (*types.BuildCachePruneReport,
 	return &types.BuildCachePruneReport{SpaceReclaimed: size}, nil
 }
 
-func squashBuild(build *builder.Result, imageComponent ImageComponent) (string, error) {
+func squashBuild(build *builder.Result, imageComponent ImageComponent, sourceDateEpoch *time.Time) (string, error) {
 	var fromID string
 	if build.FromImage != nil {
 		fromID = build.FromImage.ImageID()
 	}
-	imageID, err := imageComponent.SquashImage(build.ImageID, fromID)
+	imageID, err := imageComponent.SquashImage(build.ImageID, fromID, sourceDateEpoch)
 	if err != nil {
 		return "", errors.Wrap(err, "error squashing image")
 	}
@@ -0,0 +1,62 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+)
+
+// maxBuildReports is the number of build reports kept by the backend
+const maxBuildReports = 100
+
+// reportStore keeps the reports of the most recent builds in memory, by the
+// ID of the image they produced
+type reportStore struct {
+	mu      sync.Mutex
+	reports map[string]*types.BuildReport
+	// ids holds the image IDs of the reports in the order they were added
+	ids []string
+}
+
+func newReportStore() *reportStore {
+	return &reportStore{reports: make(map[string]*types.BuildReport)}
+}
+
+// add stores report, removing the oldest report if the store is full
+func (s *reportStore) add(report *types.BuildReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.reports[report.ID]; !ok {
+		if len(s.ids) == maxBuildReports {
+			delete(s.reports, s.ids[0])
+			s.ids = s.ids[1:]
+		}
+		s.ids = append(s.ids, report.ID)
+	}
+	s.reports[report.ID] = report
+}
+
+// get returns the report of the build of the image with imageID. The digest
+// algorithm of the ID may be omitted.
+func (s *reportStore) get(imageID string) (*types.BuildReport, error) {
+	if !strings.Contains(imageID, ":") {
+		imageID = "sha256:" + imageID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report, ok := s.reports[imageID]
+	if !ok {
+		return nil, reportNotFoundError(imageID)
+	}
+	return report, nil
+}
+
+type reportNotFoundError string
+
+func (e reportNotFoundError) Error() string {
+	return fmt.Sprintf("no build report for image %s", string(e))
+}
+
+func (e reportNotFoundError) NotFound() {}
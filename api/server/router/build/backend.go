@@ -12,6 +12,12 @@ type Backend interface {
 	// TODO: make this return a reference instead of string
 	Build(context.Context, backend.BuildConfig) (string, error)
 
+	// BuildReport returns the report of the build of an image
+	BuildReport(ctx context.Context, imageID string) (*types.BuildReport, error)
+
+	// PrepareContextUpload starts an incremental upload of a build context
+	PrepareContextUpload(ctx context.Context, manifest types.BuildContextManifest) (*types.BuildContextUpload, error)
+
 	// Prune build cache
 	PruneCache(context.Context) (*types.BuildCachePruneReport, error)
 }
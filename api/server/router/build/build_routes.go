@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/types"
@@ -154,7 +155,28 @@ func newImageBuildOptions(ctx context.Context, r *http.Request) (*types.ImageBui
 		}
 		options.CacheFrom = cacheFrom
 	}
+	options.CacheTo = r.FormValue("cacheto")
+	options.CacheToMode = r.FormValue("cachetomode")
+	switch options.CacheToMode {
+	case "", "min", "max":
+	default:
+		return nil, validationError{errors.Errorf("invalid cachetomode: %s", options.CacheToMode)}
+	}
 	options.SessionID = r.FormValue("session")
+	options.Check = httputils.BoolValue(r, "check")
+	options.ContextUpload = r.FormValue("contextupload")
+	if options.ContextUpload != "" && options.RemoteContext != "" {
+		return nil, validationError{errors.New("contextupload cannot be used with a remote context")}
+	}
+
+	if epoch := r.FormValue("sourcedateepoch"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil || seconds < 0 {
+			return nil, validationError{fmt.Errorf("invalid sourcedateepoch: %s", epoch)}
+		}
+		sourceDateEpoch := time.Unix(seconds, 0).UTC()
+		options.SourceDateEpoch = &sourceDateEpoch
+	}
 
 	return options, nil
 }
@@ -167,6 +189,29 @@ func (br *buildRouter) postPrune(ctx context.Context, w http.ResponseWriter, r *
 	return httputils.WriteJSON(w, http.StatusOK, report)
 }
 
+func (br *buildRouter) postBuildContext(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.CheckForJSON(r); err != nil {
+		return err
+	}
+	var manifest types.BuildContextManifest
+	if err := json.NewDecoder(r.Body).Decode(&manifest); err != nil {
+		return validationError{errors.Wrap(err, "invalid context manifest")}
+	}
+	upload, err := br.backend.PrepareContextUpload(ctx, manifest)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, upload)
+}
+
+func (br *buildRouter) getBuildReport(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	report, err := br.backend.BuildReport(ctx, vars["id"])
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
 type validationError struct {
 	cause error
 }
@@ -238,7 +283,7 @@ func (br *buildRouter) postBuild(ctx context.Context, w http.ResponseWriter, r *
 
 	// Everything worked so if -q was provided the output from the daemon
 	// should be just the image ID and we'll print that to stdout.
-	if buildOptions.SuppressOutput {
+	if buildOptions.SuppressOutput && imgID != "" {
 		fmt.Fprintln(streamformatter.NewStdoutWriter(output), imgID)
 	}
 	return nil
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
@@ -192,12 +193,19 @@ func (s *imageRouter) postImagesPush(ctx context.Context, w http.ResponseWriter,
 	image := vars["name"]
 	tag := r.Form.Get("tag")
 
+	format := r.Form.Get("format")
+	switch format {
+	case "", types.ImagePushFormatDocker, types.ImagePushFormatOCI:
+	default:
+		return validationError{errors.Errorf("invalid format %q: must be %q or %q", format, types.ImagePushFormatDocker, types.ImagePushFormatOCI)}
+	}
+
 	output := ioutils.NewWriteFlusher(w)
 	defer output.Close()
 
 	w.Header().Set("Content-Type", "application/json")
 
-	if err := s.backend.PushImage(ctx, image, tag, metaHeaders, authConfig, output); err != nil {
+	if err := s.backend.PushImage(ctx, image, tag, format, metaHeaders, authConfig, output); err != nil {
 		if !output.Flushed() {
 			return err
 		}
@@ -211,6 +219,13 @@ func (s *imageRouter) getImagesGet(ctx context.Context, w http.ResponseWriter, r
 		return err
 	}
 
+	format := r.Form.Get("format")
+	switch format {
+	case "", types.ImageSaveFormatDocker, types.ImageSaveFormatOCI:
+	default:
+		return validationError{errors.Errorf("invalid format %q: must be %q or %q", format, types.ImageSaveFormatDocker, types.ImageSaveFormatOCI)}
+	}
+
 	w.Header().Set("Content-Type", "application/x-tar")
 
 	output := ioutils.NewWriteFlusher(w)
@@ -222,7 +237,7 @@ func (s *imageRouter) getImagesGet(ctx context.Context, w http.ResponseWriter, r
 		names = r.Form["names"]
 	}
 
-	if err := s.backend.ExportImage(names, output); err != nil {
+	if err := s.backend.ExportImage(names, format, output); err != nil {
 		if !output.Flushed() {
 			return err
 		}
@@ -236,12 +251,22 @@ func (s *imageRouter) postImagesLoad(ctx context.Context, w http.ResponseWriter,
 		return err
 	}
 	quiet := httputils.BoolValueOrDefault(r, "quiet", true)
+	repository := r.Form.Get("repository")
+	if repository != "" {
+		ref, err := reference.ParseNormalizedNamed(repository)
+		if err != nil {
+			return validationError{err}
+		}
+		if !reference.IsNameOnly(ref) {
+			return validationError{errors.Errorf("invalid repository %s: must not have a tag or digest", repository)}
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 
 	output := ioutils.NewWriteFlusher(w)
 	defer output.Close()
-	if err := s.backend.LoadImage(r.Body, output, quiet); err != nil {
+	if err := s.backend.LoadImage(r.Body, output, repository, quiet); err != nil {
 		output.Write(streamformatter.FormatError(err))
 	}
 	return nil
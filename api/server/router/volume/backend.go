@@ -1,6 +1,8 @@
 package volume
 
 import (
+	"io"
+
 	"golang.org/x/net/context"
 
 	// TODO return types need to be refactored into pkg
@@ -15,5 +17,8 @@ type Backend interface {
 	VolumeInspect(name string) (*types.Volume, error)
 	VolumeCreate(name, driverName string, opts, labels map[string]string) (*types.Volume, error)
 	VolumeRm(name string, force bool) error
+	VolumeUpdate(name string, opts, labels map[string]string) (*types.Volume, error)
+	VolumeExport(name string, out io.Writer) error
+	VolumeImport(name string, noOverwriteDirNonDir bool, content io.Reader) error
 	VolumesPrune(ctx context.Context, pruneFilters filters.Args) (*types.VolumesPruneReport, error)
 }
@@ -26,10 +26,15 @@ func (r *volumeRouter) initRoutes() {
 	r.routes = []router.Route{
 		// GET
 		router.NewGetRoute("/volumes", r.getVolumesList),
+		// the export route must be registered before the catch-all inspect route
+		router.NewGetRoute("/volumes/{name:.*}/export", r.getVolumesExport),
 		router.NewGetRoute("/volumes/{name:.*}", r.getVolumeByName),
 		// POST
 		router.NewPostRoute("/volumes/create", r.postVolumesCreate),
 		router.NewPostRoute("/volumes/prune", r.postVolumesPrune, router.WithCancel),
+		router.NewPostRoute("/volumes/{name:.*}/update", r.postVolumesUpdate),
+		// PUT
+		router.NewPutRoute("/volumes/{name:.*}/import", r.putVolumesImport),
 		// DELETE
 		router.NewDeleteRoute("/volumes/{name:.*}", r.deleteVolumes),
 	}
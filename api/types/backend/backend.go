@@ -103,4 +103,8 @@ type ContainerCommitConfig struct {
 	// TODO: ContainerConfig is only used by the dockerfile Builder, so remove it
 	// once the Builder has been updated to use a different interface
 	ContainerConfig *container.Config
+	// SourceDateEpoch is set to create a reproducible image. It is used as
+	// the creation time of the image and the file times later than it in
+	// the layer are set to it.
+	SourceDateEpoch *time.Time
 }
@@ -42,3 +42,11 @@ type GetImageAndLayerOptions struct {
 	Output     io.Writer
 	OS         string
 }
+
+// BuildCacheOptions are the options supported by ImportBuildCache and
+// ExportBuildCache
+type BuildCacheOptions struct {
+	AuthConfig map[string]types.AuthConfig
+	Output     io.Writer
+	OS         string
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"io"
 	"net"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
@@ -74,6 +75,7 @@ type ContainerLogsOptions struct {
 	ShowStdout bool
 	ShowStderr bool
 	Since      string
+	Until      string
 	Timestamps bool
 	Follow     bool
 	Tail       string
@@ -100,6 +102,12 @@ type CopyToContainerOptions struct {
 	CopyUIDGID                bool
 }
 
+// VolumeImportOptions holds information about content imported into a
+// volume
+type VolumeImportOptions struct {
+	AllowOverwriteDirWithFile bool
+}
+
 // EventsOptions holds parameters to filter events with.
 type EventsOptions struct {
 	Since   string
@@ -174,12 +182,30 @@ type ImageBuildOptions struct {
 	Squash bool
 	// CacheFrom specifies images that are used for matching cache. Images
 	// specified here do not need to have a valid parent chain to match cache.
-	CacheFrom   []string
+	// References that are not available locally are imported as build caches
+	// from a registry.
+	CacheFrom []string
+	// CacheTo specifies a registry reference the build cache is exported to
+	// after a successful build.
+	CacheTo string
+	// CacheToMode selects the stages exported to CacheTo: "min" (the
+	// default) only exports the target stage, "max" exports all the stages.
+	CacheToMode string
 	SecurityOpt []string
 	ExtraHosts  []string // List of extra hosts
 	Target      string
 	SessionID   string
 	Platform    string
+	// SourceDateEpoch is used as the creation time of the images created by
+	// the build, and the file times later than it in their layers are set
+	// to it, so that identical inputs produce identical images.
+	SourceDateEpoch *time.Time
+	// Check only validates the Dockerfile and reports the problems found,
+	// without pulling images or running instructions.
+	Check bool
+	// ContextUpload is the ID of an incremental context upload. The context
+	// sent with the build then only contains the files missing on the daemon.
+	ContextUpload string
 }
 
 // ImageBuildResponse holds information
@@ -216,6 +242,14 @@ type ImageListOptions struct {
 	Filters filters.Args
 }
 
+// ImageLoadOptions holds parameters to load images.
+type ImageLoadOptions struct {
+	Quiet bool
+	// Repository is the repository of the images of an OCI image layout
+	// whose ref names are only a tag
+	Repository string
+}
+
 // ImageLoadResponse returns information to the client about a load process.
 type ImageLoadResponse struct {
 	// Body must be closed to avoid a resource leak
@@ -223,6 +257,19 @@ type ImageLoadResponse struct {
 	JSON bool
 }
 
+// Formats of the archives of saved images
+const (
+	ImageSaveFormatDocker = "docker"
+	ImageSaveFormatOCI    = "oci"
+)
+
+// ImageSaveOptions holds parameters to save images.
+type ImageSaveOptions struct {
+	// Format is the format of the archive, ImageSaveFormatDocker (the
+	// default) or ImageSaveFormatOCI for an OCI image layout
+	Format string
+}
+
 // ImagePullOptions holds information to pull images.
 type ImagePullOptions struct {
 	All           bool
@@ -239,8 +286,22 @@ type ImagePullOptions struct {
 // if the privilege request fails.
 type RequestPrivilegeFunc func() (string, error)
 
+// Formats of the manifests of pushed images
+const (
+	ImagePushFormatDocker = "docker"
+	ImagePushFormatOCI    = "oci"
+)
+
 //ImagePushOptions holds information to push images.
-type ImagePushOptions ImagePullOptions
+type ImagePushOptions struct {
+	All           bool
+	RegistryAuth  string // RegistryAuth is the base64 encoded credentials for the registry
+	PrivilegeFunc RequestPrivilegeFunc
+	Platform      string
+	// Format is the format of the manifests pushed, ImagePushFormatDocker
+	// (the default) or ImagePushFormatOCI for OCI image manifests
+	Format string
+}
 
 // ImageRemoveOptions holds parameters to remove images.
 type ImageRemoveOptions struct {
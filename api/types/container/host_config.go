@@ -261,6 +261,14 @@ func (rp *RestartPolicy) IsUnlessStopped() bool {
 	return rp.Name == "unless-stopped"
 }
 
+// IsOnUnhealthy indicates whether the container has the "on-unhealthy"
+// restart policy. This means the container will automatically restart if
+// exiting with a non-zero exit status, or once its healthcheck reports it
+// as unhealthy.
+func (rp *RestartPolicy) IsOnUnhealthy() bool {
+	return rp.Name == "on-unhealthy"
+}
+
 // IsSame compares two RestartPolicy to see if they are the same
 func (rp *RestartPolicy) IsSame(tp *RestartPolicy) bool {
 	return rp.Name == tp.Name && rp.MaximumRetryCount == tp.MaximumRetryCount
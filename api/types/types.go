@@ -530,6 +530,26 @@ type BuildCachePruneReport struct {
 	SpaceReclaimed uint64
 }
 
+// BuildContextManifest contains the request for Engine API:
+// POST "/build/context"
+type BuildContextManifest struct {
+	// SharedKey identifies the contexts uploaded from the same source. The
+	// files of the last context uploaded with the same key are reused.
+	SharedKey string
+	// Hashes are the tarsum hashes of the files of the context, keyed by
+	// their path relative to the root of the context
+	Hashes map[string]string
+}
+
+// BuildContextUpload contains the response for Engine API:
+// POST "/build/context"
+type BuildContextUpload struct {
+	// ID of the upload, passed to the build that sends the missing files
+	ID string
+	// Missing are the paths of the files the daemon doesn't hold
+	Missing []string
+}
+
 // NetworksPruneReport contains the response for Engine API:
 // POST "/networks/prune"
 type NetworksPruneReport struct {
@@ -569,7 +589,68 @@ type PushResult struct {
 	Size   int
 }
 
-// BuildResult contains the image id of a successful build
+// BuildResult contains the image id of a successful build. The result of
+// the final image also contains the report of the build. A build in check
+// mode only returns the result of the check.
 type BuildResult struct {
-	ID string
+	ID     string
+	Report *BuildReport      `json:",omitempty"`
+	Check  *BuildCheckReport `json:",omitempty"`
+}
+
+// Levels of the messages of a BuildCheckReport
+const (
+	BuildCheckError   = "error"
+	BuildCheckWarning = "warning"
+)
+
+// BuildCheckReport contains the problems found in a Dockerfile by a build in
+// check mode
+type BuildCheckReport struct {
+	Messages []BuildCheckMessage
+}
+
+// BuildCheckMessage is a problem found in a Dockerfile
+type BuildCheckMessage struct {
+	Level string
+	// Line is the line of the instruction in the Dockerfile, or 0 if the
+	// message is not about an instruction
+	Line    int `json:",omitempty"`
+	Message string
+}
+
+// Cache statuses of the instructions of a build
+const (
+	BuildCacheHit  = "hit"
+	BuildCacheMiss = "miss"
+)
+
+// BuildReport contains the timings and cache usage of the stages of a build
+type BuildReport struct {
+	ID       string
+	Duration time.Duration
+	Stages   []BuildStageReport
+}
+
+// BuildStageReport contains the timings and cache usage of the instructions
+// of a build stage
+type BuildStageReport struct {
+	Index     int
+	Name      string `json:",omitempty"`
+	BaseImage string `json:",omitempty"`
+	Duration  time.Duration
+	Steps     []BuildStepReport
+}
+
+// BuildStepReport contains the timing and cache usage of an instruction of a
+// build stage, and the layer it produced, if any
+type BuildStepReport struct {
+	Instruction string
+	// Cache is BuildCacheHit or BuildCacheMiss, or empty if the build cache
+	// is not used by the instruction
+	Cache       string `json:",omitempty"`
+	Duration    time.Duration
+	ImageID     string `json:",omitempty"`
+	LayerDigest string `json:",omitempty"`
+	LayerSize   int64  `json:",omitempty"`
 }
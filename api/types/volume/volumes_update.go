@@ -0,0 +1,19 @@
+package volume
+
+// ----------------------------------------------------------------------------
+// DO NOT EDIT THIS FILE
+// This file was generated by `swagger generate operation`
+//
+// See hack/generate-swagger-api.sh
+// ----------------------------------------------------------------------------
+
+// VolumesUpdateBody volumes update body
+// swagger:model VolumesUpdateBody
+type VolumesUpdateBody struct {
+
+	// A mapping of driver options and values. These options are passed directly to the driver and are driver specific.
+	DriverOpts map[string]string `json:"DriverOpts,omitempty"`
+
+	// User-defined key/value metadata.
+	Labels map[string]string `json:"Labels,omitempty"`
+}
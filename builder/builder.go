@@ -6,6 +6,7 @@ package builder
 
 import (
 	"io"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
@@ -43,10 +44,18 @@ type Backend interface {
 	Commit(string, *backend.ContainerCommitConfig) (string, error)
 	// ContainerCreateWorkdir creates the workdir
 	ContainerCreateWorkdir(containerID string) error
+	// ContainerRemoveMountpoints removes the empty mountpoints created for
+	// the given targets, so that they are not committed
+	ContainerRemoveMountpoints(containerID string, targets []string) error
 
 	CreateImage(config []byte, parent string, platform string) (Image, error)
+	// ImageTopLayer returns the diff ID and size of the layer added by the
+	// last history entry of an image. The diff ID is empty if the entry did
+	// not add a layer.
+	ImageTopLayer(imageID string) (layer.DiffID, int64, error)
 
 	ImageCacheBuilder
+	BuildCacheBackend
 }
 
 // ImageBackend are the interface methods required from an image component
@@ -74,6 +83,8 @@ type ExecBackend interface {
 type Result struct {
 	ImageID   string
 	FromImage Image
+	Report    *types.BuildReport
+	Check     *types.BuildCheckReport
 }
 
 // ImageCacheBuilder represents a generator for stateful image cache.
@@ -82,6 +93,18 @@ type ImageCacheBuilder interface {
 	MakeImageCache(cacheFrom []string, platform string) ImageCache
 }
 
+// BuildCacheBackend imports and exports build caches from and to a registry.
+type BuildCacheBackend interface {
+	// ImportBuildCache returns an image cache builder that also matches the
+	// build caches in the registry for the references in cacheFrom that are
+	// not available locally. Only the cache metadata is pulled, layers are
+	// pulled on a cache hit.
+	ImportBuildCache(ctx context.Context, cacheFrom []string, opts backend.BuildCacheOptions) ImageCacheBuilder
+	// ExportBuildCache pushes the configs and layers of the images with the
+	// given IDs as a build cache to ref.
+	ExportBuildCache(ctx context.Context, ref string, imageIDs []string, opts backend.BuildCacheOptions) error
+}
+
 // ImageCache abstracts an image cache.
 // (parent image, child runconfig) -> child image
 type ImageCache interface {
@@ -102,6 +125,8 @@ type Image interface {
 type ReleaseableLayer interface {
 	Release() error
 	Mount() (containerfs.ContainerFS, error)
-	Commit(platform string) (ReleaseableLayer, error)
+	// Commit registers the changes of the mounted layer as a new layer. If
+	// sourceDateEpoch is set, the file times later than it are set to it.
+	Commit(platform string, sourceDateEpoch *time.Time) (ReleaseableLayer, error)
 	DiffID() layer.DiffID
 }
@@ -17,9 +17,10 @@ import (
 	"github.com/docker/docker/builder/dockerfile/parser"
 	"github.com/docker/docker/builder/fscache"
 	"github.com/docker/docker/builder/remotecontext"
+	"github.com/docker/docker/builder/remotecontext/git"
+	"github.com/docker/docker/builder/secrets"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/streamformatter"
-	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/pkg/system"
 	"github.com/moby/buildkit/session"
 	"github.com/pkg/errors"
@@ -43,6 +44,10 @@ var validCommitCommands = map[string]bool{
 
 const (
 	stepFormat = "Step %d/%d : %v"
+
+	// gitAuthTokenSecret is the id of the secret of the build session that
+	// holds the token to authenticate to the git server of a git context
+	gitAuthTokenSecret = "GIT_AUTH_TOKEN"
 )
 
 // SessionGetter is object used to get access to a session by uuid
@@ -81,7 +86,23 @@ func (bm *BuildManager) Build(ctx context.Context, config backend.BuildConfig) (
 		config.Options.Dockerfile = builder.DefaultDockerfileName
 	}
 
-	source, dockerfile, err := remotecontext.Detect(config)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		source     builder.Source
+		dockerfile *parser.Result
+		err        error
+	)
+	switch {
+	case config.Options.ContextUpload != "":
+		source, dockerfile, err = bm.contextFromUpload(config)
+	case config.Options.Check:
+		// checking the Dockerfile does not need the rest of the context
+		dockerfile, err = remotecontext.DetectDockerfile(config, bm.gitAuthFromSession(ctx, config.Options))
+	default:
+		source, dockerfile, err = remotecontext.Detect(config, bm.gitAuthFromSession(ctx, config.Options))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -93,13 +114,12 @@ func (bm *BuildManager) Build(ctx context.Context, config backend.BuildConfig) (
 		}
 	}()
 
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	if src, err := bm.initializeClientSession(ctx, cancel, config.Options); err != nil {
-		return nil, err
-	} else if src != nil {
-		source = src
+	if !config.Options.Check {
+		if src, err := bm.initializeClientSession(ctx, cancel, config.Options); err != nil {
+			return nil, err
+		} else if src != nil {
+			source = src
+		}
 	}
 
 	os := runtime.GOOS
@@ -121,10 +141,60 @@ func (bm *BuildManager) Build(ctx context.Context, config backend.BuildConfig) (
 		Backend:        bm.backend,
 		PathCache:      bm.pathCache,
 		IDMappings:     bm.idMappings,
+		SessionGetter:  bm.sg,
+		FSCache:        bm.fsCache,
 	}
 	return newBuilder(ctx, builderOptions, os).build(source, dockerfile)
 }
 
+// gitAuthFromSession returns a function that requests the token used to
+// authenticate to a git server from the secrets of the build session. The
+// token for a host is the GIT_AUTH_TOKEN.<host> secret, or the
+// GIT_AUTH_TOKEN secret for all hosts.
+func (bm *BuildManager) gitAuthFromSession(ctx context.Context, options *types.ImageBuildOptions) git.AuthFunc {
+	if options.SessionID == "" || bm.sg == nil {
+		return nil
+	}
+	return func(host string) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, sessionConnectTimeout)
+		defer cancel()
+
+		c, err := bm.sg.Get(ctx, options.SessionID)
+		if err != nil {
+			return "", err
+		}
+		if !secrets.Supported(c) {
+			return "", nil
+		}
+		for _, id := range []string{gitAuthTokenSecret + "." + host, gitAuthTokenSecret} {
+			token, err := secrets.GetSecret(ctx, c, id)
+			if err == nil {
+				return string(token), nil
+			}
+			if errors.Cause(err) != secrets.ErrNotFound {
+				return "", err
+			}
+		}
+		return "", nil
+	}
+}
+
+// contextFromUpload returns the context of an incremental context upload,
+// whose missing files are the source of the build
+func (bm *BuildManager) contextFromUpload(config backend.BuildConfig) (builder.Source, *parser.Result, error) {
+	defer config.Source.Close()
+	source, err := bm.fsCache.CompleteUpload(config.Options.ContextUpload, config.Source)
+	if err != nil {
+		return nil, nil, err
+	}
+	dockerfile, err := remotecontext.DockerfileFromSource(source, config.Options.Dockerfile)
+	if err != nil {
+		source.Close()
+		return nil, nil, err
+	}
+	return source, dockerfile, nil
+}
+
 func (bm *BuildManager) initializeClientSession(ctx context.Context, cancel func(), options *types.ImageBuildOptions) (builder.Source, error) {
 	if options.SessionID == "" || bm.sg == nil {
 		return nil, nil
@@ -165,6 +235,8 @@ type builderOptions struct {
 	ProgressWriter backend.ProgressWriter
 	PathCache      pathCache
 	IDMappings     *idtools.IDMappings
+	SessionGetter  SessionGetter
+	FSCache        *fscache.FSCache
 }
 
 // Builder is a Dockerfile builder
@@ -186,6 +258,11 @@ type Builder struct {
 	pathCache        pathCache
 	containerManager *containerManager
 	imageProber      ImageProber
+	cacheBuilder     builder.ImageCacheBuilder
+	cacheStatus      string
+	sessionGetter    SessionGetter
+	fsCache          *fscache.FSCache
+	os               string
 }
 
 // newBuilder creates a new Dockerfile builder from an optional dockerfile and a Options.
@@ -207,7 +284,11 @@ func newBuilder(clientCtx context.Context, options builderOptions, os string) *B
 		imageSources:     newImageSources(clientCtx, options),
 		pathCache:        options.PathCache,
 		imageProber:      newImageProber(options.Backend, config.CacheFrom, os, config.NoCache),
+		cacheBuilder:     options.Backend,
 		containerManager: newContainerManager(options.Backend),
+		sessionGetter:    options.SessionGetter,
+		fsCache:          options.FSCache,
+		os:               os,
 	}
 
 	return b
@@ -218,6 +299,9 @@ func newBuilder(clientCtx context.Context, options builderOptions, os string) *B
 func (b *Builder) build(source builder.Source, dockerfile *parser.Result) (*builder.Result, error) {
 	defer b.imageSources.Unmount()
 
+	if b.options.Check {
+		return b.check(dockerfile)
+	}
 	addNodesForLabelOption(dockerfile.AST, b.options.Labels)
 
 	stages, metaArgs, err := instructions.Parse(dockerfile.AST)
@@ -237,7 +321,11 @@ func (b *Builder) build(source builder.Source, dockerfile *parser.Result) (*buil
 	}
 
 	dockerfile.PrintWarnings(b.Stderr)
-	dispatchState, err := b.dispatchDockerfileWithCancellation(stages, metaArgs, dockerfile.EscapeToken, source)
+	if len(b.options.CacheFrom) > 0 && !b.options.NoCache {
+		b.cacheBuilder = b.docker.ImportBuildCache(b.clientCtx, b.options.CacheFrom, b.buildCacheOptions())
+		b.imageProber = newImageProber(b.cacheBuilder, b.options.CacheFrom, b.os, b.options.NoCache)
+	}
+	dispatchState, report, err := b.dispatchDockerfileWithCancellation(stages, metaArgs, dockerfile.EscapeToken, source)
 	if err != nil {
 		return nil, err
 	}
@@ -245,14 +333,14 @@ func (b *Builder) build(source builder.Source, dockerfile *parser.Result) (*buil
 		buildsFailed.WithValues(metricsDockerfileEmptyError).Inc()
 		return nil, errors.New("No image was generated. Is your Dockerfile empty?")
 	}
-	return &builder.Result{ImageID: dispatchState.imageID, FromImage: dispatchState.baseImage}, nil
+	return &builder.Result{ImageID: dispatchState.imageID, FromImage: dispatchState.baseImage, Report: report}, nil
 }
 
-func emitImageID(aux *streamformatter.AuxFormatter, state *dispatchState) error {
+func emitImageID(aux *streamformatter.AuxFormatter, state *dispatchState, report *types.BuildReport) error {
 	if aux == nil || state.imageID == "" {
 		return nil
 	}
-	return aux.Emit(types.BuildResult{ID: state.imageID})
+	return aux.Emit(types.BuildResult{ID: state.imageID, Report: report})
 }
 
 func processMetaArg(meta instructions.ArgCommand, shlex *ShellLex, args *buildArgs) error {
@@ -274,8 +362,7 @@ func printCommand(out io.Writer, currentCommandIndex int, totalCommands int, cmd
 	return currentCommandIndex + 1
 }
 
-func (b *Builder) dispatchDockerfileWithCancellation(parseResult []instructions.Stage, metaArgs []instructions.ArgCommand, escapeToken rune, source builder.Source) (*dispatchState, error) {
-	dispatchRequest := dispatchRequest{}
+func (b *Builder) dispatchDockerfileWithCancellation(parseResult []instructions.Stage, metaArgs []instructions.ArgCommand, escapeToken rune, source builder.Source) (*dispatchState, *types.BuildReport, error) {
 	buildArgs := newBuildArgs(b.options.BuildArgs)
 	totalCommands := len(metaArgs) + len(parseResult)
 	currentCommandIndex := 1
@@ -288,54 +375,79 @@ func (b *Builder) dispatchDockerfileWithCancellation(parseResult []instructions.
 
 		err := processMetaArg(meta, shlex, buildArgs)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
-	stagesResults := newStagesBuildResults()
+	graph, err := newStageGraph(parseResult, shlex, buildArgs, b.options.Target != "")
+	if err != nil {
+		return nil, nil, err
+	}
+	// steps keep the numbers of their position in the Dockerfile, even if
+	// stages are skipped or built concurrently
+	firstSteps := make([]int, len(parseResult))
+	for i, stage := range parseResult {
+		firstSteps[i] = currentCommandIndex
+		currentCommandIndex += len(stage.Commands) + 1
+	}
 
-	for _, stage := range parseResult {
-		if err := stagesResults.checkStageNameAvailable(stage.Name); err != nil {
-			return nil, err
+	run := &stageRun{
+		builder:       b,
+		graph:         graph,
+		escapeToken:   escapeToken,
+		source:        source,
+		firstSteps:    firstSteps,
+		totalCommands: totalCommands,
+		buildArgs:     buildArgs,
+	}
+	state, report, err := b.dispatchStages(run)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b.options.CacheTo != "" {
+		states := []*dispatchState{state}
+		if b.options.CacheToMode == "max" {
+			states = run.states
 		}
-		dispatchRequest = newDispatchRequest(b, escapeToken, source, buildArgs, stagesResults)
-
-		currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, stage.SourceCode)
-		if err := initializeStage(dispatchRequest, &stage); err != nil {
-			return nil, err
+		if err := b.exportBuildCache(states); err != nil {
+			return nil, nil, err
 		}
-		dispatchRequest.state.updateRunConfig()
-		fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
-		for _, cmd := range stage.Commands {
-			select {
-			case <-b.clientCtx.Done():
-				logrus.Debug("Builder: build cancelled!")
-				fmt.Fprint(b.Stdout, "Build cancelled\n")
-				buildsFailed.WithValues(metricsBuildCanceled).Inc()
-				return nil, errors.New("Build cancelled")
-			default:
-				// Not cancelled yet, keep going...
-			}
-
-			currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, cmd)
+	}
+	buildArgs.WarnOnUnusedBuildArgs(b.Stdout)
+	return state, report, nil
+}
 
-			if err := dispatch(dispatchRequest, cmd); err != nil {
-				return nil, err
-			}
-			dispatchRequest.state.updateRunConfig()
-			fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
+func (b *Builder) buildCacheOptions() backend.BuildCacheOptions {
+	return backend.BuildCacheOptions{
+		AuthConfig: b.options.AuthConfigs,
+		Output:     b.Output,
+		OS:         b.os,
+	}
+}
 
+// exportBuildCache pushes the images of the given stages to the registry
+// reference set by the cacheto option
+func (b *Builder) exportBuildCache(states []*dispatchState) error {
+	var imageIDs []string
+	seen := make(map[string]struct{})
+	for _, state := range states {
+		if state == nil || state.imageID == "" {
+			continue
 		}
-		if err := emitImageID(b.Aux, dispatchRequest.state); err != nil {
-			return nil, err
-		}
-		buildArgs.MergeReferencedArgs(dispatchRequest.state.buildArgs)
-		if err := commitStage(dispatchRequest.state, stagesResults); err != nil {
-			return nil, err
+		if _, ok := seen[state.imageID]; ok {
+			continue
 		}
+		seen[state.imageID] = struct{}{}
+		imageIDs = append(imageIDs, state.imageID)
 	}
-	buildArgs.WarnOnUnusedBuildArgs(b.Stdout)
-	return dispatchRequest.state, nil
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	fmt.Fprintf(b.Stdout, "Exporting build cache to %s\n", b.options.CacheTo)
+	if err := b.docker.ExportBuildCache(b.clientCtx, b.options.CacheTo, imageIDs, b.buildCacheOptions()); err != nil {
+		return errors.Wrap(err, "failed to export build cache")
+	}
+	return nil
 }
 
 func addNodesForLabelOption(dockerfile *parser.Node, labels map[string]string) {
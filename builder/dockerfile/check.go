@@ -0,0 +1,277 @@
+package dockerfile
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/command"
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/docker/docker/pkg/system"
+	"github.com/pkg/errors"
+)
+
+// check validates the Dockerfile of a build in check mode and reports the
+// problems found, without pulling base images or running any instruction
+func (b *Builder) check(dockerfile *parser.Result) (*builder.Result, error) {
+	optionsOS := system.ParsePlatform(b.options.Platform).OS
+	report := checkDockerfile(dockerfile, b.options, optionsOS)
+
+	errCount := 0
+	for _, msg := range report.Messages {
+		if msg.Level == types.BuildCheckError {
+			errCount++
+		}
+		if msg.Line > 0 {
+			fmt.Fprintf(b.Stdout, "Line %d: %s: %s\n", msg.Line, msg.Level, msg.Message)
+		} else {
+			fmt.Fprintf(b.Stdout, "%s: %s\n", msg.Level, msg.Message)
+		}
+	}
+	if b.Aux != nil {
+		if err := b.Aux.Emit(types.BuildResult{Check: report}); err != nil {
+			return nil, err
+		}
+	}
+	if errCount > 0 {
+		return nil, validationError{errors.Errorf("Dockerfile check failed with %d error(s)", errCount)}
+	}
+	return &builder.Result{Check: report}, nil
+}
+
+// checkState is the state of the stage being checked
+type checkState struct {
+	index int
+	env   []string
+	args  *buildArgs
+	// lines of the CMD, ENTRYPOINT and HEALTHCHECK instructions of the stage
+	overrides map[string]int
+}
+
+type dockerfileChecker struct {
+	report types.BuildCheckReport
+}
+
+func (c *dockerfileChecker) add(level string, line int, format string, args ...interface{}) {
+	c.report.Messages = append(c.report.Messages, types.BuildCheckMessage{
+		Level:   level,
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *dockerfileChecker) errorf(line int, format string, args ...interface{}) {
+	c.add(types.BuildCheckError, line, format, args...)
+}
+
+func (c *dockerfileChecker) warnf(line int, format string, args ...interface{}) {
+	c.add(types.BuildCheckWarning, line, format, args...)
+}
+
+// checkDockerfile parses the instructions of dockerfile, expands their
+// variables and validates the stage references, flags and platform of the
+// instructions. All the problems found are returned, not only the first one.
+func checkDockerfile(dockerfile *parser.Result, options *types.ImageBuildOptions, optionsOS string) *types.BuildCheckReport {
+	c := &dockerfileChecker{}
+	for _, warning := range dockerfile.Warnings {
+		c.warnf(0, "%s", strings.TrimPrefix(warning, "[WARNING]: "))
+	}
+
+	// Parse all the instructions first, so that references to later stages
+	// can be told apart from image names
+	nodes := dockerfile.AST.Children
+	cmds := make([]interface{}, len(nodes))
+	stageIndex := make(map[string]int)
+	stageCount := 0
+	for i, node := range nodes {
+		cmd, err := instructions.ParseInstruction(node)
+		if err != nil {
+			c.errorf(node.StartLine, "%v", err)
+			if node.Value == command.From {
+				stageCount++
+			}
+			continue
+		}
+		cmds[i] = cmd
+		if stage, ok := cmd.(*instructions.Stage); ok {
+			if stage.Name != "" {
+				if _, ok := stageIndex[stage.Name]; ok {
+					c.errorf(node.StartLine, "%s stage name already used", stage.Name)
+				} else {
+					stageIndex[stage.Name] = stageCount
+				}
+			}
+			stageCount++
+		}
+	}
+
+	shlex := NewShellLex(dockerfile.EscapeToken)
+	metaArgs := newBuildArgs(options.BuildArgs)
+	referencedArgs := newBuildArgs(options.BuildArgs)
+	var state *checkState
+	stages := 0
+	for i, node := range nodes {
+		line := node.StartLine
+		if node.Value == command.From {
+			state = &checkState{
+				index:     stages,
+				args:      metaArgs.Clone(),
+				overrides: make(map[string]int),
+			}
+			state.args.ResetAllowed()
+			stages++
+		}
+		switch cmd := cmds[i].(type) {
+		case nil:
+			continue
+		case *instructions.Stage:
+			c.checkFrom(line, cmd, state.index, shlex, metaArgs, stageIndex, optionsOS)
+			continue
+		case *instructions.ArgCommand:
+			if state == nil {
+				if err := processMetaArg(*cmd, shlex, metaArgs); err != nil {
+					c.errorf(line, "%v", err)
+				}
+				continue
+			}
+		}
+		if state == nil {
+			c.errorf(line, "No build stage in current context")
+			continue
+		}
+		c.checkCommand(line, cmds[i], state, shlex, stageIndex, optionsOS)
+		referencedArgs.MergeReferencedArgs(state.args)
+	}
+	referencedArgs.MergeReferencedArgs(metaArgs)
+
+	if stageCount == 0 {
+		c.errorf(0, "No build stage found. A Dockerfile must start with a FROM instruction")
+	}
+	if options.Target != "" {
+		if _, ok := stageIndex[options.Target]; !ok {
+			c.errorf(0, "failed to reach build target %s in Dockerfile", options.Target)
+		}
+	}
+	var unused []string
+	for arg := range options.BuildArgs {
+		_, isReferenced := referencedArgs.referencedArgs[arg]
+		if !isReferenced && !builtinAllowedBuildArgs[arg] {
+			unused = append(unused, arg)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		c.warnf(0, "One or more build-args %v were not consumed", unused)
+	}
+	return &c.report
+}
+
+// checkFrom validates the base image or stage of the stage at index
+func (c *dockerfileChecker) checkFrom(line int, stage *instructions.Stage, index int, shlex *ShellLex, metaArgs *buildArgs, stageIndex map[string]int, optionsOS string) {
+	name, err := shlex.ProcessWord(stage.BaseName, convertMapToEnvList(metaArgs.GetAllMeta()))
+	if err != nil {
+		c.errorf(line, "%v", err)
+		return
+	}
+	if name == "" {
+		c.errorf(line, "base name (%s) should not be blank", stage.BaseName)
+		return
+	}
+	if ix, ok := stageIndex[strings.ToLower(name)]; ok {
+		if ix < index {
+			return
+		}
+		c.warnf(line, "%s is not a previous stage and is used as an image name", name)
+	}
+	if name == api.NoBaseImageSpecifier {
+		if optionsOS == "windows" {
+			c.errorf(line, "Windows does not support FROM scratch")
+		}
+		return
+	}
+	if _, err := reference.ParseNormalizedNamed(name); err != nil {
+		c.errorf(line, "invalid base image %s: %v", name, err)
+	}
+}
+
+// checkCommand expands the variables of a command of a stage and validates
+// it, updating the environment and build args of the stage
+func (c *dockerfileChecker) checkCommand(line int, cmd interface{}, state *checkState, shlex *ShellLex, stageIndex map[string]int, optionsOS string) {
+	if p, ok := cmd.(instructions.PlatformSpecific); ok {
+		if err := p.CheckPlatform(optionsOS); err != nil {
+			c.errorf(line, "%v", err)
+		}
+	}
+	envs := append(state.env, state.args.FilterAllowed(state.env)...)
+	if ex, ok := cmd.(instructions.SupportsSingleWordExpansion); ok {
+		if err := ex.Expand(func(word string) (string, error) {
+			return shlex.ProcessWord(word, envs)
+		}); err != nil {
+			c.errorf(line, "%v", err)
+			return
+		}
+	}
+
+	switch cmd := cmd.(type) {
+	case *instructions.EnvCommand:
+		for _, e := range cmd.Env {
+			state.env = setEnv(state.env, e.Key, e.String())
+		}
+	case *instructions.ArgCommand:
+		state.args.AddArg(cmd.Key, cmd.Value)
+	case *instructions.CopyCommand:
+		if err := cmd.ExpandContents(func(content string) (string, error) {
+			return shlex.ProcessHeredoc(content, envs)
+		}); err != nil {
+			c.errorf(line, "%v", err)
+		}
+		if cmd.From != "" {
+			c.checkCopyFrom(line, cmd.From, state.index, stageIndex)
+		}
+	case *instructions.MaintainerCommand:
+		c.warnf(line, "MAINTAINER is deprecated, use a LABEL instead")
+	case *instructions.CmdCommand, *instructions.EntrypointCommand, *instructions.HealthCheckCommand:
+		name := strings.ToUpper(cmd.(instructions.Command).Name())
+		if previous, ok := state.overrides[name]; ok {
+			c.warnf(line, "%s instruction overrides the %s at line %d", name, name, previous)
+		}
+		state.overrides[name] = line
+	}
+}
+
+// checkCopyFrom validates the --from flag of a COPY of the stage at index,
+// which must refer to a previous stage or to an image
+func (c *dockerfileChecker) checkCopyFrom(line int, from string, index int, stageIndex map[string]int) {
+	if ix, ok := stageIndex[strings.ToLower(from)]; ok {
+		if ix >= index {
+			c.errorf(line, "invalid from flag value %s: does not refer to a previous stage", from)
+		}
+		return
+	}
+	if ix, err := strconv.Atoi(from); err == nil {
+		if ix < 0 || ix >= index {
+			c.errorf(line, "invalid from flag value %s: does not refer to a previous stage", from)
+		}
+		return
+	}
+	if _, err := reference.ParseNormalizedNamed(from); err != nil {
+		c.errorf(line, "invalid from flag value %s: %v", from, err)
+	}
+}
+
+// setEnv sets the variable key of env to keyValue
+func setEnv(env []string, key, keyValue string) []string {
+	for i, e := range env {
+		if equalEnvKeys(strings.SplitN(e, "=", 2)[0], key) {
+			env[i] = keyValue
+			return env
+		}
+	}
+	return append(env, keyValue)
+}
@@ -0,0 +1,89 @@
+package dockerfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func checkDockerfileString(t *testing.T, dockerfile string, options *types.ImageBuildOptions) []types.BuildCheckMessage {
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	return checkDockerfile(result, options, "linux").Messages
+}
+
+func TestCheckDockerfile(t *testing.T) {
+	dockerfile := `ARG BASE=busybox
+FROM ${BASE} AS build
+ENV DIR=/src
+COPY --from=final /out ${DIR}
+CMD ["a"]
+CMD ["b"]
+MAINTAINER someone
+FROM final
+FROM build AS final
+COPY --from=build ${DIR:?unset} /
+COPY --from=5 /a /b
+COPY --from=Invalid:Ref /a /b
+RUN --unknown=1 true
+`
+	value := "1"
+	messages := checkDockerfileString(t, dockerfile, &types.ImageBuildOptions{
+		BuildArgs: map[string]*string{"UNUSED": &value, "HTTP_PROXY": &value, "BASE": &value},
+		Target:    "missing",
+	})
+	expected := []types.BuildCheckMessage{
+		{Level: types.BuildCheckError, Line: 13, Message: "Unknown flag: unknown"},
+		{Level: types.BuildCheckError, Line: 4, Message: "invalid from flag value final: does not refer to a previous stage"},
+		{Level: types.BuildCheckWarning, Line: 6, Message: "CMD instruction overrides the CMD at line 5"},
+		{Level: types.BuildCheckWarning, Line: 7, Message: "MAINTAINER is deprecated, use a LABEL instead"},
+		{Level: types.BuildCheckWarning, Line: 8, Message: "final is not a previous stage and is used as an image name"},
+		{Level: types.BuildCheckError, Line: 10, Message: `failed to process "${DIR:?unset}": unsupported modifier (?) in substitution`},
+		{Level: types.BuildCheckError, Line: 11, Message: "invalid from flag value 5: does not refer to a previous stage"},
+		{Level: types.BuildCheckError, Line: 12, Message: "invalid from flag value Invalid:Ref: invalid reference format: repository name must be lowercase"},
+		{Level: types.BuildCheckError, Message: "failed to reach build target missing in Dockerfile"},
+		{Level: types.BuildCheckWarning, Message: "One or more build-args [UNUSED] were not consumed"},
+	}
+	assert.Equal(t, expected, messages)
+}
+
+func TestCheckDockerfileValid(t *testing.T) {
+	dockerfile := `ARG VERSION=3.6
+FROM alpine:${VERSION} AS build
+ARG NAME
+RUN echo $NAME > /name
+FROM scratch
+COPY --from=build /name /
+COPY --from=0 /name /name2
+COPY --from=busybox:latest /bin/sh /sh
+CMD ["/sh"]
+`
+	value := "x"
+	messages := checkDockerfileString(t, dockerfile, &types.ImageBuildOptions{
+		BuildArgs: map[string]*string{"NAME": &value},
+		Target:    "build",
+	})
+	assert.Empty(t, messages)
+}
+
+func TestCheckDockerfileNoStage(t *testing.T) {
+	messages := checkDockerfileString(t, "ARG A\nRUN true\n", &types.ImageBuildOptions{})
+	assert.Equal(t, []types.BuildCheckMessage{
+		{Level: types.BuildCheckError, Line: 2, Message: "No build stage in current context"},
+		{Level: types.BuildCheckError, Message: "No build stage found. A Dockerfile must start with a FROM instruction"},
+	}, messages)
+}
+
+func TestCheckDockerfileWindowsScratch(t *testing.T) {
+	result, err := parser.Parse(strings.NewReader("FROM scratch\nSTOPSIGNAL SIGKILL\n"))
+	require.NoError(t, err)
+	messages := checkDockerfile(result, &types.ImageBuildOptions{}, "windows").Messages
+	assert.Equal(t, []types.BuildCheckMessage{
+		{Level: types.BuildCheckError, Line: 1, Message: "Windows does not support FROM scratch"},
+		{Level: types.BuildCheckError, Line: 2, Message: "The daemon on this platform does not support the command stopsignal"},
+	}, messages)
+}
@@ -3,11 +3,20 @@ package dockerfile
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/builder/fscache"
 	containerpkg "github.com/docker/docker/container"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -141,3 +150,122 @@ func (c *containerManager) RemoveAll(stdout io.Writer) {
 		fmt.Fprintf(stdout, "Removing intermediate container %s\n", stringid.TruncateID(containerID))
 	}
 }
+
+// runMounts are the mounts requested by the flags of a single RUN
+// instruction. They are only added to the container of that instruction and
+// their content is never committed into its layer.
+type runMounts struct {
+	mounts    []mount.Mount
+	releasers []func() error
+}
+
+// targets returns the paths the mounts are mounted at in the container
+func (rm *runMounts) targets() []string {
+	targets := make([]string, len(rm.mounts))
+	for i, m := range rm.mounts {
+		targets[i] = m.Target
+	}
+	return targets
+}
+
+// Release releases the resources of all the mounts, it must be called once
+// the container has run
+func (rm *runMounts) Release() error {
+	var errs []string
+	for i := len(rm.releasers) - 1; i >= 0; i-- {
+		if err := rm.releasers[i](); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	rm.releasers = nil
+	if len(errs) > 0 {
+		return errors.Errorf("failed to release mounts: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// addSecrets fetches the secrets with getSecret and adds read-only mounts
+// exposing them. The secrets are written to a tmpfs so that they are never
+// stored on disk.
+func (rm *runMounts) addSecrets(secrets []instructions.SecretMount, getSecret func(id string) ([]byte, error), idMappings *idtools.IDMappings) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	// fetch everything first so that nothing is mounted if a secret is missing
+	data := make([][]byte, len(secrets))
+	for i, secret := range secrets {
+		d, err := getSecret(secret.ID)
+		if err != nil {
+			return err
+		}
+		data[i] = d
+	}
+
+	dir, err := ioutil.TempDir("", "docker-build-secrets")
+	if err != nil {
+		return err
+	}
+	if err := mountTmpfs(dir); err != nil {
+		os.Remove(dir)
+		return errors.Wrap(err, "failed to mount tmpfs for secrets")
+	}
+	rm.releasers = append(rm.releasers, func() error {
+		if err := unmountTmpfs(dir); err != nil {
+			return err
+		}
+		return os.RemoveAll(dir)
+	})
+
+	for i, secret := range secrets {
+		src := filepath.Join(dir, strconv.Itoa(i))
+		if err := ioutil.WriteFile(src, data[i], secret.Mode); err != nil {
+			return err
+		}
+		if err := chownAndChmod(src, secret.UID, secret.GID, secret.Mode, idMappings); err != nil {
+			return errors.Wrapf(err, "failed to set up secret %s", secret.ID)
+		}
+		rm.mounts = append(rm.mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   src,
+			Target:   secret.Target,
+			ReadOnly: true,
+		})
+	}
+	return nil
+}
+
+// addCaches adds mounts for the persistent cache directories returned by
+// getCache
+func (rm *runMounts) addCaches(caches []instructions.CacheMount, getCache func(id string) (fscache.CacheMountRef, error), idMappings *idtools.IDMappings) error {
+	for _, cache := range caches {
+		ref, err := getCache(cache.ID)
+		if err != nil {
+			return err
+		}
+		rm.releasers = append(rm.releasers, ref.Release)
+		if err := chownAndChmod(ref.Dir(), cache.UID, cache.GID, cache.Mode, idMappings); err != nil {
+			return errors.Wrapf(err, "failed to set up cache mount %s", cache.ID)
+		}
+		rm.mounts = append(rm.mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: ref.Dir(),
+			Target: cache.Target,
+		})
+	}
+	return nil
+}
+
+// chownAndChmod sets the owner, mapped to the host, and the permissions of
+// the source of a mount
+func chownAndChmod(p string, uid, gid int, mode os.FileMode, idMappings *idtools.IDMappings) error {
+	hostIDs, err := idMappings.ToHost(idtools.IDPair{UID: uid, GID: gid})
+	if err != nil {
+		return err
+	}
+	if err := os.Chown(p, hostIDs.UID, hostIDs.GID); err != nil {
+		return err
+	}
+	// the permissions of a file created by WriteFile are subject to the umask
+	return os.Chmod(p, mode)
+}
@@ -0,0 +1,15 @@
+// +build !windows
+
+package dockerfile
+
+import (
+	"github.com/docker/docker/pkg/mount"
+)
+
+func mountTmpfs(dir string) error {
+	return mount.Mount("tmpfs", dir, "tmpfs", "nodev,nosuid,noexec,mode=0700")
+}
+
+func unmountTmpfs(dir string) error {
+	return mount.Unmount(dir)
+}
@@ -0,0 +1,13 @@
+package dockerfile
+
+import (
+	"github.com/pkg/errors"
+)
+
+func mountTmpfs(dir string) error {
+	return errors.New("RUN --secret is not supported on Windows")
+}
+
+func unmountTmpfs(dir string) error {
+	return nil
+}
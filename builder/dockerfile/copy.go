@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime"
 	"net/http"
 	"net/url"
@@ -15,6 +16,7 @@ import (
 	"time"
 
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/instructions"
 	"github.com/docker/docker/builder/remotecontext"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/containerfs"
@@ -86,17 +88,24 @@ func copierFromDispatchRequest(req dispatchRequest, download sourceDownloader, i
 	}
 }
 
-func (o *copier) createCopyInstruction(args []string, cmdName string) (copyInstruction, error) {
+func (o *copier) createCopyInstruction(args []string, contents []instructions.SourceContent, cmdName string) (copyInstruction, error) {
 	inst := copyInstruction{cmdName: cmdName}
 	last := len(args) - 1
 
 	// Work in platform-specific filepath semantics
 	inst.dest = fromSlash(args[last], o.platform)
 	separator := string(separator(o.platform))
-	infos, err := o.getCopyInfosForSourcePaths(args[0:last], inst.dest)
+	infos, err := o.getCopyInfosForSourceContents(contents)
 	if err != nil {
 		return inst, errors.Wrapf(err, "%s failed", cmdName)
 	}
+	if last > 0 || len(infos) == 0 {
+		pathInfos, err := o.getCopyInfosForSourcePaths(args[0:last], inst.dest)
+		if err != nil {
+			return inst, errors.Wrapf(err, "%s failed", cmdName)
+		}
+		infos = append(pathInfos, infos...)
+	}
 	if len(infos) > 1 && !strings.HasSuffix(inst.dest, separator) {
 		return inst, errors.Errorf("When using %s with more than one source file, the destination must be a directory and end with a /", cmdName)
 	}
@@ -148,6 +157,41 @@ func (o *copier) getCopyInfoForSourcePath(orig, dest string) ([]copyInfo, error)
 	return newCopyInfos(ci), err
 }
 
+// getCopyInfosForSourceContents writes the content of each here-document
+// source to a file in a temporary directory, and calculates the info needed
+// to copy it
+func (o *copier) getCopyInfosForSourceContents(contents []instructions.SourceContent) ([]copyInfo, error) {
+	var infos []copyInfo
+	for _, content := range contents {
+		tmpDir, err := ioutils.TempDir("", "docker-heredoc")
+		if err != nil {
+			return nil, err
+		}
+		o.tmpPaths = append(o.tmpPaths, tmpDir)
+
+		tmpFileName := filepath.Join(tmpDir, content.Path)
+		if err := ioutil.WriteFile(tmpFileName, []byte(content.Data), 0644); err != nil {
+			return nil, err
+		}
+		// Remove atime and mtime so that the cache only depends on the content
+		if err := system.Chtimes(tmpFileName, time.Time{}, time.Time{}); err != nil {
+			return nil, err
+		}
+
+		source, err := remotecontext.NewLazySource(containerfs.NewLocalContainerFS(tmpDir))
+		if err != nil {
+			return nil, err
+		}
+		ci, err := copyInfoForFile(source, content.Path)
+		if err != nil {
+			return nil, err
+		}
+		ci.noDecompress = true
+		infos = append(infos, ci)
+	}
+	return infos, nil
+}
+
 // Cleanup removes any temporary directories created as part of downloading
 // remote files.
 func (o *copier) Cleanup() {
@@ -1,12 +1,17 @@
 package dockerfile
 
 import (
+	"io/ioutil"
 	"net/http"
+	"path/filepath"
+	"runtime"
 	"testing"
 
+	"github.com/docker/docker/builder/dockerfile/instructions"
 	"github.com/docker/docker/pkg/containerfs"
 	"github.com/gotestyourself/gotestyourself/fs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsExistingDirectory(t *testing.T) {
@@ -145,3 +150,29 @@ func TestGetFilenameForDownload(t *testing.T) {
 		assert.Equal(t, testcase.expected, filename)
 	}
 }
+
+func TestCreateCopyInstructionWithSourceContents(t *testing.T) {
+	contents := []instructions.SourceContent{
+		{Path: "CONF", Data: "debug = true\n"},
+		{Path: "SCRIPT", Data: "#!/bin/sh\n"},
+	}
+	o := &copier{platform: runtime.GOOS}
+	defer o.Cleanup()
+
+	inst, err := o.createCopyInstruction([]string{"/etc/app.conf"}, contents[:1], "COPY")
+	require.NoError(t, err)
+	require.Len(t, inst.infos, 1)
+	path, err := inst.infos[0].fullPath()
+	require.NoError(t, err)
+	assert.Equal(t, "CONF", filepath.Base(path))
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "debug = true\n", string(data))
+
+	again, err := o.createCopyInstruction([]string{"/etc/app.conf"}, contents[:1], "COPY")
+	require.NoError(t, err)
+	assert.Equal(t, inst.infos[0].hash, again.infos[0].hash)
+
+	_, err = o.createCopyInstruction([]string{"/etc/app.conf"}, contents, "COPY")
+	assert.EqualError(t, err, "When using COPY with more than one source file, the destination must be a directory and end with a /")
+}
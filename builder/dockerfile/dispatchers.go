@@ -94,7 +94,7 @@ func dispatchAdd(d dispatchRequest, c *instructions.AddCommand) error {
 	copier := copierFromDispatchRequest(d, downloader, nil)
 	defer copier.Cleanup()
 
-	copyInstruction, err := copier.createCopyInstruction(c.SourcesAndDest, "ADD")
+	copyInstruction, err := copier.createCopyInstruction(c.SourcesAndDest, nil, "ADD")
 	if err != nil {
 		return err
 	}
@@ -119,7 +119,7 @@ func dispatchCopy(d dispatchRequest, c *instructions.CopyCommand) error {
 	}
 	copier := copierFromDispatchRequest(d, errOnSourceDownload, im)
 	defer copier.Cleanup()
-	copyInstruction, err := copier.createCopyInstruction(c.SourcesAndDest, "COPY")
+	copyInstruction, err := copier.createCopyInstruction(c.SourcesAndDest, c.SourceContents, "COPY")
 	if err != nil {
 		return err
 	}
@@ -318,6 +318,9 @@ func dispatchRun(d dispatchRequest, c *instructions.RunCommand) error {
 	if len(buildArgs) > 0 {
 		saveCmd = prependEnvOnCmd(d.state.buildArgs, buildArgs, cmdFromArgs)
 	}
+	if len(c.CacheMounts) > 0 {
+		saveCmd = prependCacheMountsOnCmd(c.CacheMounts, saveCmd)
+	}
 
 	runConfigForCacheProbe := copyRunConfig(stateRunConfig,
 		withCmd(saveCmd),
@@ -335,8 +338,20 @@ func dispatchRun(d dispatchRequest, c *instructions.RunCommand) error {
 	// set config as already being escaped, this prevents double escaping on windows
 	runConfig.ArgsEscaped = true
 
+	// the mounts themselves are not part of the committed config, only the
+	// specification of the cache mounts is recorded in its command
+	mounts, err := d.builder.prepareRunMounts(c)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := mounts.Release(); err != nil {
+			logrus.Warnf("%v", err)
+		}
+	}()
+
 	logrus.Debugf("[BUILDER] Command to be executed: %v", runConfig.Cmd)
-	cID, err := d.builder.create(runConfig)
+	cID, err := d.builder.create(runConfig, mounts.mounts...)
 	if err != nil {
 		return err
 	}
@@ -353,6 +368,14 @@ func dispatchRun(d dispatchRequest, c *instructions.RunCommand) error {
 		return err
 	}
 
+	// the mountpoints created in the container for the mounts are not part
+	// of the result of the instruction
+	if len(mounts.mounts) > 0 {
+		if err := d.builder.docker.ContainerRemoveMountpoints(cID, mounts.targets()); err != nil {
+			return err
+		}
+	}
+
 	return d.builder.commitContainer(d.state, cID, runConfigForCacheProbe)
 }
 
@@ -381,6 +404,17 @@ func prependEnvOnCmd(buildArgs *buildArgs, buildArgVars []string, cmd strslice.S
 	return strslice.StrSlice(append(tmpEnv, cmd...))
 }
 
+// Record the cache mounts of a RUN in the command to use for probeCache() and
+// to commit, so that a RUN with cache mounts does not share its cache with the
+// same command run without them, or with other mounts.
+func prependCacheMountsOnCmd(mounts []instructions.CacheMount, cmd strslice.StrSlice) strslice.StrSlice {
+	specs := make([]string, 0, len(mounts)+len(cmd))
+	for _, m := range mounts {
+		specs = append(specs, fmt.Sprintf("--mount=type=cache,id=%s,target=%s,uid=%d,gid=%d,mode=%o", m.ID, m.Target, m.UID, m.GID, m.Mode))
+	}
+	return strslice.StrSlice(append(specs, cmd...))
+}
+
 // CMD foo
 //
 // Set the default command to run in the container (which may be empty).
@@ -3,15 +3,23 @@ package dockerfile
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/builder/fscache"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/system"
 	"github.com/docker/go-connections/nat"
 	"github.com/stretchr/testify/assert"
@@ -466,3 +474,111 @@ func TestRunWithBuildArgs(t *testing.T) {
 	// Check that runConfig.Cmd has not been modified by run
 	assert.Equal(t, origCmd, sb.state.runConfig.Cmd)
 }
+
+func TestRunWithSecretWithoutSession(t *testing.T) {
+	b := newBuilderWithMockBackend()
+	b.disableCommit = false
+	sb := newDispatchRequest(b, '`', nil, newBuildArgs(make(map[string]*string)), newStagesBuildResults())
+
+	mockBackend := b.docker.(*MockBackend)
+	mockBackend.makeImageCacheFunc = func(_ []string, _ string) builder.ImageCache {
+		return &mockImageCache{}
+	}
+	b.imageProber = newImageProber(mockBackend, nil, runtime.GOOS, false)
+	mockBackend.getImageFunc = func(_ string) (builder.Image, builder.ReleaseableLayer, error) {
+		return &mockImage{id: "abcdef", config: &container.Config{}}, nil, nil
+	}
+	mockBackend.containerCreateFunc = func(config types.ContainerCreateConfig) (container.ContainerCreateCreatedBody, error) {
+		t.Fatal("container should not be created when a secret is unavailable")
+		return container.ContainerCreateCreatedBody{}, nil
+	}
+	require.NoError(t, initializeStage(sb, &instructions.Stage{BaseName: "abcdef"}))
+
+	run := &instructions.RunCommand{
+		ShellDependantCmdLine: instructions.ShellDependantCmdLine{
+			CmdLine:      strslice.StrSlice{"cat /run/secrets/token"},
+			PrependShell: true,
+		},
+		Secrets: []instructions.SecretMount{{ID: "token", Target: "/run/secrets/token", Mode: 0400}},
+	}
+	err := dispatch(sb, run)
+	assert.EqualError(t, err, "secret token requested but the build has no session to provide it")
+}
+
+func TestRunWithCacheMount(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cache mounts are not supported on Windows")
+	}
+	tmpDir, err := ioutil.TempDir("", "builder-cache-mount")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	fsCache, err := fscache.NewFSCache(fscache.Opt{
+		Root:     tmpDir,
+		Backend:  fscache.NewNaiveCacheBackend(filepath.Join(tmpDir, "backend")),
+		GCPolicy: fscache.GCPolicy{MaxSize: 1024, MaxKeepDuration: time.Hour},
+	})
+	require.NoError(t, err)
+	defer fsCache.Close()
+
+	b := newBuilderWithMockBackend()
+	b.disableCommit = false
+	b.fsCache = fsCache
+	b.idMappings = &idtools.IDMappings{}
+	sb := newDispatchRequest(b, '`', nil, newBuildArgs(make(map[string]*string)), newStagesBuildResults())
+
+	cmdWithShell := strslice.StrSlice(append(getShell(&container.Config{}, runtime.GOOS), "go build"))
+	mountSpec := fmt.Sprintf("--mount=type=cache,id=go,target=/root/.cache,uid=%d,gid=%d,mode=755", os.Getuid(), os.Getgid())
+	cachedCmd := strslice.StrSlice(append([]string{mountSpec}, cmdWithShell...))
+
+	imageCache := &mockImageCache{
+		getCacheFunc: func(parentID string, cfg *container.Config) (string, error) {
+			// the cache mount is part of the cache key
+			assert.Equal(t, cachedCmd, cfg.Cmd)
+			return "", nil
+		},
+	}
+	mockBackend := b.docker.(*MockBackend)
+	mockBackend.makeImageCacheFunc = func(_ []string, _ string) builder.ImageCache {
+		return imageCache
+	}
+	b.imageProber = newImageProber(mockBackend, nil, runtime.GOOS, false)
+	mockBackend.getImageFunc = func(_ string) (builder.Image, builder.ReleaseableLayer, error) {
+		return &mockImage{id: "abcdef", config: &container.Config{}}, nil, nil
+	}
+	var cacheDir string
+	mockBackend.containerCreateFunc = func(config types.ContainerCreateConfig) (container.ContainerCreateCreatedBody, error) {
+		require.Len(t, config.HostConfig.Mounts, 1)
+		m := config.HostConfig.Mounts[0]
+		assert.Equal(t, mount.TypeBind, m.Type)
+		assert.Equal(t, "/root/.cache", m.Target)
+		assert.False(t, m.ReadOnly)
+		assert.Equal(t, cmdWithShell, config.Config.Cmd)
+		cacheDir = m.Source
+		return container.ContainerCreateCreatedBody{ID: "12345"}, nil
+	}
+	mockBackend.commitFunc = func(cID string, cfg *backend.ContainerCommitConfig) (string, error) {
+		assert.Equal(t, cachedCmd, cfg.ContainerConfig.Cmd)
+		return "", nil
+	}
+	require.NoError(t, initializeStage(sb, &instructions.Stage{BaseName: "abcdef"}))
+
+	run := &instructions.RunCommand{
+		ShellDependantCmdLine: instructions.ShellDependantCmdLine{
+			CmdLine:      strslice.StrSlice{"go build"},
+			PrependShell: true,
+		},
+		CacheMounts: []instructions.CacheMount{{ID: "go", Target: "/root/.cache", UID: os.Getuid(), GID: os.Getgid(), Mode: 0755}},
+	}
+	require.NoError(t, dispatch(sb, run))
+
+	fi, err := os.Stat(cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+
+	// the cache mount is released once the container has run, so it can be pruned
+	_, err = fsCache.Prune(context.Background())
+	require.NoError(t, err)
+	_, err = os.Stat(cacheDir)
+	assert.True(t, os.IsNotExist(err))
+}
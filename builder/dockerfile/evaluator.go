@@ -51,6 +51,14 @@ func dispatch(d dispatchRequest, cmd instructions.Command) (err error) {
 			return validationError{err}
 		}
 	}
+	if c, ok := cmd.(*instructions.CopyCommand); ok {
+		err := c.ExpandContents(func(content string) (string, error) {
+			return d.shlex.ProcessHeredoc(content, envs)
+		})
+		if err != nil {
+			return validationError{err}
+		}
+	}
 
 	defer func() {
 		if d.builder.options.ForceRemove {
@@ -121,6 +129,12 @@ func newDispatchState(baseArgs *buildArgs) *dispatchState {
 type stagesBuildResults struct {
 	flat    []*container.Config
 	indexed map[string]*container.Config
+
+	// pending holds the names of the previous stages that are still being
+	// built concurrently with the current stage. Their flat entries are nil
+	// and waitFor blocks until the result of such a stage is available.
+	pending map[string]int
+	waitFor func(i int) (*container.Config, error)
 }
 
 func newStagesBuildResults() *stagesBuildResults {
@@ -148,6 +162,9 @@ func (r *stagesBuildResults) get(nameOrIndex string) (*container.Config, error)
 	if c, ok := r.getByName(nameOrIndex); ok {
 		return c, nil
 	}
+	if ix, ok := r.pending[strings.ToLower(nameOrIndex)]; ok {
+		return r.waitFor(ix)
+	}
 	ix, err := strconv.ParseInt(nameOrIndex, 10, 0)
 	if err != nil {
 		return nil, nil
@@ -155,6 +172,9 @@ func (r *stagesBuildResults) get(nameOrIndex string) (*container.Config, error)
 	if err := r.validateIndex(int(ix)); err != nil {
 		return nil, err
 	}
+	if r.flat[ix] == nil && r.waitFor != nil {
+		return r.waitFor(int(ix))
+	}
 	return r.flat[ix], nil
 }
 
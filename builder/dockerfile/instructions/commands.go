@@ -2,7 +2,7 @@ package instructions
 
 import (
 	"errors"
-
+	"os"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
@@ -161,8 +161,22 @@ func (c *AddCommand) Expand(expander SingleWordExpander) error {
 type CopyCommand struct {
 	withNameAndCode
 	SourcesAndDest
-	From  string
-	Chown string
+	SourceContents []SourceContent
+	From           string
+	Chown          string
+}
+
+// SourceContent is the content of a here-document source of COPY. It is
+// copied as a file named after the delimiter word of the here-document.
+//
+// COPY <<EOF /etc/app.conf
+// debug = true
+// EOF
+//
+type SourceContent struct {
+	Path   string
+	Data   string
+	Expand bool
 }
 
 // Expand variables
@@ -170,6 +184,22 @@ func (c *CopyCommand) Expand(expander SingleWordExpander) error {
 	return expandSliceInPlace(c.SourcesAndDest, expander)
 }
 
+// ExpandContents expands the variables of the here-document sources whose
+// delimiter word is not quoted
+func (c *CopyCommand) ExpandContents(expander SingleWordExpander) error {
+	for i, content := range c.SourceContents {
+		if !content.Expand {
+			continue
+		}
+		data, err := expander(content.Data)
+		if err != nil {
+			return err
+		}
+		c.SourceContents[i].Data = data
+	}
+	return nil
+}
+
 // OnbuildCommand : ONBUILD <some other command>
 type OnbuildCommand struct {
 	withNameAndCode
@@ -214,6 +244,35 @@ type ShellDependantCmdLine struct {
 type RunCommand struct {
 	withNameAndCode
 	ShellDependantCmdLine
+	Secrets     []SecretMount
+	CacheMounts []CacheMount
+}
+
+// SecretMount is a secret requested with RUN --secret. The secret is only
+// available to the command of this RUN instruction.
+//
+// RUN --secret=id=npmrc,target=/root/.npmrc npm install
+//
+type SecretMount struct {
+	ID     string
+	Target string
+	UID    int
+	GID    int
+	Mode   os.FileMode
+}
+
+// CacheMount is a persistent cache directory requested with
+// RUN --mount=type=cache. The directory is shared by all the builds using the
+// same id and its content is never committed into the image.
+//
+// RUN --mount=type=cache,target=/root/.cache/go-build go build ./...
+//
+type CacheMount struct {
+	ID     string
+	Target string
+	UID    int
+	GID    int
+	Mode   os.FileMode
 }
 
 // CmdCommand : CMD foo
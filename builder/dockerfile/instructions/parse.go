@@ -2,6 +2,8 @@ package instructions
 
 import (
 	"fmt"
+	"os"
+	"path"
 	"regexp"
 	"sort"
 	"strconv"
@@ -21,6 +23,7 @@ type parseRequest struct {
 	attributes map[string]bool
 	flags      *BFlags
 	original   string
+	heredocs   []parser.Heredoc
 }
 
 func nodeArgs(node *parser.Node) []string {
@@ -45,6 +48,7 @@ func newParseRequestFromNode(node *parser.Node) parseRequest {
 		attributes: node.Attributes,
 		original:   node.Original,
 		flags:      NewBFlagsWithArgs(node.Flags),
+		heredocs:   node.Heredocs,
 	}
 }
 
@@ -257,14 +261,46 @@ func parseCopy(req parseRequest) (*CopyCommand, error) {
 	if err := req.flags.Parse(); err != nil {
 		return nil, err
 	}
+	sourcesAndDest, contents, err := parseSourceContents(req.args, req.heredocs)
+	if err != nil {
+		return nil, err
+	}
 	return &CopyCommand{
-		SourcesAndDest:  SourcesAndDest(req.args),
+		SourcesAndDest:  sourcesAndDest,
+		SourceContents:  contents,
 		From:            flFrom.Value,
 		withNameAndCode: newWithNameAndCode(req),
 		Chown:           flChown.Value,
 	}, nil
 }
 
+// parseSourceContents splits the here-documents from the sources of a COPY
+// instruction
+func parseSourceContents(args []string, heredocs []parser.Heredoc) (SourcesAndDest, []SourceContent, error) {
+	if len(heredocs) == 0 {
+		return SourcesAndDest(args), nil, nil
+	}
+	last := len(args) - 1
+	if parser.ParseHeredoc(args[last]) != nil {
+		return nil, nil, errors.New("COPY destination can't be a heredoc")
+	}
+	var sourcesAndDest SourcesAndDest
+	var contents []SourceContent
+	for _, arg := range args[:last] {
+		heredoc := parser.ParseHeredoc(arg)
+		if heredoc == nil {
+			sourcesAndDest = append(sourcesAndDest, arg)
+			continue
+		}
+		if len(contents) == len(heredocs) || heredocs[len(contents)].Name != heredoc.Name {
+			return nil, nil, errors.Errorf("missing content of heredoc %s", heredoc.Name)
+		}
+		h := heredocs[len(contents)]
+		contents = append(contents, SourceContent{Path: h.Name, Data: h.Content, Expand: h.Expand})
+	}
+	return append(sourcesAndDest, args[last]), contents, nil
+}
+
 func parseFrom(req parseRequest) (*Stage, error) {
 	stageName, err := parseBuildStageName(req.args)
 	if err != nil {
@@ -352,16 +388,169 @@ func parseShellDependentCommand(req parseRequest, emptyAsNil bool) ShellDependan
 	}
 }
 
-func parseRun(req parseRequest) (*RunCommand, error) {
+// heredocScript returns the shell script of a RUN instruction with
+// here-documents. If the command is a single here-document, its content is
+// the script. Otherwise the here-documents are appended to the command for
+// the shell to redirect them.
+func heredocScript(cmdLine string, heredocs []parser.Heredoc) string {
+	if len(heredocs) == 1 && parser.ParseHeredoc(strings.TrimSpace(cmdLine)) != nil {
+		return heredocs[0].Content
+	}
+	script := cmdLine
+	for _, heredoc := range heredocs {
+		script += "\n" + heredoc.Content + heredoc.Name
+	}
+	return script
+}
 
+func parseRun(req parseRequest) (*RunCommand, error) {
+	flSecrets := req.flags.AddStrings("secret")
+	flMounts := req.flags.AddStrings("mount")
 	if err := req.flags.Parse(); err != nil {
 		return nil, err
 	}
-	return &RunCommand{
+	cmd := &RunCommand{
 		ShellDependantCmdLine: parseShellDependentCommand(req, false),
 		withNameAndCode:       newWithNameAndCode(req),
-	}, nil
+	}
+	if len(req.heredocs) > 0 && cmd.PrependShell && len(cmd.CmdLine) == 1 {
+		cmd.CmdLine = strslice.StrSlice{heredocScript(cmd.CmdLine[0], req.heredocs)}
+	}
 
+	targets := make(map[string]struct{})
+	checkTarget := func(target string) error {
+		if _, exists := targets[target]; exists {
+			return errors.Errorf("duplicate mount target %s", target)
+		}
+		targets[target] = struct{}{}
+		return nil
+	}
+	for _, value := range flSecrets.StringValues {
+		secret, err := parseSecretMount(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkTarget(secret.Target); err != nil {
+			return nil, err
+		}
+		cmd.Secrets = append(cmd.Secrets, secret)
+	}
+	for _, value := range flMounts.StringValues {
+		cache, err := parseCacheMount(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkTarget(cache.Target); err != nil {
+			return nil, err
+		}
+		cmd.CacheMounts = append(cmd.CacheMounts, cache)
+	}
+	return cmd, nil
+}
+
+const (
+	defaultSecretMode = 0400
+	defaultCacheMode  = 0755
+)
+
+// parseSecretMount parses the value of a --secret flag
+func parseSecretMount(value string) (SecretMount, error) {
+	opts, err := parseMountOpts("--secret", value, defaultSecretMode)
+	if err != nil {
+		return SecretMount{}, err
+	}
+	if opts.typ != "" {
+		return SecretMount{}, errors.New(`unknown key "type" in --secret`)
+	}
+	if opts.id == "" {
+		return SecretMount{}, errors.New("--secret requires an id")
+	}
+	if opts.target == "" {
+		opts.target = path.Join("/run/secrets", opts.id)
+	}
+	if err := opts.cleanTarget("--secret"); err != nil {
+		return SecretMount{}, err
+	}
+	return SecretMount{ID: opts.id, Target: opts.target, UID: opts.uid, GID: opts.gid, Mode: opts.mode}, nil
+}
+
+// parseCacheMount parses the value of a --mount flag. Only cache mounts are
+// supported.
+func parseCacheMount(value string) (CacheMount, error) {
+	opts, err := parseMountOpts("--mount", value, defaultCacheMode)
+	if err != nil {
+		return CacheMount{}, err
+	}
+	if opts.typ != "cache" {
+		return CacheMount{}, errors.Errorf("unsupported mount type %q in --mount, only cache is supported", opts.typ)
+	}
+	if opts.target == "" {
+		return CacheMount{}, errors.New("--mount requires a target")
+	}
+	if err := opts.cleanTarget("--mount"); err != nil {
+		return CacheMount{}, err
+	}
+	if opts.id == "" {
+		opts.id = opts.target
+	}
+	return CacheMount{ID: opts.id, Target: opts.target, UID: opts.uid, GID: opts.gid, Mode: opts.mode}, nil
+}
+
+type mountOpts struct {
+	typ    string
+	id     string
+	target string
+	uid    int
+	gid    int
+	mode   os.FileMode
+}
+
+func (opts *mountOpts) cleanTarget(flag string) error {
+	if !path.IsAbs(opts.target) {
+		return errors.Errorf("target %q in %s must be an absolute path", opts.target, flag)
+	}
+	opts.target = path.Clean(opts.target)
+	return nil
+}
+
+// parseMountOpts parses a comma separated list of key=value pairs as used by
+// the --secret and --mount flags
+func parseMountOpts(flag, value string, defaultMode os.FileMode) (mountOpts, error) {
+	opts := mountOpts{mode: defaultMode}
+	for _, field := range strings.Split(value, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return opts, errors.Errorf("invalid field %q in %s: must be a key=value pair", field, flag)
+		}
+		key, val := strings.ToLower(parts[0]), parts[1]
+		switch key {
+		case "type":
+			opts.typ = val
+		case "id":
+			opts.id = val
+		case "target":
+			opts.target = val
+		case "uid", "gid":
+			id, err := strconv.Atoi(val)
+			if err != nil || id < 0 {
+				return opts, errors.Errorf("invalid %s %q in %s", key, val, flag)
+			}
+			if key == "uid" {
+				opts.uid = id
+			} else {
+				opts.gid = id
+			}
+		case "mode":
+			mode, err := strconv.ParseUint(val, 8, 32)
+			if err != nil || mode > 0777 {
+				return opts, errors.Errorf("invalid mode %q in %s", val, flag)
+			}
+			opts.mode = os.FileMode(mode)
+		default:
+			return opts, errors.Errorf("unknown key %q in %s", key, flag)
+		}
+	}
+	return opts, nil
 }
 
 func parseCmd(req parseRequest) (*CmdCommand, error) {
@@ -447,8 +636,16 @@ func parseHealthcheck(req parseRequest) (*HealthCheckCommand, error) {
 			}
 
 			healthcheck.Test = strslice.StrSlice(append([]string{typ}, cmdSlice...))
+		case "HTTP", "TCP":
+			// the probe is run by the daemon, so it takes a single URL or
+			// address rather than a command
+			target := handleJSONArgs(args, req.attributes)
+			if len(target) != 1 || len(strings.Fields(target[0])) != 1 {
+				return nil, fmt.Errorf("HEALTHCHECK %s requires exactly one argument", typ)
+			}
+			healthcheck.Test = strslice.StrSlice{typ, target[0]}
 		default:
-			return nil, fmt.Errorf("Unknown type %#v in HEALTHCHECK (try CMD)", typ)
+			return nil, fmt.Errorf("Unknown type %#v in HEALTHCHECK (try CMD, HTTP or TCP)", typ)
 		}
 
 		interval, err := parseOptInterval(flInterval)
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/builder/dockerfile/command"
 	"github.com/docker/docker/builder/dockerfile/parser"
 	"github.com/docker/docker/internal/testutil"
@@ -127,6 +128,170 @@ func TestHealthCheckCmd(t *testing.T) {
 	assert.Equal(t, expected, hc.Health.Test)
 }
 
+func TestHealthCheckProbeTypes(t *testing.T) {
+	testCases := []struct {
+		dockerfile string
+		expected   []string
+		err        string
+	}{
+		{dockerfile: "HEALTHCHECK HTTP http://localhost:8080/health", expected: []string{"HTTP", "http://localhost:8080/health"}},
+		{dockerfile: `HEALTHCHECK HTTP ["http://localhost:8080/health"]`, expected: []string{"HTTP", "http://localhost:8080/health"}},
+		{dockerfile: "HEALTHCHECK TCP localhost:5432", expected: []string{"TCP", "localhost:5432"}},
+		{dockerfile: "HEALTHCHECK TCP localhost:5432 localhost:5433", err: "HEALTHCHECK TCP requires exactly one argument"},
+		{dockerfile: "HEALTHCHECK UDP localhost:53", err: `Unknown type "UDP" in HEALTHCHECK (try CMD, HTTP or TCP)`},
+	}
+
+	for _, tc := range testCases {
+		ast, err := parser.Parse(strings.NewReader(tc.dockerfile))
+		require.NoError(t, err)
+		cmd, err := ParseInstruction(ast.AST.Children[0])
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, tc.dockerfile)
+			continue
+		}
+		require.NoError(t, err, tc.dockerfile)
+		hc, ok := cmd.(*HealthCheckCommand)
+		require.True(t, ok)
+		assert.Equal(t, tc.expected, []string(hc.Health.Test))
+	}
+}
+
+func TestRunSecrets(t *testing.T) {
+	testCases := []struct {
+		dockerfile string
+		expected   []SecretMount
+		err        string
+	}{
+		{
+			dockerfile: "RUN --secret=id=token cat /run/secrets/token",
+			expected:   []SecretMount{{ID: "token", Target: "/run/secrets/token", Mode: 0400}},
+		},
+		{
+			dockerfile: "RUN --secret=id=npmrc,target=/root/.npmrc,uid=1000,gid=1000,mode=0440 --secret=id=token npm install",
+			expected: []SecretMount{
+				{ID: "npmrc", Target: "/root/.npmrc", UID: 1000, GID: 1000, Mode: 0440},
+				{ID: "token", Target: "/run/secrets/token", Mode: 0400},
+			},
+		},
+		{dockerfile: "RUN --secret=target=/foo true", err: "--secret requires an id"},
+		{dockerfile: "RUN --secret=id=foo,target=foo true", err: `target "foo" in --secret must be an absolute path`},
+		{dockerfile: "RUN --secret=id=foo,mode=999 true", err: `invalid mode "999" in --secret`},
+		{dockerfile: "RUN --secret=id=foo,src=/foo true", err: `unknown key "src" in --secret`},
+		{dockerfile: "RUN --secret=id=foo --secret=id=bar,target=/run/secrets/foo true", err: "duplicate mount target /run/secrets/foo"},
+	}
+
+	for _, tc := range testCases {
+		ast, err := parser.Parse(strings.NewReader(tc.dockerfile))
+		require.NoError(t, err)
+		cmd, err := ParseInstruction(ast.AST.Children[0])
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, tc.dockerfile)
+			continue
+		}
+		require.NoError(t, err, tc.dockerfile)
+		run, ok := cmd.(*RunCommand)
+		require.True(t, ok)
+		assert.Equal(t, tc.expected, run.Secrets)
+	}
+}
+
+func TestRunCacheMounts(t *testing.T) {
+	testCases := []struct {
+		dockerfile string
+		expected   []CacheMount
+		err        string
+	}{
+		{
+			dockerfile: "RUN --mount=type=cache,target=/root/.cache/ go build",
+			expected:   []CacheMount{{ID: "/root/.cache", Target: "/root/.cache", Mode: 0755}},
+		},
+		{
+			dockerfile: "RUN --mount=type=cache,id=apt,target=/var/cache/apt,uid=100,mode=0700 --mount=type=cache,target=/var/lib/apt apt-get update",
+			expected: []CacheMount{
+				{ID: "apt", Target: "/var/cache/apt", UID: 100, Mode: 0700},
+				{ID: "/var/lib/apt", Target: "/var/lib/apt", Mode: 0755},
+			},
+		},
+		{dockerfile: "RUN --mount=target=/cache true", err: `unsupported mount type "" in --mount, only cache is supported`},
+		{dockerfile: "RUN --mount=type=bind,target=/cache true", err: `unsupported mount type "bind" in --mount, only cache is supported`},
+		{dockerfile: "RUN --mount=type=cache true", err: "--mount requires a target"},
+		{dockerfile: "RUN --mount=type=cache,target=cache true", err: `target "cache" in --mount must be an absolute path`},
+		{dockerfile: "RUN --mount=type=cache,target=/cache,gid=-1 true", err: `invalid gid "-1" in --mount`},
+		{dockerfile: "RUN --secret=id=cache,target=/cache --mount=type=cache,target=/cache true", err: "duplicate mount target /cache"},
+	}
+
+	for _, tc := range testCases {
+		ast, err := parser.Parse(strings.NewReader(tc.dockerfile))
+		require.NoError(t, err)
+		cmd, err := ParseInstruction(ast.AST.Children[0])
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, tc.dockerfile)
+			continue
+		}
+		require.NoError(t, err, tc.dockerfile)
+		run, ok := cmd.(*RunCommand)
+		require.True(t, ok)
+		assert.Equal(t, tc.expected, run.CacheMounts)
+	}
+}
+
+func TestRunHeredocs(t *testing.T) {
+	testCases := []struct {
+		dockerfile string
+		expected   string
+	}{
+		{
+			dockerfile: "RUN <<EOF\napk add curl\ncurl --version\nEOF",
+			expected:   "apk add curl\ncurl --version\n",
+		},
+		{
+			dockerfile: "RUN python3 <<EOF > /out\nprint('hi')\nEOF",
+			expected:   "python3 <<EOF > /out\nprint('hi')\nEOF",
+		},
+		{
+			dockerfile: "RUN cat <<-A <<'B'\n\tone\n\tA\n$two\nB",
+			expected:   "cat <<-A <<'B'\none\nA\n$two\nB",
+		},
+	}
+
+	for _, tc := range testCases {
+		ast, err := parser.Parse(strings.NewReader(tc.dockerfile))
+		require.NoError(t, err)
+		cmd, err := ParseInstruction(ast.AST.Children[0])
+		require.NoError(t, err, tc.dockerfile)
+		run, ok := cmd.(*RunCommand)
+		require.True(t, ok)
+		assert.Equal(t, strslice.StrSlice{tc.expected}, run.CmdLine, tc.dockerfile)
+		assert.True(t, run.PrependShell)
+	}
+}
+
+func TestCopyHeredocs(t *testing.T) {
+	ast, err := parser.Parse(strings.NewReader("COPY --chown=app <<CONF file.txt <<'SCRIPT' /etc/app/\nport = $PORT\nCONF\necho $HOME\nSCRIPT\n"))
+	require.NoError(t, err)
+	cmd, err := ParseInstruction(ast.AST.Children[0])
+	require.NoError(t, err)
+	copyCmd, ok := cmd.(*CopyCommand)
+	require.True(t, ok)
+	assert.Equal(t, SourcesAndDest{"file.txt", "/etc/app/"}, copyCmd.SourcesAndDest)
+	assert.Equal(t, []SourceContent{
+		{Path: "CONF", Data: "port = $PORT\n", Expand: true},
+		{Path: "SCRIPT", Data: "echo $HOME\n"},
+	}, copyCmd.SourceContents)
+	assert.Equal(t, "app", copyCmd.Chown)
+
+	require.NoError(t, copyCmd.ExpandContents(func(word string) (string, error) {
+		return strings.Replace(word, "$PORT", "8080", -1), nil
+	}))
+	assert.Equal(t, "port = 8080\n", copyCmd.SourceContents[0].Data)
+	assert.Equal(t, "echo $HOME\n", copyCmd.SourceContents[1].Data)
+
+	ast, err = parser.Parse(strings.NewReader("COPY foo <<EOF\nbar\nEOF\n"))
+	require.NoError(t, err)
+	_, err = ParseInstruction(ast.AST.Children[0])
+	assert.EqualError(t, err, "COPY destination can't be a heredoc")
+}
+
 func TestParseOptInterval(t *testing.T) {
 	flInterval := &Flag{
 		name:     "interval",
@@ -17,6 +17,10 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/builder/fscache"
+	"github.com/docker/docker/builder/secrets"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/chrootarchive"
@@ -27,6 +31,8 @@ import (
 	"github.com/docker/docker/pkg/system"
 	lcUser "github.com/opencontainers/runc/libcontainer/user"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
 )
 
 // Archiver defines an interface for copying files from one destination to
@@ -89,7 +95,9 @@ func (b *Builder) commit(dispatchState *dispatchState, comment string) error {
 	if err != nil || hit {
 		return err
 	}
-	id, err := b.create(runConfigWithCommentCmd)
+	// the container is created from a copy as the daemon sets a generated
+	// hostname on the config, which would end up in the image
+	id, err := b.create(copyRunConfig(runConfigWithCommentCmd))
 	if err != nil {
 		return err
 	}
@@ -110,6 +118,7 @@ func (b *Builder) commitContainer(dispatchState *dispatchState, id string, conta
 			Config: copyRunConfig(dispatchState.runConfig),
 		},
 		ContainerConfig: containerConfig,
+		SourceDateEpoch: b.options.SourceDateEpoch,
 	}
 
 	// Commit the container
@@ -124,7 +133,7 @@ func (b *Builder) commitContainer(dispatchState *dispatchState, id string, conta
 
 func (b *Builder) exportImage(state *dispatchState, imageMount *imageMount, runConfig *container.Config) error {
 	optionsPlatform := system.ParsePlatform(b.options.Platform)
-	newLayer, err := imageMount.Layer().Commit(optionsPlatform.OS)
+	newLayer, err := imageMount.Layer().Commit(optionsPlatform.OS, b.options.SourceDateEpoch)
 	if err != nil {
 		return err
 	}
@@ -138,12 +147,16 @@ func (b *Builder) exportImage(state *dispatchState, imageMount *imageMount, runC
 		return errors.Errorf("unexpected image type")
 	}
 
-	newImage := image.NewChildImage(parentImage, image.ChildConfig{
+	childConfig := image.ChildConfig{
 		Author:          state.maintainer,
 		ContainerConfig: runConfig,
 		DiffID:          newLayer.DiffID(),
 		Config:          copyRunConfig(state.runConfig),
-	}, parentImage.OS)
+	}
+	if b.options.SourceDateEpoch != nil {
+		childConfig.Created = *b.options.SourceDateEpoch
+	}
+	newImage := image.NewChildImage(parentImage, childConfig, parentImage.OS)
 
 	// TODO: it seems strange to marshal this here instead of just passing in the
 	// image struct
@@ -449,11 +462,16 @@ func getShell(c *container.Config, os string) []string {
 
 func (b *Builder) probeCache(dispatchState *dispatchState, runConfig *container.Config) (bool, error) {
 	cachedID, err := b.imageProber.Probe(dispatchState.imageID, runConfig)
-	if cachedID == "" || err != nil {
+	if err != nil {
 		return false, err
 	}
+	if cachedID == "" {
+		b.cacheStatus = types.BuildCacheMiss
+		return false, nil
+	}
 	fmt.Fprint(b.Stdout, " ---> Using cache\n")
 
+	b.cacheStatus = types.BuildCacheHit
 	dispatchState.imageID = cachedID
 	return true, nil
 }
@@ -471,8 +489,9 @@ func (b *Builder) probeAndCreate(dispatchState *dispatchState, runConfig *contai
 	return container.ID, err
 }
 
-func (b *Builder) create(runConfig *container.Config) (string, error) {
+func (b *Builder) create(runConfig *container.Config, mounts ...mount.Mount) (string, error) {
 	hostConfig := hostConfigFromOptions(b.options)
+	hostConfig.Mounts = mounts
 	optionsPlatform := system.ParsePlatform(b.options.Platform)
 	container, err := b.containerManager.Create(runConfig, hostConfig, optionsPlatform.OS)
 	if err != nil {
@@ -526,3 +545,42 @@ func separator(platform string) byte {
 	}
 	return '/'
 }
+
+// prepareRunMounts sets up the secret and cache mounts requested by a RUN
+// instruction
+func (b *Builder) prepareRunMounts(c *instructions.RunCommand) (*runMounts, error) {
+	rm := &runMounts{}
+	err := rm.addSecrets(c.Secrets, b.getSecret, b.idMappings)
+	if err == nil {
+		err = rm.addCaches(c.CacheMounts, b.getCacheMount, b.idMappings)
+	}
+	if err != nil {
+		if err := rm.Release(); err != nil {
+			logrus.Warnf("%v", err)
+		}
+		return nil, err
+	}
+	return rm, nil
+}
+
+func (b *Builder) getCacheMount(id string) (fscache.CacheMountRef, error) {
+	if b.fsCache == nil {
+		return nil, errors.New("cache mounts are not supported by this builder")
+	}
+	return b.fsCache.CacheMount(id)
+}
+
+// getSecret requests a secret for RUN --secret from the client of the build
+// session
+func (b *Builder) getSecret(id string) ([]byte, error) {
+	if b.options.SessionID == "" || b.sessionGetter == nil {
+		return nil, errors.Errorf("secret %s requested but the build has no session to provide it", id)
+	}
+	ctx, cancel := context.WithTimeout(b.clientCtx, sessionConnectTimeout)
+	defer cancel()
+	c, err := b.sessionGetter.Get(ctx, b.options.SessionID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get session for %s", b.options.SessionID)
+	}
+	return secrets.GetSecret(b.clientCtx, c, id)
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
@@ -78,7 +79,7 @@ func readAndCheckDockerfile(t *testing.T, testName, contextDir, dockerfilePath,
 		Options: &types.ImageBuildOptions{Dockerfile: dockerfilePath},
 		Source:  tarStream,
 	}
-	_, _, err = remotecontext.Detect(config)
+	_, _, err = remotecontext.Detect(config, nil)
 	assert.EqualError(t, err, expectedError)
 }
 
@@ -259,3 +260,35 @@ othergrp:x:6666:
 		})
 	}
 }
+
+func TestCommitWithSourceDateEpoch(t *testing.T) {
+	sourceDateEpoch := time.Unix(1500000000, 0)
+	var commitCfg *backend.ContainerCommitConfig
+	mockBackend := &MockBackend{}
+	mockBackend.containerCreateFunc = func(config types.ContainerCreateConfig) (container.ContainerCreateCreatedBody, error) {
+		// the daemon generates a hostname for the container
+		config.Config.Hostname = "generated"
+		return container.ContainerCreateCreatedBody{ID: "container"}, nil
+	}
+	mockBackend.commitFunc = func(cID string, cfg *backend.ContainerCommitConfig) (string, error) {
+		commitCfg = cfg
+		return "newimage", nil
+	}
+
+	b := newBuilderWithMockBackend()
+	b.docker = mockBackend
+	b.containerManager = newContainerManager(mockBackend)
+	b.disableCommit = false
+	b.options.NoCache = true
+	b.options.SourceDateEpoch = &sourceDateEpoch
+	b.imageProber = &nopProber{}
+
+	state := newDispatchState(newBuildArgs(nil))
+	state.imageID = "theid"
+	state.runConfig = &container.Config{}
+	require.NoError(t, b.commit(state, "LABEL foo=bar"))
+	assert.Equal(t, "newimage", state.imageID)
+	require.NotNil(t, commitCfg)
+	assert.Equal(t, &sourceDateEpoch, commitCfg.SourceDateEpoch)
+	assert.Equal(t, "", commitCfg.ContainerConfig.Hostname)
+}
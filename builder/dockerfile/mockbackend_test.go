@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io"
 	"runtime"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
@@ -21,6 +22,10 @@ type MockBackend struct {
 	commitFunc          func(string, *backend.ContainerCommitConfig) (string, error)
 	getImageFunc        func(string) (builder.Image, builder.ReleaseableLayer, error)
 	makeImageCacheFunc  func(cacheFrom []string, platform string) builder.ImageCache
+	exportCacheFunc     func(ref string, imageIDs []string) error
+	topLayerFunc        func(imageID string) (layer.DiffID, int64, error)
+
+	removeMountpointsFunc func(containerID string, targets []string) error
 }
 
 func (m *MockBackend) ContainerAttachRaw(cID string, stdin io.ReadCloser, stdout, stderr io.Writer, stream bool, attached chan struct{}) error {
@@ -61,6 +66,13 @@ func (m *MockBackend) ContainerCreateWorkdir(containerID string) error {
 	return nil
 }
 
+func (m *MockBackend) ContainerRemoveMountpoints(containerID string, targets []string) error {
+	if m.removeMountpointsFunc != nil {
+		return m.removeMountpointsFunc(containerID, targets)
+	}
+	return nil
+}
+
 func (m *MockBackend) CopyOnBuild(containerID string, destPath string, srcRoot string, srcPath string, decompress bool) error {
 	return nil
 }
@@ -80,10 +92,28 @@ func (m *MockBackend) MakeImageCache(cacheFrom []string, platform string) builde
 	return nil
 }
 
+func (m *MockBackend) ImportBuildCache(ctx context.Context, cacheFrom []string, opts backend.BuildCacheOptions) builder.ImageCacheBuilder {
+	return m
+}
+
+func (m *MockBackend) ExportBuildCache(ctx context.Context, ref string, imageIDs []string, opts backend.BuildCacheOptions) error {
+	if m.exportCacheFunc != nil {
+		return m.exportCacheFunc(ref, imageIDs)
+	}
+	return nil
+}
+
 func (m *MockBackend) CreateImage(config []byte, parent string, platform string) (builder.Image, error) {
 	return nil, nil
 }
 
+func (m *MockBackend) ImageTopLayer(imageID string) (layer.DiffID, int64, error) {
+	if m.topLayerFunc != nil {
+		return m.topLayerFunc(imageID)
+	}
+	return "", 0, nil
+}
+
 type mockImage struct {
 	id     string
 	config *container.Config
@@ -127,7 +157,7 @@ func (l *mockLayer) Mount() (containerfs.ContainerFS, error) {
 	return containerfs.NewLocalContainerFS("mountPath"), nil
 }
 
-func (l *mockLayer) Commit(string) (builder.ReleaseableLayer, error) {
+func (l *mockLayer) Commit(string, *time.Time) (builder.ReleaseableLayer, error) {
 	return nil, nil
 }
 
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/builder/dockerfile/command"
+	"github.com/pkg/errors"
+)
+
+// Heredoc is a here-document of an instruction. The lines following the
+// instruction, up to a line that only contains the delimiter word, are the
+// content of the here-document.
+//
+// RUN <<EOF
+// apt-get update
+// apt-get install -y curl
+// EOF
+//
+type Heredoc struct {
+	Name    string // the delimiter word
+	Content string // the lines of the here-document, including line breaks
+	Expand  bool   // false if the delimiter word is quoted
+	Chomp   bool   // true for <<-, leading tabs are removed from the lines
+}
+
+var tokenHeredoc = regexp.MustCompile(`^<<(-?)(["']?)([a-zA-Z_][a-zA-Z0-9_]*)(["']?)$`)
+
+// heredocCommands are the instructions that support here-documents
+var heredocCommands = map[string]bool{
+	command.Copy: true,
+	command.Run:  true,
+}
+
+// ParseHeredoc returns the here-document started by word, or nil if word is
+// not a here-document redirection (<<EOF, <<-EOF, <<"EOF" or <<'EOF'). The
+// content of the returned here-document is empty.
+func ParseHeredoc(word string) *Heredoc {
+	match := tokenHeredoc.FindStringSubmatch(word)
+	if match == nil || match[2] != match[4] {
+		return nil
+	}
+	return &Heredoc{
+		Name:   match[3],
+		Expand: match[2] == "",
+		Chomp:  match[1] == "-",
+	}
+}
+
+// heredocsFromLine returns the here-documents started by an instruction
+// line, in the order they appear on the line. The sources of COPY are words,
+// the command of RUN is tokenized as a shell command line.
+func heredocsFromLine(line string) ([]Heredoc, error) {
+	cmd, _, args, err := splitCommand(line)
+	if err != nil || !heredocCommands[cmd] {
+		return nil, err
+	}
+	if cmd == command.Run {
+		return shellHeredocs(args), nil
+	}
+	var heredocs []Heredoc
+	for _, word := range strings.Fields(args) {
+		if heredoc := ParseHeredoc(word); heredoc != nil {
+			heredocs = append(heredocs, *heredoc)
+		}
+	}
+	return heredocs, nil
+}
+
+// shellHeredocs returns the here-documents started by the << operators of a
+// shell command line, with or without blanks around them (cat<<EOF>file).
+// Operators in quoted strings or comments, escaped ones and here-strings
+// (<<<) are skipped.
+func shellHeredocs(cmdLine string) []Heredoc {
+	var heredocs []Heredoc
+	var quote byte
+	for i := 0; i < len(cmdLine); i++ {
+		c := cmdLine[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '#' && (i == 0 || isShellBlank(cmdLine[i-1])):
+			return heredocs
+		case strings.HasPrefix(cmdLine[i:], "<<<"):
+			i += 2
+		case strings.HasPrefix(cmdLine[i:], "<<"):
+			word, n := heredocWord(cmdLine[i+2:])
+			if heredoc := ParseHeredoc("<<" + word); heredoc != nil {
+				heredocs = append(heredocs, *heredoc)
+			}
+			i += 1 + n
+		}
+	}
+	return heredocs
+}
+
+// heredocWord returns the optional - and the delimiter word following a <<
+// operator, without the blanks between them, and the number of bytes of s
+// they span
+func heredocWord(s string) (string, int) {
+	i := 0
+	prefix := ""
+	if strings.HasPrefix(s, "-") {
+		prefix = "-"
+		i++
+	}
+	for i < len(s) && isShellBlank(s[i]) {
+		i++
+	}
+	start := i
+	for i < len(s) && !isShellBlank(s[i]) && !strings.ContainsRune(";&|<>()", rune(s[i])) {
+		i++
+	}
+	return prefix + s[start:i], i
+}
+
+func isShellBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// readHeredoc reads the content of heredoc from the lines of scanner, up to
+// the delimiter line. It returns the number of lines read.
+func readHeredoc(scanner *bufio.Scanner, heredoc *Heredoc) (int, error) {
+	var content []string
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		// the delimiter must match with CRLF line endings too, whatever the
+		// split function of scanner
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if heredoc.Chomp {
+			line = strings.TrimLeft(line, "\t")
+		}
+		if line == heredoc.Name {
+			heredoc.Content = strings.Join(content, "")
+			return lines, nil
+		}
+		content = append(content, line+"\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return lines, err
+	}
+	return lines, errors.Errorf("unterminated heredoc %s", heredoc.Name)
+}
@@ -36,6 +36,7 @@ type Node struct {
 	Attributes map[string]bool // special attributes for this node
 	Original   string          // original line used before parsing
 	Flags      []string        // only top Node should have this set
+	Heredocs   []Heredoc       // only top Node should have this set
 	StartLine  int             // the line in the original dockerfile where the node begins
 	endLine    int             // the line in the original dockerfile where the node ends
 }
@@ -310,6 +311,18 @@ func Parse(rwc io.Reader) (*Result, error) {
 		if err != nil {
 			return nil, err
 		}
+		heredocs, err := heredocsFromLine(line)
+		if err != nil {
+			return nil, err
+		}
+		for i := range heredocs {
+			lines, err := readHeredoc(scanner, &heredocs[i])
+			currentLine += lines
+			if err != nil {
+				return nil, errors.Wrapf(err, "Dockerfile parse error line %d", startLine)
+			}
+		}
+		child.Heredocs = heredocs
 		root.AddChild(child, startLine, currentLine)
 	}
 
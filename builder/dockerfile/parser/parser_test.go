@@ -159,3 +159,120 @@ RUN indented \
 	assert.Contains(t, warnings[1], "RUN another     thing")
 	assert.Contains(t, warnings[2], "will become errors in a future release")
 }
+
+func TestParseHeredocs(t *testing.T) {
+	dockerfile := bytes.NewBufferString(`FROM busybox
+RUN <<EOF
+echo $HOME
+  indented
+EOF
+COPY <<'CONF' <<-SCRIPT /etc/
+# not a comment
+CONF
+	#!/bin/sh
+	exit 0
+	SCRIPT
+RUN echo done
+`)
+
+	result, err := Parse(dockerfile)
+	require.NoError(t, err)
+	children := result.AST.Children
+	require.Len(t, children, 4)
+
+	assert.Equal(t, "RUN <<EOF", children[1].Original)
+	assert.Equal(t, []Heredoc{
+		{Name: "EOF", Content: "echo $HOME\n  indented\n", Expand: true},
+	}, children[1].Heredocs)
+	assert.Equal(t, []int{2, 5}, []int{children[1].StartLine, children[1].endLine})
+
+	assert.Equal(t, []Heredoc{
+		{Name: "CONF", Content: "# not a comment\n"},
+		{Name: "SCRIPT", Content: "#!/bin/sh\nexit 0\n", Expand: true, Chomp: true},
+	}, children[2].Heredocs)
+	assert.Equal(t, []int{6, 11}, []int{children[2].StartLine, children[2].endLine})
+
+	assert.Equal(t, "RUN echo done", children[3].Original)
+	assert.Nil(t, children[3].Heredocs)
+}
+
+func TestParseShellHeredocs(t *testing.T) {
+	dockerfile := bytes.NewBufferString(`FROM busybox
+RUN cat<<EOF>/hello
+hello
+EOF
+RUN cat <<-EOF \
+  > /indented
+	indented
+	EOF
+RUN echo "<<EOF" '<<EOF' \<<EOF <<<EOF \
+  && echo not a heredoc # <<EOF
+RUN echo done
+`)
+
+	result, err := Parse(dockerfile)
+	require.NoError(t, err)
+	children := result.AST.Children
+	require.Len(t, children, 5)
+
+	assert.Equal(t, []Heredoc{
+		{Name: "EOF", Content: "hello\n", Expand: true},
+	}, children[1].Heredocs)
+	assert.Equal(t, []int{2, 4}, []int{children[1].StartLine, children[1].endLine})
+
+	assert.Equal(t, []Heredoc{
+		{Name: "EOF", Content: "indented\n", Expand: true, Chomp: true},
+	}, children[2].Heredocs)
+	assert.Equal(t, []int{5, 8}, []int{children[2].StartLine, children[2].endLine})
+
+	assert.Nil(t, children[3].Heredocs)
+	assert.Equal(t, []int{9, 10}, []int{children[3].StartLine, children[3].endLine})
+	assert.Equal(t, "RUN echo done", children[4].Original)
+}
+
+func TestShellHeredocs(t *testing.T) {
+	testCases := []struct {
+		cmdLine  string
+		expected []Heredoc
+	}{
+		{cmdLine: "<<EOF", expected: []Heredoc{{Name: "EOF", Expand: true}}},
+		{cmdLine: "cat<<EOF>file", expected: []Heredoc{{Name: "EOF", Expand: true}}},
+		{cmdLine: "cat <<-EOF", expected: []Heredoc{{Name: "EOF", Expand: true, Chomp: true}}},
+		{cmdLine: "cat << 'EOF' | sh", expected: []Heredoc{{Name: "EOF"}}},
+		{cmdLine: `cat <<"A";cat <<B`, expected: []Heredoc{{Name: "A"}, {Name: "B", Expand: true}}},
+		{cmdLine: `echo "a <<EOF b"`},
+		{cmdLine: "echo 'a <<EOF b'"},
+		{cmdLine: `echo \<<EOF`},
+		{cmdLine: "cat <<<EOF"},
+		{cmdLine: "echo $((1<<2))"},
+		{cmdLine: "echo # <<EOF"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, shellHeredocs(tc.cmdLine), tc.cmdLine)
+	}
+}
+
+func TestParseHeredocsCRLF(t *testing.T) {
+	dockerfile := bytes.NewBufferString("FROM busybox\r\nRUN <<EOF\r\necho hello\r\nEOF\r\nRUN echo done\r\n")
+
+	result, err := Parse(dockerfile)
+	require.NoError(t, err)
+	children := result.AST.Children
+	require.Len(t, children, 3)
+
+	assert.Equal(t, []Heredoc{
+		{Name: "EOF", Content: "echo hello\n", Expand: true},
+	}, children[1].Heredocs)
+	assert.Equal(t, "RUN echo done", children[2].Original)
+}
+
+func TestParseHeredoc(t *testing.T) {
+	assert.Equal(t, &Heredoc{Name: "EOF", Expand: true}, ParseHeredoc("<<EOF"))
+	assert.Equal(t, &Heredoc{Name: "EOF", Chomp: true, Expand: true}, ParseHeredoc("<<-EOF"))
+	assert.Equal(t, &Heredoc{Name: "EOF"}, ParseHeredoc(`<<"EOF"`))
+	assert.Equal(t, &Heredoc{Name: "EOF"}, ParseHeredoc("<<'EOF'"))
+	assert.Nil(t, ParseHeredoc(`<<"EOF'`))
+	assert.Nil(t, ParseHeredoc("<<"))
+	assert.Nil(t, ParseHeredoc("<<EOF>file"))
+	assert.Nil(t, ParseHeredoc("EOF"))
+}
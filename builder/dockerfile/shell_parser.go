@@ -44,6 +44,23 @@ func (s *ShellLex) ProcessWords(word string, env []string) ([]string, error) {
 	return words, err
 }
 
+// ProcessHeredoc will use the 'env' list of environment variables, and
+// replace any env var references in the content of a here-document. Like in
+// a shell, quotes are taken as-is and only $ and the escape token can be
+// escaped.
+func (s *ShellLex) ProcessHeredoc(content string, env []string) (string, error) {
+	sw := &shellWord{
+		envs:        env,
+		escapeToken: s.escapeToken,
+	}
+	sw.scanner.Init(strings.NewReader(content))
+	result, err := sw.processHeredoc()
+	if err != nil {
+		err = errors.Wrap(err, "failed to process heredoc")
+	}
+	return result, err
+}
+
 func (s *ShellLex) process(word string, env []string) (string, []string, error) {
 	sw := &shellWord{
 		envs:        env,
@@ -246,6 +263,33 @@ func (sw *shellWord) processDoubleQuote() (string, error) {
 	}
 }
 
+func (sw *shellWord) processHeredoc() (string, error) {
+	var result bytes.Buffer
+
+	for {
+		switch sw.scanner.Peek() {
+		case scanner.EOF:
+			return result.String(), nil
+		case '$':
+			value, err := sw.processDollar()
+			if err != nil {
+				return "", err
+			}
+			result.WriteString(value)
+		default:
+			ch := sw.scanner.Next()
+			if ch == sw.escapeToken {
+				switch sw.scanner.Peek() {
+				case '$', sw.escapeToken:
+					// These chars can be escaped, all other \'s are left as-is
+					ch = sw.scanner.Next()
+				}
+			}
+			result.WriteRune(ch)
+		}
+	}
+}
+
 func (sw *shellWord) processDollar() (string, error) {
 	sw.scanner.Next()
 
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestShellParser4EnvVars(t *testing.T) {
@@ -112,6 +113,22 @@ func TestShellParser4Words(t *testing.T) {
 	}
 }
 
+func TestShellParserHeredoc(t *testing.T) {
+	shlex := NewShellLex('\\')
+	envs := []string{"PORT=8080", "NAME=app"}
+
+	content, err := shlex.ProcessHeredoc("name = \"$NAME\"\nport = '${PORT}'\n", envs)
+	require.NoError(t, err)
+	assert.Equal(t, "name = \"app\"\nport = '8080'\n", content)
+
+	content, err = shlex.ProcessHeredoc("echo \\$HOME \\\\ \\n ${UNSET:-default}\n", envs)
+	require.NoError(t, err)
+	assert.Equal(t, "echo $HOME \\ \\n default\n", content)
+
+	_, err = shlex.ProcessHeredoc("${PORT:?}", envs)
+	assert.EqualError(t, err, "failed to process heredoc: unsupported modifier (?) in substitution")
+}
+
 func TestGetEnv(t *testing.T) {
 	sw := &shellWord{envs: nil}
 
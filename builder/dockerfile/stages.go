@@ -0,0 +1,402 @@
+package dockerfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+)
+
+// errStageCancelled is returned by a stage that stopped because the build was
+// cancelled or another stage failed
+var errStageCancelled = errors.New("build stage cancelled")
+
+// stageGraph holds the dependencies between the stages of a Dockerfile. A
+// stage depends on the previous stages it uses as its base image
+// (FROM <stage>) or copies files from (COPY --from=<stage>). Stages that do
+// not depend on each other are built concurrently.
+type stageGraph struct {
+	stages []instructions.Stage
+	// deps holds the indexes of the stages each stage depends on
+	deps [][]int
+	// needed is false for the stages that are not required to build the
+	// last stage when a target is set
+	needed []bool
+}
+
+// newStageGraph computes the dependencies of stages. Base image names are
+// expanded with the meta args in buildArgs, like they are when the stage is
+// initialized. If pruneUnused is set, only the last stage and the stages it
+// depends on are marked as needed.
+func newStageGraph(stages []instructions.Stage, shlex *ShellLex, buildArgs *buildArgs, pruneUnused bool) (*stageGraph, error) {
+	metaArgs := convertMapToEnvList(buildArgs.GetAllMeta())
+	g := &stageGraph{
+		stages: stages,
+		deps:   make([][]int, len(stages)),
+		needed: make([]bool, len(stages)),
+	}
+	names := make(map[string]int)
+	for i, stage := range stages {
+		deps := make(map[int]struct{})
+		baseName, err := shlex.ProcessWord(stage.BaseName, metaArgs)
+		if err != nil {
+			return nil, err
+		}
+		if ix, ok := names[strings.ToLower(baseName)]; ok {
+			deps[ix] = struct{}{}
+		}
+		for _, cmd := range stage.Commands {
+			c, ok := cmd.(*instructions.CopyCommand)
+			if !ok || c.From == "" {
+				continue
+			}
+			if ix, ok := names[strings.ToLower(c.From)]; ok {
+				deps[ix] = struct{}{}
+			} else if ix, err := strconv.Atoi(c.From); err == nil && ix >= 0 && ix < i {
+				deps[ix] = struct{}{}
+			}
+		}
+		for ix := 0; ix < i; ix++ {
+			if _, ok := deps[ix]; ok {
+				g.deps[i] = append(g.deps[i], ix)
+			}
+		}
+
+		if stage.Name != "" {
+			name := strings.ToLower(stage.Name)
+			if _, ok := names[name]; ok {
+				return nil, errors.Errorf("%s stage name already used", stage.Name)
+			}
+			names[name] = i
+		}
+	}
+
+	if len(stages) == 0 {
+		return g, nil
+	}
+	if !pruneUnused {
+		for i := range g.needed {
+			g.needed[i] = true
+		}
+		return g, nil
+	}
+	g.markNeeded(len(stages) - 1)
+	return g, nil
+}
+
+func (g *stageGraph) markNeeded(i int) {
+	if g.needed[i] {
+		return
+	}
+	g.needed[i] = true
+	for _, dep := range g.deps[i] {
+		g.markNeeded(dep)
+	}
+}
+
+// dependsOn returns true if stage i depends on stage j, directly or through
+// other stages
+func (g *stageGraph) dependsOn(i, j int) bool {
+	for _, dep := range g.deps[i] {
+		if dep == j || g.dependsOn(dep, j) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLinear returns true if every needed stage depends on the needed stage
+// before it, in which case no stages are built concurrently
+func (g *stageGraph) isLinear() bool {
+	prev := -1
+	for i, needed := range g.needed {
+		if !needed {
+			continue
+		}
+		if prev != -1 && !g.dependsOn(i, prev) {
+			return false
+		}
+		prev = i
+	}
+	return true
+}
+
+func (g *stageGraph) label(i int) string {
+	if name := g.stages[i].Name; name != "" {
+		return name
+	}
+	return "stage-" + strconv.Itoa(i)
+}
+
+// stageRun holds what is shared by the stages of a build while they are
+// dispatched
+type stageRun struct {
+	builder     *Builder
+	graph       *stageGraph
+	escapeToken rune
+	source      builder.Source
+
+	// firstSteps holds the step number of the FROM instruction of each stage
+	firstSteps    []int
+	totalCommands int
+
+	argsMu    sync.Mutex
+	buildArgs *buildArgs
+
+	states  []*dispatchState
+	reports []types.BuildStageReport
+	done    []chan struct{}
+}
+
+// dispatchStages builds the needed stages of the graph, each one as soon as
+// the stages it depends on have been built, and returns the state of the last
+// stage and the report of the build.
+func (b *Builder) dispatchStages(r *stageRun) (*dispatchState, *types.BuildReport, error) {
+	start := time.Now()
+	last := len(r.graph.stages) - 1
+	if last < 0 {
+		return newDispatchState(r.buildArgs), nil, nil
+	}
+	r.states = make([]*dispatchState, len(r.graph.stages))
+	r.reports = make([]types.BuildStageReport, len(r.graph.stages))
+	r.done = make([]chan struct{}, len(r.graph.stages))
+	for i := range r.done {
+		r.done[i] = make(chan struct{})
+	}
+
+	concurrent := !r.graph.isLinear()
+	group, ctx := errgroup.WithContext(b.clientCtx)
+	for i, needed := range r.graph.needed {
+		if !needed {
+			continue
+		}
+		i := i
+		group.Go(func() error {
+			stdout, stderr := b.Stdout, b.Stderr
+			if concurrent {
+				prefix := "[" + r.graph.label(i) + "] "
+				pStdout, pStderr := newPrefixWriter(stdout, prefix), newPrefixWriter(stderr, prefix)
+				defer pStdout.Flush()
+				defer pStderr.Flush()
+				stdout, stderr = pStdout, pStderr
+			}
+			return r.dispatchStage(ctx, i, b.newStageBuilder(ctx, stdout, stderr), i != last)
+		})
+	}
+
+	err := group.Wait()
+	if err == errStageCancelled {
+		logrus.Debug("Builder: build cancelled!")
+		fmt.Fprint(b.Stdout, "Build cancelled\n")
+		buildsFailed.WithValues(metricsBuildCanceled).Inc()
+		return nil, nil, errors.New("Build cancelled")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &types.BuildReport{ID: r.states[last].imageID, Duration: time.Since(start)}
+	for i, needed := range r.graph.needed {
+		if needed {
+			report.Stages = append(report.Stages, r.reports[i])
+		}
+	}
+	// the image ID of the last stage is emitted last as it is the result of
+	// the build
+	if err := emitImageID(b.Aux, r.states[last], report); err != nil {
+		return nil, nil, err
+	}
+	return r.states[last], report, nil
+}
+
+func (r *stageRun) dispatchStage(ctx context.Context, i int, b *Builder, emitID bool) error {
+	for _, dep := range r.graph.deps[i] {
+		select {
+		case <-r.done[dep]:
+		case <-ctx.Done():
+			return errStageCancelled
+		}
+	}
+
+	r.argsMu.Lock()
+	dispatchRequest := newDispatchRequest(b, r.escapeToken, r.source, r.buildArgs, r.previousResults(ctx, i))
+	r.argsMu.Unlock()
+
+	start := time.Now()
+	stage := &r.graph.stages[i]
+	currentCommandIndex := printCommand(b.Stdout, r.firstSteps[i], r.totalCommands, stage.SourceCode)
+	if err := initializeStage(dispatchRequest, stage); err != nil {
+		return err
+	}
+	dispatchRequest.state.updateRunConfig()
+	fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
+	report := types.BuildStageReport{Index: i, Name: stage.Name, BaseImage: dispatchRequest.state.imageID}
+	for _, cmd := range stage.Commands {
+		select {
+		case <-ctx.Done():
+			return errStageCancelled
+		default:
+			// Not cancelled yet, keep going...
+		}
+
+		currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, r.totalCommands, cmd)
+
+		stepStart, parentID := time.Now(), dispatchRequest.state.imageID
+		b.cacheStatus = ""
+		if err := dispatch(dispatchRequest, cmd); err != nil {
+			return err
+		}
+		dispatchRequest.state.updateRunConfig()
+		fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
+
+		report.Steps = append(report.Steps, b.stepReport(cmd, parentID, dispatchRequest.state, time.Since(stepStart)))
+	}
+	if emitID {
+		if err := emitImageID(b.Aux, dispatchRequest.state, nil); err != nil {
+			return err
+		}
+	}
+	report.Duration = time.Since(start)
+	r.reports[i] = report
+
+	r.argsMu.Lock()
+	r.buildArgs.MergeReferencedArgs(dispatchRequest.state.buildArgs)
+	r.argsMu.Unlock()
+
+	r.states[i] = dispatchRequest.state
+	close(r.done[i])
+	return nil
+}
+
+// stepReport returns the report of cmd, which was dispatched in duration and
+// changed the image of the stage from parentID to the image of state. The
+// layer is left out of the report if it cannot be looked up.
+func (b *Builder) stepReport(cmd instructions.Command, parentID string, state *dispatchState, duration time.Duration) types.BuildStepReport {
+	step := types.BuildStepReport{
+		Instruction: fmt.Sprint(cmd),
+		Cache:       b.cacheStatus,
+		Duration:    duration,
+		ImageID:     state.imageID,
+	}
+	if state.imageID == "" || state.imageID == parentID {
+		return step
+	}
+	diffID, size, err := b.docker.ImageTopLayer(state.imageID)
+	if err != nil {
+		logrus.Warnf("failed to get the layer of image %s for the build report: %v", state.imageID, err)
+		return step
+	}
+	step.LayerDigest = diffID.String()
+	step.LayerSize = size
+	return step
+}
+
+// previousResults returns the results of the stages before stage i. The stages
+// i depends on have been built already. The other ones may still be building,
+// a lookup from an ONBUILD trigger waits for them.
+func (r *stageRun) previousResults(ctx context.Context, i int) *stagesBuildResults {
+	results := newStagesBuildResults()
+	results.pending = make(map[string]int)
+	results.waitFor = func(ix int) (*container.Config, error) {
+		if !r.graph.needed[ix] {
+			return nil, errors.Errorf("stage %s is not built for target %s", r.graph.label(ix), r.builder.options.Target)
+		}
+		select {
+		case <-r.done[ix]:
+			return r.states[ix].runConfig, nil
+		case <-ctx.Done():
+			return nil, errStageCancelled
+		}
+	}
+	for ix, stage := range r.graph.stages[:i] {
+		var config *container.Config
+		select {
+		case <-r.done[ix]:
+			config = r.states[ix].runConfig
+		default:
+		}
+		if stage.Name != "" {
+			if config != nil {
+				results.indexed[strings.ToLower(stage.Name)] = config
+			} else {
+				results.pending[strings.ToLower(stage.Name)] = ix
+			}
+		}
+		results.flat = append(results.flat, config)
+	}
+	return results
+}
+
+// newStageBuilder returns a copy of the builder to dispatch a stage with. The
+// copy has its own cache prober and temporary containers, and writes its
+// output to stdout and stderr.
+func (b *Builder) newStageBuilder(ctx context.Context, stdout, stderr io.Writer) *Builder {
+	sb := *b
+	sb.clientCtx = ctx
+	sb.Stdout = stdout
+	sb.Stderr = stderr
+	sb.imageProber = newImageProber(b.cacheBuilder, b.options.CacheFrom, b.os, b.options.NoCache)
+	sb.containerManager = newContainerManager(b.docker)
+	return &sb
+}
+
+// prefixWriter writes each line written to it to the underlying writer with
+// prefix prepended. Incomplete lines are buffered until they are completed or
+// the writer is flushed.
+type prefixWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+func newPrefixWriter(out io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{out: out, prefix: []byte(prefix)}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes the buffered incomplete line, if any
+func (w *prefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.writeLine(append(w.buf, '\n'))
+	w.buf = nil
+	return err
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	_, err := w.out.Write(append(append([]byte{}, w.prefix...), line...))
+	return err
+}
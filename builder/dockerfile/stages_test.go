@@ -0,0 +1,318 @@
+package dockerfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func parseStages(t *testing.T, dockerfile string) ([]instructions.Stage, *buildArgs) {
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	stages, metaArgs, err := instructions.Parse(result.AST)
+	require.NoError(t, err)
+	args := newBuildArgs(nil)
+	for _, meta := range metaArgs {
+		require.NoError(t, processMetaArg(meta, NewShellLex('\\'), args))
+	}
+	return stages, args
+}
+
+func TestStageGraph(t *testing.T) {
+	dockerfile := `ARG BASE=base
+FROM busybox AS base
+FROM busybox AS Tools
+FROM golang AS build
+COPY --from=tools /bin/tool /bin/tool
+FROM ${BASE}
+COPY --from=2 /out /out
+COPY --from=later /foo /foo
+FROM busybox AS later
+`
+	stages, args := parseStages(t, dockerfile)
+
+	graph, err := newStageGraph(stages, NewShellLex('\\'), args, false)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{nil, nil, {1}, {0, 2}, nil}, graph.deps)
+	assert.Equal(t, []bool{true, true, true, true, true}, graph.needed)
+	assert.False(t, graph.isLinear())
+	assert.Equal(t, "tools", graph.label(1))
+	assert.Equal(t, "stage-3", graph.label(3))
+
+	graph, err = newStageGraph(stages[:4], NewShellLex('\\'), args, true)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true, true}, graph.needed)
+
+	graph, err = newStageGraph(stages[:3], NewShellLex('\\'), args, true)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, true, true}, graph.needed)
+	assert.True(t, graph.isLinear())
+}
+
+func TestStageGraphDuplicateName(t *testing.T) {
+	stages, args := parseStages(t, "FROM busybox AS foo\nFROM busybox AS FOO\n")
+	_, err := newStageGraph(stages, NewShellLex('\\'), args, false)
+	assert.EqualError(t, err, "foo stage name already used")
+}
+
+func TestPrefixWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := newPrefixWriter(buf, "[foo] ")
+	w.Write([]byte("Step 1/2 : "))
+	w.Write([]byte("RUN true\nhello\nwor"))
+	w.Write([]byte("ld"))
+	assert.Equal(t, "[foo] Step 1/2 : RUN true\n[foo] hello\n", buf.String())
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "[foo] Step 1/2 : RUN true\n[foo] hello\n[foo] world\n", buf.String())
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newStagesTestBuilder(mockBackend *MockBackend, target string, stdout *syncBuffer) *Builder {
+	return newBuilder(context.Background(), builderOptions{
+		Options: &types.ImageBuildOptions{NoCache: true, Target: target},
+		Backend: mockBackend,
+		ProgressWriter: backend.ProgressWriter{
+			StdoutFormatter: stdout,
+			StderrFormatter: stdout,
+		},
+	}, runtime.GOOS)
+}
+
+func TestBuildIndependentStagesConcurrently(t *testing.T) {
+	dockerfile := `FROM busybox AS a
+LABEL stage=a
+FROM busybox AS b
+LABEL stage=b
+FROM a
+LABEL stage=final
+`
+	bCommitted := make(chan struct{})
+	mockBackend := &MockBackend{}
+	mockBackend.commitFunc = func(cID string, cfg *backend.ContainerCommitConfig) (string, error) {
+		stage := cfg.Config.Labels["stage"]
+		switch stage {
+		case "a":
+			select {
+			case <-bCommitted:
+			case <-time.After(10 * time.Second):
+				return "", errors.New("stages a and b were not built concurrently")
+			}
+		case "b":
+			close(bCommitted)
+		}
+		return "id-" + stage, nil
+	}
+
+	stdout := &syncBuffer{}
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	res, err := newStagesTestBuilder(mockBackend, "", stdout).build(nil, result)
+	require.NoError(t, err)
+	assert.Equal(t, "id-final", res.ImageID)
+
+	out := stdout.String()
+	assert.Contains(t, out, "[a] Step 1/6 : FROM busybox AS a\n")
+	assert.Contains(t, out, "[b] Step 4/6 : LABEL stage=b\n")
+	assert.Contains(t, out, "[stage-2] Step 5/6 : FROM a\n")
+	assert.Contains(t, out, "[stage-2]  ---> id-final\n")
+}
+
+func TestBuildTargetSkipsUnneededStages(t *testing.T) {
+	dockerfile := `FROM busybox AS a
+LABEL stage=a
+FROM busybox AS b
+LABEL stage=b
+FROM b AS c
+LABEL stage=c
+FROM busybox
+LABEL stage=final
+`
+	var committed []string
+	mockBackend := &MockBackend{}
+	mockBackend.commitFunc = func(cID string, cfg *backend.ContainerCommitConfig) (string, error) {
+		stage := cfg.Config.Labels["stage"]
+		committed = append(committed, stage)
+		return "id-" + stage, nil
+	}
+
+	stdout := &syncBuffer{}
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	res, err := newStagesTestBuilder(mockBackend, "c", stdout).build(nil, result)
+	require.NoError(t, err)
+	assert.Equal(t, "id-c", res.ImageID)
+	assert.Equal(t, []string{"b", "c"}, committed)
+
+	out := stdout.String()
+	assert.NotContains(t, out, "Step 1/6")
+	assert.Contains(t, out, "Step 3/6 : FROM busybox AS b\n")
+	assert.Contains(t, out, "Step 5/6 : FROM b AS c\n")
+}
+
+func TestBuildExportsCache(t *testing.T) {
+	dockerfile := `FROM busybox AS a
+LABEL stage=a
+FROM busybox
+LABEL stage=final
+`
+	var exportedRef string
+	var exportedIDs []string
+	mockBackend := &MockBackend{}
+	mockBackend.commitFunc = func(cID string, cfg *backend.ContainerCommitConfig) (string, error) {
+		return "id-" + cfg.Config.Labels["stage"], nil
+	}
+	mockBackend.exportCacheFunc = func(ref string, imageIDs []string) error {
+		exportedRef = ref
+		exportedIDs = imageIDs
+		return nil
+	}
+
+	stdout := &syncBuffer{}
+	b := newStagesTestBuilder(mockBackend, "", stdout)
+	b.options.CacheTo = "registry.example.com/app:buildcache"
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	_, err = b.build(nil, result)
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/app:buildcache", exportedRef)
+	assert.Equal(t, []string{"id-final"}, exportedIDs)
+	assert.Contains(t, stdout.String(), "Exporting build cache to registry.example.com/app:buildcache\n")
+
+	b.options.CacheToMode = "max"
+	result, err = parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	_, err = b.build(nil, result)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id-a", "id-final"}, exportedIDs)
+
+	mockBackend.exportCacheFunc = func(ref string, imageIDs []string) error {
+		return errors.New("denied")
+	}
+	result, err = parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	_, err = b.build(nil, result)
+	assert.EqualError(t, err, "failed to export build cache: denied")
+}
+
+func TestBuildReport(t *testing.T) {
+	dockerfile := `FROM busybox AS a
+LABEL stage=a
+FROM a
+LABEL stage=final
+LABEL stage=final2
+`
+	mockBackend := &MockBackend{}
+	mockBackend.commitFunc = func(cID string, cfg *backend.ContainerCommitConfig) (string, error) {
+		return "id-" + cfg.Config.Labels["stage"], nil
+	}
+	mockBackend.makeImageCacheFunc = func(_ []string, _ string) builder.ImageCache {
+		return &mockImageCache{getCacheFunc: func(parentID string, cfg *container.Config) (string, error) {
+			if cfg.Labels["stage"] == "a" {
+				return "cached-a", nil
+			}
+			return "", nil
+		}}
+	}
+	mockBackend.topLayerFunc = func(imageID string) (layer.DiffID, int64, error) {
+		switch imageID {
+		case "cached-a":
+			return layer.DiffID("sha256:aaa"), 10, nil
+		case "id-final":
+			return layer.DiffID("sha256:fff"), 20, nil
+		case "id-final2":
+			// the build does not fail, the layer is left out of the report
+			return "", 0, errors.New("layer not found")
+		}
+		return "", 0, nil
+	}
+
+	stdout := &syncBuffer{}
+	aux := &bytes.Buffer{}
+	b := newStagesTestBuilder(mockBackend, "", stdout)
+	b.options.NoCache = false
+	b.Aux = &streamformatter.AuxFormatter{Writer: aux}
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	res, err := b.build(nil, result)
+	require.NoError(t, err)
+	require.NotNil(t, res.Report)
+
+	var results []types.BuildResult
+	dec := json.NewDecoder(aux)
+	for dec.More() {
+		var msg jsonmessage.JSONMessage
+		require.NoError(t, dec.Decode(&msg))
+		var result types.BuildResult
+		require.NoError(t, json.Unmarshal(*msg.Aux, &result))
+		results = append(results, result)
+	}
+	require.Len(t, results, 2)
+	assert.Equal(t, types.BuildResult{ID: "cached-a"}, results[0])
+	buildResult := results[1]
+	assert.Equal(t, "id-final2", buildResult.ID)
+	require.NotNil(t, buildResult.Report)
+
+	for _, report := range []*types.BuildReport{res.Report, buildResult.Report} {
+		assert.True(t, report.Duration > 0)
+		for i, stage := range report.Stages {
+			assert.True(t, stage.Duration > 0)
+			report.Stages[i].Duration = 0
+			for j := range stage.Steps {
+				stage.Steps[j].Duration = 0
+			}
+		}
+		assert.Equal(t, "id-final2", report.ID)
+		assert.Equal(t, []types.BuildStageReport{
+			{
+				Index:     0,
+				Name:      "a",
+				BaseImage: "theid",
+				Steps: []types.BuildStepReport{
+					{Instruction: "LABEL stage=a", Cache: types.BuildCacheHit, ImageID: "cached-a", LayerDigest: "sha256:aaa", LayerSize: 10},
+				},
+			},
+			{
+				Index:     1,
+				BaseImage: "theid",
+				Steps: []types.BuildStepReport{
+					{Instruction: "LABEL stage=final", Cache: types.BuildCacheMiss, ImageID: "id-final", LayerDigest: "sha256:fff", LayerSize: 20},
+					{Instruction: "LABEL stage=final2", Cache: types.BuildCacheMiss, ImageID: "id-final2"},
+				},
+			},
+		}, report.Stages)
+	}
+}
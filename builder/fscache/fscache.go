@@ -29,6 +29,7 @@ import (
 const dbFile = "fscache.db"
 const cacheKey = "cache"
 const metaKey = "meta"
+const cacheMountPrefix = "cache-mount:"
 
 // Backend is a backing implementation for FSCache
 type Backend interface {
@@ -40,6 +41,7 @@ type Backend interface {
 type FSCache struct {
 	opt        Opt
 	transports map[string]Transport
+	uploads    map[string]*pendingUpload
 	mu         sync.Mutex
 	g          singleflight.Group
 	store      *fsCacheStore
@@ -68,6 +70,7 @@ func NewFSCache(opt Opt) (*FSCache, error) {
 		store:      store,
 		opt:        opt,
 		transports: make(map[string]Transport),
+		uploads:    make(map[string]*pendingUpload),
 	}, nil
 }
 
@@ -153,6 +156,24 @@ func (fsc *FSCache) SyncFrom(ctx context.Context, id RemoteIdentifier) (builder.
 	return wc, nil
 }
 
+// CacheMountRef is a reference to the directory of a cache mount
+type CacheMountRef interface {
+	Dir() string
+	Release() error
+}
+
+// CacheMount returns a reference to the persistent directory of the cache
+// mount with the given id, creating it if it doesn't exist yet. Like synced
+// sources, the directory is garbage collected and pruned once all references
+// to it have been released.
+func (fsc *FSCache) CacheMount(id string) (CacheMountRef, error) {
+	ref, err := fsc.store.GetOrNew(cacheMountPrefix + id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get cache mount %s", id)
+	}
+	return &cacheMountRef{cachedSourceRef: ref}, nil
+}
+
 // DiskUsage reports how much data is allocated by the cache
 func (fsc *FSCache) DiskUsage() (int64, error) {
 	return fsc.store.DiskUsage()
@@ -169,26 +190,9 @@ func (fsc *FSCache) Close() error {
 }
 
 func syncFrom(ctx context.Context, cs *cachedSourceRef, transport Transport, id RemoteIdentifier) (retErr error) {
-	src := cs.src
-	if src == nil {
-		src = remotecontext.NewCachableSource(cs.Dir())
-	}
-
-	if !cs.cached {
-		if err := cs.storage.db.View(func(tx *bolt.Tx) error {
-			b := tx.Bucket([]byte(id.Key()))
-			dt := b.Get([]byte(cacheKey))
-			if dt != nil {
-				if err := src.UnmarshalBinary(dt); err != nil {
-					return err
-				}
-			} else {
-				return errors.Wrap(src.Scan(), "failed to scan cache records")
-			}
-			return nil
-		}); err != nil {
-			return err
-		}
+	src, err := cs.cacheRecords()
+	if err != nil {
+		return err
 	}
 
 	dc := &detectChanges{f: src.HandleChange}
@@ -294,6 +298,22 @@ func (s *fsCacheStore) Close() error {
 func (s *fsCacheStore) New(id, sharedKey string) (*cachedSourceRef, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.new(id, sharedKey)
+}
+
+// GetOrNew returns a reference to the source with id, creating an empty one
+// if it doesn't exist yet
+func (s *fsCacheStore) GetOrNew(id string) (*cachedSourceRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if src, ok := s.sources[id]; ok {
+		return src.getRef(), nil
+	}
+	return s.new(id, "")
+}
+
+// keep mu while calling this
+func (s *fsCacheStore) new(id, sharedKey string) (*cachedSourceRef, error) {
 	var ret *cachedSource
 	if err := s.db.Update(func(tx *bolt.Tx) error {
 		b, err := tx.CreateBucket([]byte(id))
@@ -339,6 +359,11 @@ func (s *fsCacheStore) Rebase(sharedKey, newid string) (*cachedSourceRef, error)
 	for id, snap := range s.sources {
 		if snap.SharedKey == sharedKey && len(snap.refs) == 0 {
 			if err := s.db.Update(func(tx *bolt.Tx) error {
+				// the cache records are still valid for the directory
+				var records []byte
+				if dt := tx.Bucket([]byte(id)).Get([]byte(cacheKey)); dt != nil {
+					records = append(records, dt...)
+				}
 				if err := tx.DeleteBucket([]byte(id)); err != nil {
 					return err
 				}
@@ -346,6 +371,11 @@ func (s *fsCacheStore) Rebase(sharedKey, newid string) (*cachedSourceRef, error)
 				if err != nil {
 					return err
 				}
+				if records != nil {
+					if err := b.Put([]byte(cacheKey), records); err != nil {
+						return err
+					}
+				}
 				snap.id = newid
 				snap.CachePolicy = defaultCachePolicy()
 				dt, err := json.Marshal(snap.sourceMeta)
@@ -512,6 +542,33 @@ type cachedSourceRef struct {
 	*cachedSource
 }
 
+// cacheRecords returns the source with the hashes of the files of the
+// directory, loaded from the db or scanned if they are not up to date
+func (cs *cachedSource) cacheRecords() (*remotecontext.CachableSource, error) {
+	src := cs.src
+	if src == nil {
+		src = remotecontext.NewCachableSource(cs.Dir())
+	}
+	if cs.cached {
+		return src, nil
+	}
+	if err := cs.storage.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(cs.id))
+		dt := b.Get([]byte(cacheKey))
+		if dt != nil {
+			if err := src.UnmarshalBinary(dt); err != nil {
+				return err
+			}
+		} else {
+			return errors.Wrap(src.Scan(), "failed to scan cache records")
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
 func (cs *cachedSource) Dir() string {
 	return cs.dir
 }
@@ -563,6 +620,23 @@ func (csr *cachedSourceRef) Release() error {
 	return nil
 }
 
+type cacheMountRef struct {
+	*cachedSourceRef
+}
+
+// Release records the use of the cache mount before releasing it. The size
+// is reset as the content has likely been changed by the build.
+func (r *cacheMountRef) Release() error {
+	r.storage.mu.Lock()
+	r.CachePolicy.LastUsed = time.Now()
+	err := r.resetSize(-1)
+	r.storage.mu.Unlock()
+	if err != nil {
+		logrus.Warnf("failed to update cache mount %s: %v", r.id, err)
+	}
+	return r.cachedSourceRef.Release()
+}
+
 type detectChanges struct {
 	f         fsutil.ChangeFunc
 	supported bool
@@ -129,3 +129,57 @@ func (t *testIdentifier) SharedKey() string {
 func (t *testIdentifier) Transport() string {
 	return "test"
 }
+
+func TestCacheMount(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "fscache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	fscache, err := NewFSCache(Opt{
+		Root:     tmpDir,
+		Backend:  NewNaiveCacheBackend(filepath.Join(tmpDir, "backend")),
+		GCPolicy: GCPolicy{MaxSize: 15, MaxKeepDuration: time.Hour},
+	})
+	assert.Nil(t, err)
+	defer fscache.Close()
+
+	ref1, err := fscache.CacheMount("gocache")
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(ref1.Dir(), "foo"), []byte("data"), 0600))
+
+	// the same id shares the directory
+	ref2, err := fscache.CacheMount("gocache")
+	assert.Nil(t, err)
+	assert.Equal(t, ref1.Dir(), ref2.Dir())
+
+	// in use cache mounts are not reported nor pruned
+	s, err := fscache.DiskUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, s, int64(0))
+	assert.Nil(t, ref1.Release())
+	released, err := fscache.Prune(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, released, uint64(0))
+
+	assert.Nil(t, ref2.Release())
+	s, err = fscache.DiskUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, s, int64(4))
+
+	ref3, err := fscache.CacheMount("gocache")
+	assert.Nil(t, err)
+	dt, err := ioutil.ReadFile(filepath.Join(ref3.Dir(), "foo"))
+	assert.Nil(t, err)
+	assert.Equal(t, string(dt), "data")
+	assert.Nil(t, ref3.Release())
+
+	released, err = fscache.Prune(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, released, uint64(4))
+
+	ref4, err := fscache.CacheMount("gocache")
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(ref4.Dir(), "foo"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, ref4.Release())
+}
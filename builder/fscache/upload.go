@@ -0,0 +1,233 @@
+package fscache
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/pkg/symlink"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const uploadPrefix = "upload:"
+
+// uploadTimeout is how long a prepared upload waits for the missing files
+// before its directory is released
+const uploadTimeout = 10 * time.Minute
+
+type pendingUpload struct {
+	ref    *cachedSourceRef
+	hashes map[string]string
+	timer  *time.Timer
+}
+
+// PrepareUpload starts an incremental upload of a build context, described by
+// the tarsum hashes of its files keyed by their path relative to the root of
+// the context. The directory of the last context uploaded with the same
+// shared key is reused, and the paths of the files that it is missing or
+// holds with a different hash are returned. These files are then sent to
+// CompleteUpload with the returned id. Shared keys of uploads never match the
+// directories synchronized from client sessions.
+func (fsc *FSCache) PrepareUpload(sharedKey string, hashes map[string]string) (string, []string, error) {
+	for p := range hashes {
+		if !isContextPath(p) {
+			return "", nil, invalidUploadError{errors.Errorf("invalid path in context manifest: %s", p)}
+		}
+	}
+
+	id := uploadPrefix + stringid.GenerateRandomID()
+	var ref *cachedSourceRef
+	if sharedKey != "" {
+		sharedKey = uploadPrefix + sharedKey
+		if r, err := fsc.store.Rebase(sharedKey, id); err == nil {
+			ref = r
+		}
+	}
+	if ref == nil {
+		var err error
+		if ref, err = fsc.store.New(id, sharedKey); err != nil {
+			return "", nil, errors.Wrap(err, "failed to create remote context")
+		}
+	}
+	src, err := ref.cacheRecords()
+	if err != nil {
+		ref.Release()
+		return "", nil, err
+	}
+	ref.src = src
+	ref.cached = true
+
+	current := src.Hashes()
+	var missing []string
+	for p, h := range hashes {
+		if current[p] != h {
+			missing = append(missing, p)
+		}
+	}
+	sort.Strings(missing)
+
+	fsc.mu.Lock()
+	fsc.uploads[id] = &pendingUpload{
+		ref:    ref,
+		hashes: hashes,
+		timer:  time.AfterFunc(uploadTimeout, func() { fsc.cancelUpload(id) }),
+	}
+	fsc.mu.Unlock()
+	return id, missing, nil
+}
+
+// CompleteUpload adds the missing files of the upload id, read from the tar
+// archive r, to its directory and removes the files that are not part of the
+// context anymore. The files of the directory are then hashed again, and
+// must match the hashes of the context manifest. It returns the uploaded
+// context as a source.
+func (fsc *FSCache) CompleteUpload(id string, r io.Reader) (builder.Source, error) {
+	fsc.mu.Lock()
+	u, ok := fsc.uploads[id]
+	delete(fsc.uploads, id)
+	fsc.mu.Unlock()
+	if !ok {
+		return nil, uploadNotFoundError(id)
+	}
+	u.timer.Stop()
+
+	if err := u.apply(r); err != nil {
+		if ierr := u.ref.invalidateCacheRecords(); ierr != nil {
+			logrus.Warnf("failed to invalidate cache records of %s: %v", id, ierr)
+		}
+		u.ref.Release()
+		return nil, err
+	}
+	if err := u.ref.resetSize(-1); err != nil {
+		u.ref.Release()
+		return nil, err
+	}
+	return &wrappedContext{Source: u.ref.src, closer: u.ref.Release}, nil
+}
+
+func (fsc *FSCache) cancelUpload(id string) {
+	fsc.mu.Lock()
+	u, ok := fsc.uploads[id]
+	delete(fsc.uploads, id)
+	fsc.mu.Unlock()
+	if ok {
+		logrus.Debugf("context upload %s timed out", id)
+		u.ref.Release()
+	}
+}
+
+func (u *pendingUpload) apply(r io.Reader) error {
+	dir := u.ref.Dir()
+	for p := range u.ref.src.Hashes() {
+		if _, ok := u.hashes[p]; !ok {
+			fullPath, err := contextFilePath(dir, p)
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(fullPath); err != nil {
+				return errors.Wrapf(err, "failed to remove %s", p)
+			}
+		}
+	}
+
+	decompressed, err := archive.DecompressStream(r)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+	if err := chrootarchive.Untar(decompressed, dir, nil); err != nil {
+		return errors.Wrap(err, "failed to extract context files")
+	}
+	for p := range u.hashes {
+		fullPath, err := contextFilePath(dir, p)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(fullPath); err != nil {
+			return invalidUploadError{errors.Errorf("file %s of the context manifest was not uploaded", p)}
+		}
+	}
+
+	// the cache records are the hashes of the files which were received, not
+	// the ones claimed by the client
+	if err := u.ref.src.Scan(); err != nil {
+		return err
+	}
+	current := u.ref.src.Hashes()
+	for p, h := range current {
+		expected, ok := u.hashes[p]
+		if !ok {
+			return invalidUploadError{errors.Errorf("file %s is not part of the context manifest", p)}
+		}
+		// the root of the context is not extracted from the archive, as
+		// with the contexts sent as an archive
+		if h != expected && p != "." {
+			return invalidUploadError{errors.Errorf("file %s does not match its hash in the context manifest", p)}
+		}
+	}
+
+	records, err := u.ref.src.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return u.ref.storage.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(u.ref.id)).Put([]byte(cacheKey), records)
+	})
+}
+
+// invalidateCacheRecords makes the cache records of the directory be scanned
+// again the next time they are used
+func (cs *cachedSource) invalidateCacheRecords() error {
+	cs.cached = false
+	return cs.storage.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(cs.id)).Delete([]byte(cacheKey))
+	})
+}
+
+// contextFilePath returns the path of the file p of the context directory
+// dir. The parent directories of p must not be symlinks, so that the file
+// cannot be outside of dir.
+func contextFilePath(dir, p string) (string, error) {
+	unresolved := filepath.Join(dir, filepath.Dir(p))
+	parent, err := symlink.FollowSymlinkInScope(unresolved, dir)
+	if err != nil {
+		return "", err
+	}
+	if parent != unresolved {
+		return "", invalidUploadError{errors.Errorf("invalid path in context manifest: %s has a symlink in its parent directories", p)}
+	}
+	return filepath.Join(parent, filepath.Base(p)), nil
+}
+
+// isContextPath checks that p is a clean path relative to the root of a
+// context, that doesn't point outside of it
+func isContextPath(p string) bool {
+	if p == "." {
+		return true
+	}
+	return p != "" && !filepath.IsAbs(p) && filepath.Clean(p) == p &&
+		p != ".." && !strings.HasPrefix(p, ".."+string(filepath.Separator))
+}
+
+type uploadNotFoundError string
+
+func (e uploadNotFoundError) Error() string {
+	return "no context upload in progress with id " + string(e)
+}
+
+func (e uploadNotFoundError) NotFound() {}
+
+type invalidUploadError struct {
+	error
+}
+
+func (e invalidUploadError) InvalidParameter() {}
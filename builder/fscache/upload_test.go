@@ -0,0 +1,179 @@
+package fscache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/builder/remotecontext"
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	reexec.Init()
+}
+
+func uploadContext(t *testing.T, fscache *FSCache, contextDir string) ([]string, string) {
+	hashes, err := remotecontext.ContextHashes(contextDir)
+	require.NoError(t, err)
+	id, missing, err := fscache.PrepareUpload("key", hashes)
+	require.NoError(t, err)
+
+	src, err := fscache.CompleteUpload(id, remotecontext.TarContextFiles(contextDir, missing))
+	require.NoError(t, err)
+	defer src.Close()
+	for p, h := range hashes {
+		if p == "." {
+			// the root of the context keeps the attributes of the directory
+			continue
+		}
+		sum, err := src.Hash(p)
+		require.NoError(t, err)
+		assert.Equal(t, h, sum, p)
+	}
+	return missing, src.Root().Path()
+}
+
+func TestContextUpload(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "fscache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	fscache, err := NewFSCache(Opt{
+		Root:     tmpDir,
+		Backend:  NewNaiveCacheBackend(filepath.Join(tmpDir, "backend")),
+		GCPolicy: GCPolicy{MaxSize: 1 << 20, MaxKeepDuration: time.Hour},
+	})
+	require.NoError(t, err)
+	defer fscache.Close()
+
+	contextDir := filepath.Join(tmpDir, "context")
+	require.NoError(t, os.MkdirAll(filepath.Join(contextDir, "dir"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(contextDir, "Dockerfile"), []byte("FROM busybox\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(contextDir, "dir", "foo"), []byte("foo"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(contextDir, "bar"), []byte("bar"), 0644))
+
+	missing, dir1 := uploadContext(t, fscache, contextDir)
+	assert.Equal(t, []string{".", "Dockerfile", "bar", "dir", filepath.Join("dir", "foo")}, missing)
+
+	// unchanged files are not sent again
+	require.NoError(t, ioutil.WriteFile(filepath.Join(contextDir, "dir", "foo"), []byte("changed"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(contextDir, "bar")))
+	missing, dir2 := uploadContext(t, fscache, contextDir)
+	assert.Equal(t, dir1, dir2)
+	assert.NotContains(t, missing, "Dockerfile")
+	assert.Contains(t, missing, filepath.Join("dir", "foo"))
+
+	dt, err := ioutil.ReadFile(filepath.Join(dir2, "dir", "foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(dt))
+	_, err = os.Stat(filepath.Join(dir2, "bar"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestContextUploadErrors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "fscache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	fscache, err := NewFSCache(Opt{
+		Root:     tmpDir,
+		Backend:  NewNaiveCacheBackend(filepath.Join(tmpDir, "backend")),
+		GCPolicy: GCPolicy{MaxSize: 1 << 20, MaxKeepDuration: time.Hour},
+	})
+	require.NoError(t, err)
+	defer fscache.Close()
+
+	_, _, err = fscache.PrepareUpload("", map[string]string{"../foo": "abc"})
+	assert.EqualError(t, err, "invalid path in context manifest: ../foo")
+
+	_, err = fscache.CompleteUpload("upload:unknown", bytes.NewReader(nil))
+	assert.EqualError(t, err, "no context upload in progress with id upload:unknown")
+
+	id, missing, err := fscache.PrepareUpload("", map[string]string{"foo": "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo"}, missing)
+	_, err = fscache.CompleteUpload(id, remotecontext.TarContextFiles(tmpDir, nil))
+	assert.EqualError(t, err, "file foo of the context manifest was not uploaded")
+}
+
+func TestContextUploadVerifiesFiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "fscache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	fscache, err := NewFSCache(Opt{
+		Root:     tmpDir,
+		Backend:  NewNaiveCacheBackend(filepath.Join(tmpDir, "backend")),
+		GCPolicy: GCPolicy{MaxSize: 1 << 20, MaxKeepDuration: time.Hour},
+	})
+	require.NoError(t, err)
+	defer fscache.Close()
+
+	contextDir := filepath.Join(tmpDir, "context")
+	require.NoError(t, os.MkdirAll(contextDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(contextDir, "foo"), []byte("foo"), 0644))
+	require.NoError(t, os.Symlink("/", filepath.Join(contextDir, "root")))
+	hashes, err := remotecontext.ContextHashes(contextDir)
+	require.NoError(t, err)
+
+	// the hashes of the received files are checked
+	claimed := map[string]string{}
+	for p, h := range hashes {
+		claimed[p] = h
+	}
+	claimed["foo"] = hashes["."]
+	id, missing, err := fscache.PrepareUpload("key", claimed)
+	require.NoError(t, err)
+	_, err = fscache.CompleteUpload(id, remotecontext.TarContextFiles(contextDir, missing))
+	assert.EqualError(t, err, "file foo does not match its hash in the context manifest")
+
+	// files which are not in the manifest are rejected
+	delete(claimed, "foo")
+	id, _, err = fscache.PrepareUpload("key", claimed)
+	require.NoError(t, err)
+	_, err = fscache.CompleteUpload(id, remotecontext.TarContextFiles(contextDir, missing))
+	assert.EqualError(t, err, "file foo is not part of the context manifest")
+
+	// paths are not resolved through the symlinks of the context
+	claimed = map[string]string{"root/etc/passwd": hashes["foo"]}
+	for p, h := range hashes {
+		claimed[p] = h
+	}
+	id, missing, err = fscache.PrepareUpload("key", claimed)
+	require.NoError(t, err)
+	_, err = fscache.CompleteUpload(id, remotecontext.TarContextFiles(contextDir, []string{".", "foo", "root"}))
+	assert.EqualError(t, err, "invalid path in context manifest: root/etc/passwd has a symlink in its parent directories")
+}
+
+func TestContextUploadSharedKey(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "fscache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	fscache, err := NewFSCache(Opt{
+		Root:     tmpDir,
+		Backend:  NewNaiveCacheBackend(filepath.Join(tmpDir, "backend")),
+		GCPolicy: GCPolicy{MaxSize: 1 << 20, MaxKeepDuration: time.Hour},
+	})
+	require.NoError(t, err)
+	defer fscache.Close()
+
+	// a directory synchronized from a client session
+	ref, err := fscache.store.New("session", "key")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(ref.Dir(), "foo"), []byte("foo"), 0644))
+	require.NoError(t, ref.Release())
+
+	contextDir := filepath.Join(tmpDir, "context")
+	require.NoError(t, os.MkdirAll(contextDir, 0755))
+	_, dir := uploadContext(t, fscache, contextDir)
+	assert.NotEqual(t, ref.Dir(), dir)
+	_, err = os.Stat(filepath.Join(ref.Dir(), "foo"))
+	assert.NoError(t, err)
+}
@@ -1,10 +1,14 @@
 package remotecontext
 
 import (
+	"archive/tar"
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path"
 	"strings"
 
 	"github.com/containerd/continuity/driver"
@@ -12,6 +16,8 @@ import (
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/builder/dockerfile/parser"
 	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/builder/remotecontext/git"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/urlutil"
 	"github.com/pkg/errors"
@@ -23,8 +29,9 @@ const ClientSessionRemote = "client-session"
 
 // Detect returns a context and dockerfile from remote location or local
 // archive. progressReader is only used if remoteURL is actually a URL
-// (not empty, and not a Git endpoint).
-func Detect(config backend.BuildConfig) (remote builder.Source, dockerfile *parser.Result, err error) {
+// (not empty, and not a Git endpoint). gitAuth is only used if remoteURL is a
+// Git endpoint, it may be nil.
+func Detect(config backend.BuildConfig, gitAuth git.AuthFunc) (remote builder.Source, dockerfile *parser.Result, err error) {
 	remoteURL := config.Options.RemoteContext
 	dockerfilePath := config.Options.Dockerfile
 
@@ -38,7 +45,7 @@ func Detect(config backend.BuildConfig) (remote builder.Source, dockerfile *pars
 		}
 		return nil, res, nil
 	case urlutil.IsGitURL(remoteURL):
-		remote, dockerfile, err = newGitRemote(remoteURL, dockerfilePath)
+		remote, dockerfile, err = newGitRemote(remoteURL, dockerfilePath, gitAuth)
 	case urlutil.IsURL(remoteURL):
 		remote, dockerfile, err = newURLRemote(remoteURL, dockerfilePath, config.ProgressWriter.ProgressReaderFunc)
 	default:
@@ -47,6 +54,70 @@ func Detect(config backend.BuildConfig) (remote builder.Source, dockerfile *pars
 	return
 }
 
+// DetectDockerfile returns the Dockerfile of a build like Detect, for the
+// builds that only check the Dockerfile. The Dockerfile of a local archive is
+// read from the archive without extracting the rest of the build context.
+func DetectDockerfile(config backend.BuildConfig, gitAuth git.AuthFunc) (*parser.Result, error) {
+	if config.Options.RemoteContext != "" {
+		remote, dockerfile, err := Detect(config, gitAuth)
+		if remote != nil {
+			remote.Close()
+		}
+		return dockerfile, err
+	}
+	defer config.Source.Close()
+	return dockerfileFromArchive(config.Source, config.Options.Dockerfile)
+}
+
+// dockerfileFromArchive reads and parses the Dockerfile at dockerfilePath in
+// the tar stream of a build context. Symlinks to a Dockerfile stored later in
+// the stream are followed.
+func dockerfileFromArchive(tarStream io.Reader, dockerfilePath string) (*parser.Result, error) {
+	decompressedStream, err := archive.DecompressStream(tarStream)
+	if err != nil {
+		return nil, err
+	}
+	defer decompressedStream.Close()
+
+	name := archivePath(dockerfilePath)
+	var lowercase []byte
+	tr := tar.NewReader(decompressedStream)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry := archivePath(hdr.Name)
+		regular := hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA
+		switch {
+		case entry == name && hdr.Typeflag == tar.TypeSymlink:
+			if path.IsAbs(hdr.Linkname) {
+				name = archivePath(hdr.Linkname)
+			} else {
+				name = archivePath(path.Join(path.Dir(entry), hdr.Linkname))
+			}
+		case entry == name && regular:
+			return readAndParseDockerfile(dockerfilePath, tr)
+		case dockerfilePath == builder.DefaultDockerfileName && entry == strings.ToLower(dockerfilePath) && regular:
+			if lowercase, err = ioutil.ReadAll(tr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if lowercase != nil {
+		return readAndParseDockerfile(strings.ToLower(dockerfilePath), bytes.NewReader(lowercase))
+	}
+	return nil, errors.Errorf("Cannot locate specified Dockerfile: %s", dockerfilePath) // backwards compatible error
+}
+
+// archivePath returns p relative to the root of a build context archive
+func archivePath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
 func newArchiveRemote(rc io.ReadCloser, dockerfilePath string) (builder.Source, *parser.Result, error) {
 	defer rc.Close()
 	c, err := FromArchive(rc)
@@ -88,8 +159,8 @@ func withDockerfileFromContext(c modifiableContext, dockerfilePath string) (buil
 	return c, res, nil
 }
 
-func newGitRemote(gitURL string, dockerfilePath string) (builder.Source, *parser.Result, error) {
-	c, err := MakeGitContext(gitURL) // TODO: change this to NewLazySource
+func newGitRemote(gitURL string, dockerfilePath string, gitAuth git.AuthFunc) (builder.Source, *parser.Result, error) {
+	c, err := MakeGitContext(gitURL, gitAuth) // TODO: change this to NewLazySource
 	if err != nil {
 		return nil, nil, err
 	}
@@ -145,6 +216,27 @@ func removeDockerfile(c modifiableContext, filesToRemove ...string) error {
 	return nil
 }
 
+// DockerfileFromSource reads and parses the Dockerfile at dockerfilePath of a
+// source. Unlike archive contexts, the Dockerfile is not removed from the
+// source.
+func DockerfileFromSource(c builder.Source, dockerfilePath string) (*parser.Result, error) {
+	df, err := openAt(c, dockerfilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if dockerfilePath == builder.DefaultDockerfileName {
+				lowercase := strings.ToLower(dockerfilePath)
+				if _, err := StatAt(c, lowercase); err == nil {
+					return DockerfileFromSource(c, lowercase)
+				}
+			}
+			return nil, errors.Errorf("Cannot locate specified Dockerfile: %s", dockerfilePath)
+		}
+		return nil, err
+	}
+	defer df.Close()
+	return readAndParseDockerfile(dockerfilePath, df)
+}
+
 func readAndParseDockerfile(name string, rc io.Reader) (*parser.Result, error) {
 	br := bufio.NewReader(rc)
 	if _, err := br.Peek(1); err != nil {
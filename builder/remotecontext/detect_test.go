@@ -1,6 +1,8 @@
 package remotecontext
 
 import (
+	"archive/tar"
+	"bytes"
 	"errors"
 	"io/ioutil"
 	"log"
@@ -121,3 +123,73 @@ func (r *stubRemote) Close() error {
 func (r *stubRemote) Remove(p string) error {
 	return r.root.Remove(r.root.Join(r.root.Path(), p))
 }
+
+func TestDockerfileFromArchive(t *testing.T) {
+	makeArchive := func(entries ...*tar.Header) *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		for _, hdr := range entries {
+			content := []byte("FROM " + hdr.Name)
+			if hdr.Typeflag == tar.TypeReg {
+				hdr.Size = int64(len(content))
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Typeflag == tar.TypeReg {
+				tw.Write(content)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf
+	}
+	file := func(name string) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644}
+	}
+
+	testCases := []struct {
+		archive        *bytes.Buffer
+		dockerfilePath string
+		expected       string
+	}{
+		{
+			archive:        makeArchive(file("dockerfile"), file("./Dockerfile"), file("other")),
+			dockerfilePath: builder.DefaultDockerfileName,
+			expected:       "./Dockerfile",
+		},
+		{
+			archive:        makeArchive(file("dockerfile"), file("other")),
+			dockerfilePath: builder.DefaultDockerfileName,
+			expected:       "dockerfile",
+		},
+		{
+			archive: makeArchive(
+				&tar.Header{Name: "Dockerfile", Typeflag: tar.TypeSymlink, Linkname: "build/Dockerfile.dev"},
+				file("build/Dockerfile.dev"),
+			),
+			dockerfilePath: builder.DefaultDockerfileName,
+			expected:       "build/Dockerfile.dev",
+		},
+		{
+			archive:        makeArchive(file("sub/Dockerfile")),
+			dockerfilePath: "./sub/Dockerfile",
+			expected:       "sub/Dockerfile",
+		},
+	}
+	for _, tc := range testCases {
+		res, err := dockerfileFromArchive(tc.archive, tc.dockerfilePath)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", tc.expected, err)
+		}
+		if next := res.AST.Children[0].Next; next == nil || next.Value != tc.expected {
+			t.Fatalf("Expected the Dockerfile %s, got %s", tc.expected, res.AST.Children[0].Original)
+		}
+	}
+
+	_, err := dockerfileFromArchive(makeArchive(file("Dockerfile")), "sub/Dockerfile")
+	if err == nil || err.Error() != "Cannot locate specified Dockerfile: sub/Dockerfile" {
+		t.Fatalf("Expected the Dockerfile not to be found, got %v", err)
+	}
+}
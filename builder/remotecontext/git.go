@@ -9,8 +9,9 @@ import (
 )
 
 // MakeGitContext returns a Context from gitURL that is cloned in a temporary directory.
-func MakeGitContext(gitURL string) (builder.Source, error) {
-	root, err := git.Clone(gitURL)
+// auth provides the credentials of HTTP(S) git servers, it may be nil.
+func MakeGitContext(gitURL string, auth git.AuthFunc) (builder.Source, error) {
+	root, err := git.Clone(gitURL, auth)
 	if err != nil {
 		return nil, err
 	}
@@ -1,13 +1,17 @@
 package git
 
 import (
+	"encoding/base64"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/pkg/symlink"
 	"github.com/docker/docker/pkg/urlutil"
@@ -20,16 +24,33 @@ type gitRepo struct {
 	subdir string
 }
 
+// AuthFunc returns the token used to authenticate to the git server at host,
+// or an empty string if there is none. The token is sent as the password of
+// the x-access-token user, the form used by GitHub for access tokens.
+type AuthFunc func(host string) (string, error)
+
 // Clone clones a repository into a newly created directory which
-// will be under "docker-build-git"
-func Clone(remoteURL string) (string, error) {
+// will be under "docker-build-git". If the URL selects a subdirectory of the
+// repository, only that subdirectory is checked out. Submodules are checked
+// out as well. auth is used to authenticate to HTTP(S) remotes, it may be
+// nil.
+func Clone(remoteURL string, auth AuthFunc) (string, error) {
 	repo, err := parseRemoteURL(remoteURL)
 
 	if err != nil {
 		return "", err
 	}
 
+	env, err := authConfigEnv(repo.remote, auth)
+	if err != nil {
+		return "", err
+	}
+
 	fetch := fetchArgs(repo.remote, repo.ref)
+	if repo.subdir != "" && supportsPartialClone() {
+		// Only fetch the blobs of the files that are checked out
+		fetch = append([]string{fetch[0], "--filter=blob:none"}, fetch[1:]...)
+	}
 
 	root, err := ioutil.TempDir("", "docker-build-git")
 	if err != nil {
@@ -46,11 +67,68 @@ func Clone(remoteURL string) (string, error) {
 		return "", errors.Wrapf(err, "failed add origin repo at %s: %s", repo.remote, out)
 	}
 
-	if output, err := gitWithinDir(root, fetch...); err != nil {
+	if output, err := gitWithinDirEnv(root, env, fetch...); err != nil {
 		return "", errors.Wrapf(err, "error fetching: %s", output)
 	}
 
-	return checkoutGit(root, repo.ref, repo.subdir)
+	if repo.subdir != "" {
+		if out, err := setSparseCheckout(root, repo.subdir); err != nil {
+			return "", errors.Wrapf(err, "failed to set sparse checkout of %s: %s", repo.subdir, out)
+		}
+	}
+
+	checkoutDir, err := checkoutGit(root, repo.ref, repo.subdir, env...)
+	if err != nil && repo.subdir != "" {
+		// The subdirectory may be a symlink to a directory that is not part
+		// of the sparse checkout, check out the whole repository instead.
+		if out, err := unsetSparseCheckout(root, env...); err != nil {
+			return "", errors.Wrapf(err, "failed to unset sparse checkout: %s", out)
+		}
+		checkoutDir, err = checkoutGit(root, repo.ref, repo.subdir, env...)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if out, err := updateSubmodules(root, repo.subdir, env...); err != nil {
+		return "", errors.Wrapf(err, "error updating submodules: %s", out)
+	}
+	return checkoutDir, nil
+}
+
+// authConfigEnv returns the environment variables that configure git to add
+// the token returned by auth for the host of remote to the HTTPS requests made
+// to that host. The token is passed in the environment of git, rather than on
+// its command line which any user can read, so that it is neither visible to
+// other processes nor stored in the repository. No token is sent to remotes
+// that are not HTTPS, where it would be sent in cleartext.
+func authConfigEnv(remote string, auth AuthFunc) ([]string, error) {
+	if auth == nil || !urlutil.IsURL(remote) {
+		return nil, nil
+	}
+	u, err := url.Parse(remote)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, nil
+	}
+	token, err := auth(u.Host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get credentials for %s", u.Host)
+	}
+	if token == "" {
+		return nil, nil
+	}
+	if !supportsConfigEnv() {
+		return nil, errors.New("git 2.31 or later is required to authenticate to git remotes")
+	}
+	header := "Authorization: basic " + base64.StdEncoding.EncodeToString([]byte("x-access-token:"+token))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		fmt.Sprintf("GIT_CONFIG_KEY_0=http.%s://%s/.extraheader", u.Scheme, u.Host),
+		"GIT_CONFIG_VALUE_0=" + header,
+	}, nil
 }
 
 func parseRemoteURL(remoteURL string) (gitRepo, error) {
@@ -137,12 +215,12 @@ func supportsShallowClone(remoteURL string) bool {
 	return true
 }
 
-func checkoutGit(root, ref, subdir string) (string, error) {
+func checkoutGit(root, ref, subdir string, env ...string) (string, error) {
 	// Try checking out by ref name first. This will work on branches and sets
 	// .git/HEAD to the current branch name
-	if output, err := gitWithinDir(root, "checkout", ref); err != nil {
+	if output, err := gitWithinDirEnv(root, env, "checkout", ref); err != nil {
 		// If checking out by branch name fails check out the last fetched ref
-		if _, err2 := gitWithinDir(root, "checkout", "FETCH_HEAD"); err2 != nil {
+		if _, err2 := gitWithinDirEnv(root, env, "checkout", "FETCH_HEAD"); err2 != nil {
 			return "", errors.Wrapf(err, "error checking out %s: %s", ref, output)
 		}
 	}
@@ -166,13 +244,120 @@ func checkoutGit(root, ref, subdir string) (string, error) {
 	return root, nil
 }
 
+// setSparseCheckout limits the files that are checked out to subdir, and
+// .gitmodules for the submodules in subdir
+func setSparseCheckout(root, subdir string) ([]byte, error) {
+	if out, err := gitWithinDir(root, "config", "core.sparseCheckout", "true"); err != nil {
+		return out, err
+	}
+	patterns := "/.gitmodules\n/" + strings.Trim(filepath.ToSlash(subdir), "/") + "/\n"
+	return nil, ioutil.WriteFile(filepath.Join(root, ".git", "info", "sparse-checkout"), []byte(patterns), 0644)
+}
+
+// unsetSparseCheckout checks out all the files of the current commit
+func unsetSparseCheckout(root string, env ...string) ([]byte, error) {
+	if err := ioutil.WriteFile(filepath.Join(root, ".git", "info", "sparse-checkout"), []byte("/*\n"), 0644); err != nil {
+		return nil, err
+	}
+	if _, err := gitWithinDir(root, "rev-parse", "--verify", "HEAD"); err == nil {
+		if out, err := gitWithinDirEnv(root, env, "read-tree", "-mu", "HEAD"); err != nil {
+			return out, err
+		}
+	}
+	return gitWithinDir(root, "config", "core.sparseCheckout", "false")
+}
+
+// updateSubmodules checks out the submodules of the repository, or only the
+// ones in subdir if it is set. Submodules cannot be cloned from local paths,
+// as the repository could otherwise copy repositories of the host into the
+// build context.
+func updateSubmodules(root, subdir string, env ...string) ([]byte, error) {
+	if _, err := os.Stat(filepath.Join(root, ".gitmodules")); os.IsNotExist(err) {
+		return nil, nil
+	}
+	args := []string{"-c", "protocol.file.allow=never", "submodule", "update", "--init", "--recursive", "--recommend-shallow"}
+	if subdir != "" {
+		args = append(args, "--", subdir)
+	}
+	return runGit(root, env, args...)
+}
+
+var (
+	gitVersionOnce sync.Once
+	gitVersion     string
+)
+
+// installedGitVersion returns the output of "git version", or an empty
+// string if it failed
+func installedGitVersion() string {
+	gitVersionOnce.Do(func() {
+		out, err := git("version")
+		if err != nil {
+			return
+		}
+		gitVersion = string(out)
+	})
+	return gitVersion
+}
+
+// supportsPartialClone returns true if the installed git can fetch a
+// repository without the blobs of the files that are not checked out
+func supportsPartialClone() bool {
+	return gitVersionAtLeast(installedGitVersion(), 2, 25)
+}
+
+// supportsConfigEnv returns true if the installed git reads configuration
+// from the GIT_CONFIG_COUNT, GIT_CONFIG_KEY_<n> and GIT_CONFIG_VALUE_<n>
+// environment variables
+func supportsConfigEnv() bool {
+	return gitVersionAtLeast(installedGitVersion(), 2, 31)
+}
+
+// gitVersionAtLeast parses the output of "git version"
+func gitVersionAtLeast(version string, major, minor int) bool {
+	fields := strings.Fields(version)
+	if len(fields) < 3 {
+		return false
+	}
+	parts := strings.SplitN(fields[2], ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	maj, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	min, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return maj > major || maj == major && min >= minor
+}
+
 func gitWithinDir(dir string, args ...string) ([]byte, error) {
+	return gitWithinDirEnv(dir, nil, args...)
+}
+
+// gitWithinDirEnv runs git on the repository in dir, with env added to its
+// environment
+func gitWithinDirEnv(dir string, env []string, args ...string) ([]byte, error) {
 	a := []string{"--work-tree", dir, "--git-dir", filepath.Join(dir, ".git")}
-	return git(append(a, args...)...)
+	return runGit("", env, append(a, args...)...)
 }
 
 func git(args ...string) ([]byte, error) {
-	return exec.Command("git", args...).CombinedOutput()
+	return runGit("", nil, args...)
+}
+
+// runGit runs git from dir, or from the current directory if dir is empty,
+// with env added to its environment
+func runGit(dir string, env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd.CombinedOutput()
 }
 
 // isGitTransport returns true if the provided str is a git transport by inspecting
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/pkg/containerfs"
@@ -13,7 +14,7 @@ import (
 
 // NewLazySource creates a new LazyContext. LazyContext defines a hashed build
 // context based on a root directory. Individual files are hashed first time
-// they are asked. It is not safe to call methods of LazyContext concurrently.
+// they are asked. It is safe to call methods of LazyContext concurrently.
 func NewLazySource(root containerfs.ContainerFS) (builder.Source, error) {
 	return &lazySource{
 		root: root,
@@ -23,6 +24,7 @@ func NewLazySource(root containerfs.ContainerFS) (builder.Source, error) {
 
 type lazySource struct {
 	root containerfs.ContainerFS
+	mu   sync.Mutex
 	sums map[string]string
 }
 
@@ -50,7 +52,9 @@ func (c *lazySource) Hash(path string) (string, error) {
 		return "", errors.WithStack(convertPathError(err, cleanPath))
 	}
 
+	c.mu.Lock()
 	sum, ok := c.sums[relPath]
+	c.mu.Unlock()
 	if !ok {
 		sum, err = c.prepareHash(relPath, fi)
 		if err != nil {
@@ -78,7 +82,9 @@ func (c *lazySource) prepareHash(relPath string, fi os.FileInfo) (string, error)
 		}
 	}
 	sum := hex.EncodeToString(h.Sum(nil))
+	c.mu.Lock()
 	c.sums[relPath] = sum
+	c.mu.Unlock()
 	return sum, nil
 }
 
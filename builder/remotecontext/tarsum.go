@@ -34,13 +34,20 @@ func NewCachableSource(root string) *CachableSource {
 
 // MarshalBinary marshals current cache information to a byte array
 func (cs *CachableSource) MarshalBinary() ([]byte, error) {
-	b := TarsumBackup{Hashes: make(map[string]string)}
+	b := TarsumBackup{Hashes: cs.Hashes()}
+	return b.Marshal()
+}
+
+// Hashes returns the hashes of the files of the source, keyed by their path
+// relative to the root of the source
+func (cs *CachableSource) Hashes() map[string]string {
+	hashes := make(map[string]string)
 	root := cs.getRoot()
 	root.Walk(func(k []byte, v interface{}) bool {
-		b.Hashes[string(k)] = v.(*fileInfo).sum
+		hashes[string(k)] = v.(*fileInfo).sum
 		return false
 	})
-	return b.Marshal()
+	return hashes
 }
 
 // UnmarshalBinary decodes cache information for presented byte array
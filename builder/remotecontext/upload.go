@@ -0,0 +1,81 @@
+package remotecontext
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/pools"
+)
+
+// ContextHashes returns the tarsum hashes of the files of the context
+// directory root, keyed by their path relative to root. They are the
+// manifest of an incremental upload of the context.
+func ContextHashes(root string) (map[string]string, error) {
+	cs := NewCachableSource(root)
+	if err := cs.Scan(); err != nil {
+		return nil, err
+	}
+	return cs.Hashes(), nil
+}
+
+// TarContextFiles returns a tar archive of the files at paths of the context
+// directory root, for the missing files of an incremental upload of the
+// context. Directories are archived without their content, which is only
+// archived if its paths are in paths too.
+func TarContextFiles(root string, paths []string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := writeContextFiles(tw, root, paths)
+		if cerr := tw.Close(); err == nil {
+			err = cerr
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+func writeContextFiles(tw *tar.Writer, root string, paths []string) error {
+	for _, p := range paths {
+		fullPath := filepath.Join(root, p)
+		fi, err := os.Lstat(fullPath)
+		if err != nil {
+			return err
+		}
+		var link string
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(fullPath); err != nil {
+				return err
+			}
+		}
+		hdr, err := archive.FileInfoHeader(p, fi, link)
+		if err != nil {
+			return err
+		}
+		// contexts are sent with root ownership, like whole context archives
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			if err := copyFile(tw, fullPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = pools.Copy(w, f)
+	return err
+}
@@ -0,0 +1,107 @@
+// Package secrets implements a build session service that lets the builder
+// request build-time secrets from the client. Secrets are only transferred
+// when a RUN instruction asks for them and are never stored by the daemon.
+package secrets
+
+import (
+	"github.com/gogo/protobuf/types"
+	"github.com/moby/buildkit/session"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrNotFound is returned by a SecretStore for unknown secrets
+var ErrNotFound = errors.New("secret not found")
+
+// SecretStore provides the contents of the secrets a client exposes on a
+// session
+type SecretStore interface {
+	GetSecret(ctx context.Context, id string) ([]byte, error)
+}
+
+type secretProvider struct {
+	store SecretStore
+}
+
+// NewSecretProvider returns a session.Attachable that serves the secrets in
+// store to the builder
+func NewSecretProvider(store SecretStore) session.Attachable {
+	return &secretProvider{store: store}
+}
+
+func (sp *secretProvider) Register(server *grpc.Server) {
+	server.RegisterService(&secretsServiceDesc, sp)
+}
+
+func (sp *secretProvider) GetSecret(ctx context.Context, req *types.StringValue) (*types.BytesValue, error) {
+	data, err := sp.store.GetSecret(ctx, req.Value)
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return nil, grpc.Errorf(codes.NotFound, "secret %s not found", req.Value)
+		}
+		return nil, err
+	}
+	return &types.BytesValue{Value: data}, nil
+}
+
+// Supported returns true if the client at the other end of the session
+// provides secrets
+func Supported(c session.Caller) bool {
+	return c.Supports(session.MethodURL(secretsServiceDesc.ServiceName, "GetSecret"))
+}
+
+// GetSecret requests the secret with the given id from the client at the
+// other end of the session
+func GetSecret(ctx context.Context, c session.Caller, id string) ([]byte, error) {
+	if !Supported(c) {
+		return nil, errors.Errorf("secret %s requested but the session does not provide secrets", id)
+	}
+	method := session.MethodURL(secretsServiceDesc.ServiceName, "GetSecret")
+	resp := new(types.BytesValue)
+	if err := grpc.Invoke(ctx, method, &types.StringValue{Value: id}, resp, c.Conn()); err != nil {
+		if grpc.Code(err) == codes.NotFound {
+			return nil, errors.Wrapf(ErrNotFound, "secret %s", id)
+		}
+		return nil, errors.Wrapf(err, "failed to get secret %s", id)
+	}
+	return resp.Value, nil
+}
+
+// secretsServer is the server API of the moby.secrets.v1.Secrets service.
+// Requests and responses use the protobuf wrapper types so that no generated
+// code is needed for the service.
+type secretsServer interface {
+	GetSecret(context.Context, *types.StringValue) (*types.BytesValue, error)
+}
+
+func getSecretHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(types.StringValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(secretsServer).GetSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/moby.secrets.v1.Secrets/GetSecret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(secretsServer).GetSecret(ctx, req.(*types.StringValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var secretsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "moby.secrets.v1.Secrets",
+	HandlerType: (*secretsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetSecret",
+			Handler:    getSecretHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
@@ -0,0 +1,86 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/buildkit/session"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+type testCaller struct {
+	conn *grpc.ClientConn
+}
+
+func (c *testCaller) Context() context.Context {
+	return context.Background()
+}
+
+func (c *testCaller) Supports(method string) bool {
+	return method == session.MethodURL(secretsServiceDesc.ServiceName, "GetSecret")
+}
+
+func (c *testCaller) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+func (c *testCaller) Name() string {
+	return "test"
+}
+
+func (c *testCaller) SharedKey() string {
+	return ""
+}
+
+func newTestCaller(t *testing.T, store SecretStore) (*testCaller, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	NewSecretProvider(store).Register(server)
+	go server.Serve(l)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	return &testCaller{conn: conn}, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestGetSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	secretPath := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(secretPath, []byte("hunter2"), 0600))
+
+	store, err := NewFileStore([]Source{{ID: "token", FilePath: secretPath}})
+	require.NoError(t, err)
+
+	c, cleanup := newTestCaller(t, store)
+	defer cleanup()
+
+	data, err := GetSecret(context.Background(), c, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(data))
+
+	_, err = GetSecret(context.Background(), c, "unknown")
+	assert.Equal(t, ErrNotFound, errors.Cause(err))
+}
+
+func TestNewFileStoreInvalid(t *testing.T) {
+	_, err := NewFileStore([]Source{{FilePath: "/tmp/foo"}})
+	assert.EqualError(t, err, "secret source requires an id")
+
+	_, err = NewFileStore([]Source{{ID: "foo", FilePath: "/tmp/foo"}, {ID: "foo", FilePath: "/tmp/bar"}})
+	assert.EqualError(t, err, "duplicate secret foo")
+}
@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Source describes a secret that is read from a file on the client
+type Source struct {
+	ID       string
+	FilePath string
+}
+
+type fileStore struct {
+	sources map[string]Source
+}
+
+// NewFileStore returns a SecretStore that reads each secret from the file of
+// its source when it is requested
+func NewFileStore(sources []Source) (SecretStore, error) {
+	m := make(map[string]Source, len(sources))
+	for _, src := range sources {
+		if src.ID == "" {
+			return nil, errors.New("secret source requires an id")
+		}
+		if _, exists := m[src.ID]; exists {
+			return nil, errors.Errorf("duplicate secret %s", src.ID)
+		}
+		m[src.ID] = src
+	}
+	return &fileStore{sources: m}, nil
+}
+
+func (fs *fileStore) GetSecret(ctx context.Context, id string) ([]byte, error) {
+	src, ok := fs.sources[id]
+	if !ok {
+		return nil, errors.WithStack(ErrNotFound)
+	}
+	data, err := ioutil.ReadFile(src.FilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read secret %s", id)
+	}
+	return data, nil
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+// BuildContextUpload starts an incremental upload of a build context. The
+// daemon answers with the files of the manifest it doesn't hold, which are
+// then sent to ImageBuild with the ContextUpload option set to the ID of the
+// upload.
+func (cli *Client) BuildContextUpload(ctx context.Context, manifest types.BuildContextManifest) (types.BuildContextUpload, error) {
+	var upload types.BuildContextUpload
+	if err := cli.NewVersionError("1.35", "build context upload"); err != nil {
+		return upload, err
+	}
+	resp, err := cli.post(ctx, "/build/context", nil, manifest, nil)
+	if err != nil {
+		return upload, err
+	}
+	err = json.NewDecoder(resp.body).Decode(&upload)
+	ensureReaderClosed(resp)
+	return upload, err
+}
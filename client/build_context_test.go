@@ -0,0 +1,50 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestBuildContextUpload(t *testing.T) {
+	expectedURL := "/build/context"
+	client := &Client{
+		client: newMockClient(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path != expectedURL {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, r.URL)
+			}
+			if r.Method != "POST" {
+				return nil, fmt.Errorf("expected POST method, got %s", r.Method)
+			}
+			var manifest types.BuildContextManifest
+			if err := json.NewDecoder(r.Body).Decode(&manifest); err != nil {
+				return nil, err
+			}
+			if manifest.SharedKey != "key" || len(manifest.Hashes) != 2 {
+				return nil, fmt.Errorf("unexpected manifest %v", manifest)
+			}
+			b, err := json.Marshal(types.BuildContextUpload{ID: "upload:1", Missing: []string{"foo"}})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+	upload, err := client.BuildContextUpload(context.Background(), types.BuildContextManifest{
+		SharedKey: "key",
+		Hashes:    map[string]string{"foo": "abc", "bar": "def"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, types.BuildContextUpload{ID: "upload:1", Missing: []string{"foo"}}, upload)
+}
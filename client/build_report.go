@@ -0,0 +1,25 @@
+package client
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+// BuildReport returns the report of the build of an image, with the timings
+// and cache usage of its stages and instructions.
+func (cli *Client) BuildReport(ctx context.Context, imageID string) (types.BuildReport, error) {
+	var report types.BuildReport
+	if err := cli.NewVersionError("1.35", "build report"); err != nil {
+		return report, err
+	}
+	resp, err := cli.get(ctx, "/build/"+imageID+"/report", url.Values{}, nil)
+	if err != nil {
+		return report, wrapResponseError(err, resp, "build report", imageID)
+	}
+	err = json.NewDecoder(resp.body).Decode(&report)
+	ensureReaderClosed(resp)
+	return report, err
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestBuildReportError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusNotFound, "no build report")),
+	}
+	_, err := client.BuildReport(context.Background(), "sha256:abc")
+	assert.True(t, IsErrNotFound(err))
+}
+
+func TestBuildReport(t *testing.T) {
+	expectedURL := "/build/sha256:abc/report"
+	client := &Client{
+		client: newMockClient(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path != expectedURL {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, r.URL)
+			}
+			b, err := json.Marshal(types.BuildReport{
+				ID:       "sha256:abc",
+				Duration: time.Second,
+				Stages: []types.BuildStageReport{
+					{Steps: []types.BuildStepReport{{Instruction: "RUN true", Cache: types.BuildCacheHit}}},
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+	report, err := client.BuildReport(context.Background(), "sha256:abc")
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:abc", report.ID)
+	assert.Equal(t, time.Second, report.Duration)
+	require.Len(t, report.Stages, 1)
+	assert.Equal(t, types.BuildCacheHit, report.Stages[0].Steps[0].Cache)
+}
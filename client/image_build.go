@@ -128,9 +128,24 @@ func (cli *Client) imageBuildOptionsToQuery(options types.ImageBuildOptions) (ur
 		return query, err
 	}
 	query.Set("cachefrom", string(cacheFromJSON))
+	if options.CacheTo != "" {
+		query.Set("cacheto", options.CacheTo)
+	}
+	if options.CacheToMode != "" {
+		query.Set("cachetomode", options.CacheToMode)
+	}
 	if options.SessionID != "" {
 		query.Set("session", options.SessionID)
 	}
+	if options.Check {
+		query.Set("check", "1")
+	}
+	if options.ContextUpload != "" {
+		query.Set("contextupload", options.ContextUpload)
+	}
+	if options.SourceDateEpoch != nil {
+		query.Set("sourcedateepoch", strconv.FormatInt(options.SourceDateEpoch.Unix(), 10))
+	}
 	if options.Platform != "" {
 		query.Set("platform", strings.ToLower(options.Platform))
 	}
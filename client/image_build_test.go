@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -29,6 +30,7 @@ func TestImageBuildError(t *testing.T) {
 func TestImageBuild(t *testing.T) {
 	v1 := "value1"
 	v2 := "value2"
+	sourceDateEpoch := time.Unix(1500000000, 0)
 	emptyRegistryConfig := "bnVsbA=="
 	buildCases := []struct {
 		buildOptions           types.ImageBuildOptions
@@ -142,6 +144,39 @@ func TestImageBuild(t *testing.T) {
 			expectedTags:           []string{},
 			expectedRegistryConfig: emptyRegistryConfig,
 		},
+		{
+			buildOptions: types.ImageBuildOptions{
+				Check: true,
+			},
+			expectedQueryParams: map[string]string{
+				"check": "1",
+				"rm":    "0",
+			},
+			expectedTags:           []string{},
+			expectedRegistryConfig: emptyRegistryConfig,
+		},
+		{
+			buildOptions: types.ImageBuildOptions{
+				ContextUpload: "upload:1",
+			},
+			expectedQueryParams: map[string]string{
+				"contextupload": "upload:1",
+				"rm":            "0",
+			},
+			expectedTags:           []string{},
+			expectedRegistryConfig: emptyRegistryConfig,
+		},
+		{
+			buildOptions: types.ImageBuildOptions{
+				SourceDateEpoch: &sourceDateEpoch,
+			},
+			expectedQueryParams: map[string]string{
+				"sourcedateepoch": "1500000000",
+				"rm":              "0",
+			},
+			expectedTags:           []string{},
+			expectedRegistryConfig: emptyRegistryConfig,
+		},
 		{
 			buildOptions: types.ImageBuildOptions{
 				AuthConfigs: map[string]types.AuthConfig{
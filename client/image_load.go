@@ -13,11 +13,25 @@ import (
 // It's up to the caller to close the io.ReadCloser in the
 // ImageLoadResponse returned by this function.
 func (cli *Client) ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+	return cli.ImageLoadWithOptions(ctx, input, types.ImageLoadOptions{Quiet: quiet})
+}
+
+// ImageLoadWithOptions loads an image in the docker host from the client
+// host, with the options set in options.
+// It's up to the caller to close the io.ReadCloser in the
+// ImageLoadResponse returned by this function.
+func (cli *Client) ImageLoadWithOptions(ctx context.Context, input io.Reader, options types.ImageLoadOptions) (types.ImageLoadResponse, error) {
 	v := url.Values{}
 	v.Set("quiet", "0")
-	if quiet {
+	if options.Quiet {
 		v.Set("quiet", "1")
 	}
+	if options.Repository != "" {
+		if err := cli.NewVersionError("1.35", "image load repository"); err != nil {
+			return types.ImageLoadResponse{}, err
+		}
+		v.Set("repository", options.Repository)
+	}
 	headers := map[string][]string{"Content-Type": {"application/x-tar"}}
 	resp, err := cli.postRaw(ctx, "/images/load", v, input, headers)
 	if err != nil {
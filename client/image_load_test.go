@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/docker/docker/api/types"
 	"golang.org/x/net/context"
 )
 
@@ -28,6 +29,7 @@ func TestImageLoad(t *testing.T) {
 	expectedOutput := "outputBody"
 	loadCases := []struct {
 		quiet                bool
+		repository           string
 		responseContentType  string
 		expectedResponseJSON bool
 		expectedQueryParams  map[string]string
@@ -48,6 +50,15 @@ func TestImageLoad(t *testing.T) {
 				"quiet": "1",
 			},
 		},
+		{
+			repository:           "example.com/app",
+			responseContentType:  "application/json",
+			expectedResponseJSON: true,
+			expectedQueryParams: map[string]string{
+				"quiet":      "0",
+				"repository": "example.com/app",
+			},
+		},
 	}
 	for _, loadCase := range loadCases {
 		client := &Client{
@@ -77,7 +88,7 @@ func TestImageLoad(t *testing.T) {
 		}
 
 		input := bytes.NewReader([]byte(expectedInput))
-		imageLoadResponse, err := client.ImageLoad(context.Background(), input, loadCase.quiet)
+		imageLoadResponse, err := client.ImageLoadWithOptions(context.Background(), input, types.ImageLoadOptions{Quiet: loadCase.quiet, Repository: loadCase.repository})
 		if err != nil {
 			t.Fatal(err)
 		}
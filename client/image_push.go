@@ -35,6 +35,12 @@ func (cli *Client) ImagePush(ctx context.Context, image string, options types.Im
 
 	query := url.Values{}
 	query.Set("tag", tag)
+	if options.Format != "" {
+		if err := cli.NewVersionError("1.35", "image push format"); err != nil {
+			return nil, err
+		}
+		query.Set("format", options.Format)
+	}
 
 	resp, err := cli.tryImagePush(ctx, name, query, options.RegistryAuth)
 	if resp.statusCode == http.StatusUnauthorized && options.PrivilegeFunc != nil {
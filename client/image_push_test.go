@@ -132,9 +132,11 @@ func TestImagePushWithoutErrors(t *testing.T) {
 	expectedOutput := "hello world"
 	expectedURLFormat := "/images/%s/push"
 	pullCases := []struct {
-		reference     string
-		expectedImage string
-		expectedTag   string
+		reference      string
+		format         string
+		expectedImage  string
+		expectedTag    string
+		expectedFormat string
 	}{
 		{
 			reference:     "myimage",
@@ -146,6 +148,13 @@ func TestImagePushWithoutErrors(t *testing.T) {
 			expectedImage: "myimage",
 			expectedTag:   "tag",
 		},
+		{
+			reference:      "myimage:tag",
+			format:         types.ImagePushFormatOCI,
+			expectedImage:  "myimage",
+			expectedTag:    "tag",
+			expectedFormat: "oci",
+		},
 	}
 	for _, pullCase := range pullCases {
 		client := &Client{
@@ -159,13 +168,17 @@ func TestImagePushWithoutErrors(t *testing.T) {
 				if tag != pullCase.expectedTag {
 					return nil, fmt.Errorf("tag not set in URL query properly. Expected '%s', got %s", pullCase.expectedTag, tag)
 				}
+				format := query.Get("format")
+				if format != pullCase.expectedFormat {
+					return nil, fmt.Errorf("format not set in URL query properly. Expected '%s', got %s", pullCase.expectedFormat, format)
+				}
 				return &http.Response{
 					StatusCode: http.StatusOK,
 					Body:       ioutil.NopCloser(bytes.NewReader([]byte(expectedOutput))),
 				}, nil
 			}),
 		}
-		resp, err := client.ImagePush(context.Background(), pullCase.reference, types.ImagePushOptions{})
+		resp, err := client.ImagePush(context.Background(), pullCase.reference, types.ImagePushOptions{Format: pullCase.format})
 		if err != nil {
 			t.Fatal(err)
 		}
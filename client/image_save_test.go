@@ -8,6 +8,8 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 
 	"strings"
@@ -56,3 +58,49 @@ func TestImageSave(t *testing.T) {
 		t.Fatalf("expected response to contain 'response', got %s", string(response))
 	}
 }
+
+func TestImageSaveWithOptionsUnsupported(t *testing.T) {
+	client := &Client{
+		version: "1.34",
+		client:  &http.Client{},
+	}
+	_, err := client.ImageSaveWithOptions(context.Background(), []string{"image_id1"}, types.ImageSaveOptions{Format: types.ImageSaveFormatOCI})
+	assert.EqualError(t, err, `"image save format" requires API version 1.35, but the Docker daemon API version is 1.34`)
+}
+
+func TestImageSaveWithOptions(t *testing.T) {
+	expectedURL := "/images/get"
+	client := &Client{
+		client: newMockClient(func(r *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(r.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, r.URL)
+			}
+			query := r.URL.Query()
+			names := query["names"]
+			expectedNames := []string{"image_id1", "image_id2"}
+			if !reflect.DeepEqual(names, expectedNames) {
+				return nil, fmt.Errorf("names not set in URL query properly. Expected %v, got %v", names, expectedNames)
+			}
+			if format := query.Get("format"); format != "oci" {
+				return nil, fmt.Errorf("format not set in URL query properly. Expected oci, got %s", format)
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("response"))),
+			}, nil
+		}),
+	}
+	saveResponse, err := client.ImageSaveWithOptions(context.Background(), []string{"image_id1", "image_id2"}, types.ImageSaveOptions{Format: types.ImageSaveFormatOCI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := ioutil.ReadAll(saveResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveResponse.Close()
+	if string(response) != "response" {
+		t.Fatalf("expected response to contain 'response', got %s", string(response))
+	}
+}
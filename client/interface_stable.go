@@ -8,3 +8,6 @@ type APIClient interface {
 
 // Ensure that Client always implements APIClient.
 var _ APIClient = &Client{}
+
+// Ensure that Client always implements ImageOptionsAPIClient.
+var _ ImageOptionsAPIClient = &Client{}
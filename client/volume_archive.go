@@ -0,0 +1,43 @@
+package client
+
+import (
+	"io"
+	"net/url"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+// VolumeExport retrieves the contents of a volume as a tar archive and
+// returns them as an io.ReadCloser. It's up to the caller to close the
+// stream.
+func (cli *Client) VolumeExport(ctx context.Context, volumeID string) (io.ReadCloser, error) {
+	if err := cli.NewVersionError("1.35", "volume export"); err != nil {
+		return nil, err
+	}
+	resp, err := cli.get(ctx, "/volumes/"+volumeID+"/export", url.Values{}, nil)
+	if err != nil {
+		return nil, wrapResponseError(err, resp, "volume", volumeID)
+	}
+	return resp.body, nil
+}
+
+// VolumeImport extracts content into a volume.
+// Note that `content` must be a Reader for a TAR
+func (cli *Client) VolumeImport(ctx context.Context, volumeID string, content io.Reader, options types.VolumeImportOptions) error {
+	if err := cli.NewVersionError("1.35", "volume import"); err != nil {
+		return err
+	}
+	query := url.Values{}
+	// Do not allow for an existing directory to be overwritten by a non-directory and vice versa.
+	if !options.AllowOverwriteDirWithFile {
+		query.Set("noOverwriteDirNonDir", "true")
+	}
+
+	resp, err := cli.putRaw(ctx, "/volumes/"+volumeID+"/import", query, content, nil)
+	if err != nil {
+		return wrapResponseError(err, resp, "volume", volumeID)
+	}
+	ensureReaderClosed(resp)
+	return nil
+}
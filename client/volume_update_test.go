@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestVolumeUpdateError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+	}
+
+	_, err := client.VolumeUpdate(context.Background(), "volume", volumetypes.VolumesUpdateBody{})
+	testutil.ErrorContains(t, err, "Error response from daemon: Server error")
+}
+
+func TestVolumeUpdateNotFound(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusNotFound, "Server error")),
+	}
+
+	_, err := client.VolumeUpdate(context.Background(), "unknown", volumetypes.VolumesUpdateBody{})
+	assert.True(t, IsErrNotFound(err))
+}
+
+func TestVolumeUpdate(t *testing.T) {
+	expectedURL := "/volumes/volume_id/update"
+
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "POST" {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+
+			var body volumetypes.VolumesUpdateBody
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+			if body.DriverOpts != nil {
+				return nil, fmt.Errorf("expected no driver options, got %v", body.DriverOpts)
+			}
+
+			content, err := json.Marshal(types.Volume{
+				Name:   "volume_id",
+				Driver: "local",
+				Labels: body.Labels,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(content)),
+			}, nil
+		}),
+	}
+
+	volume, err := client.VolumeUpdate(context.Background(), "volume_id", volumetypes.VolumesUpdateBody{
+		Labels: map[string]string{"foo": "bar"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "bar"}, volume.Labels)
+}
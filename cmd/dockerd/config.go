@@ -4,6 +4,7 @@ import (
 	"runtime"
 
 	"github.com/docker/docker/daemon/config"
+	"github.com/docker/docker/daemon/events/sinkconfig"
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/registry"
 	"github.com/spf13/pflag"
@@ -58,7 +59,11 @@ func installCommonConfigFlags(conf *config.Config, flags *pflag.FlagSet) {
 	flags.StringVar(&conf.CorsHeaders, "api-cors-header", "", "Set CORS headers in the Engine API")
 	flags.IntVar(&maxConcurrentDownloads, "max-concurrent-downloads", config.DefaultMaxConcurrentDownloads, "Set the max concurrent downloads for each pull")
 	flags.IntVar(&maxConcurrentUploads, "max-concurrent-uploads", config.DefaultMaxConcurrentUploads, "Set the max concurrent uploads for each push")
+	flags.IntVar(&conf.MaxDownloadParts, "max-download-parts", 1, "Set the max concurrent range requests for each layer download")
 	flags.IntVar(&conf.ShutdownTimeout, "shutdown-timeout", defaultShutdownTimeout, "Set the default shutdown timeout")
+	flags.Var(&conf.EventsJournalMaxSize, "events-journal-max-size", "Maximum size of the on-disk events journal (0 disables the journal)")
+	flags.StringVar(&conf.EventsJournalMaxAge, "events-journal-max-age", "", "Maximum age of the events kept in the events journal")
+	flags.Var(opts.NewNamedListOptsRef("event-sinks", &conf.EventSinks, sinkconfig.Validate), "event-sink", "Event sink plugins to forward engine events to")
 
 	flags.StringVar(&conf.SwarmDefaultAdvertiseAddr, "swarm-default-advertise-addr", "", "Set default address or interface for swarm advertised address")
 	flags.BoolVar(&conf.Experimental, "experimental", false, "Enable experimental features")
@@ -81,11 +86,11 @@ func installCommonConfigFlags(conf *config.Config, flags *pflag.FlagSet) {
 
 func installRegistryServiceFlags(options *registry.ServiceOptions, flags *pflag.FlagSet) {
 	ana := opts.NewNamedListOptsRef("allow-nondistributable-artifacts", &options.AllowNondistributableArtifacts, registry.ValidateIndexName)
-	mirrors := opts.NewNamedListOptsRef("registry-mirrors", &options.Mirrors, registry.ValidateMirror)
+	mirrors := opts.NewNamedListOptsRef("registry-mirrors", (*[]string)(&options.Mirrors), registry.ValidateMirror)
 	insecureRegistries := opts.NewNamedListOptsRef("insecure-registries", &options.InsecureRegistries, registry.ValidateIndexName)
 
 	flags.Var(ana, "allow-nondistributable-artifacts", "Allow push of nondistributable artifacts to registry")
-	flags.Var(mirrors, "registry-mirror", "Preferred Docker registry mirror")
+	flags.Var(mirrors, "registry-mirror", "Preferred registry mirror, prefixed with the registry and '=' for a registry other than Docker Hub")
 	flags.Var(insecureRegistries, "insecure-registry", "Enable insecure registry communication")
 
 	if runtime.GOOS != "windows" {
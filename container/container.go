@@ -24,6 +24,7 @@ import (
 	"github.com/docker/docker/daemon/exec"
 	"github.com/docker/docker/daemon/logger"
 	"github.com/docker/docker/daemon/logger/jsonfilelog"
+	"github.com/docker/docker/daemon/logger/local"
 	"github.com/docker/docker/daemon/network"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
@@ -394,6 +395,14 @@ func (container *Container) StartLogger() (logger.Logger, error) {
 		}
 	}
 
+	// Set logging file for "local"
+	if cfg.Type == local.Name {
+		info.LogPath, err = container.GetRootResourcePath(filepath.Join("local-logs", "container.log"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	l, err := initDriver(info)
 	if err != nil {
 		return nil, err
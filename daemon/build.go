@@ -3,6 +3,7 @@ package daemon
 import (
 	"io"
 	"runtime"
+	"time"
 
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
@@ -10,6 +11,7 @@ import (
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/containerfs"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/stringid"
@@ -55,7 +57,7 @@ func (rl *releaseableLayer) Mount() (containerfs.ContainerFS, error) {
 	return mountPath, nil
 }
 
-func (rl *releaseableLayer) Commit(os string) (builder.ReleaseableLayer, error) {
+func (rl *releaseableLayer) Commit(os string, sourceDateEpoch *time.Time) (builder.ReleaseableLayer, error) {
 	var chainID layer.ChainID
 	if rl.roLayer != nil {
 		chainID = rl.roLayer.ChainID()
@@ -65,6 +67,9 @@ func (rl *releaseableLayer) Commit(os string) (builder.ReleaseableLayer, error)
 	if err != nil {
 		return nil, err
 	}
+	if sourceDateEpoch != nil {
+		stream = archive.ClampTimesTarWrapper(stream, *sourceDateEpoch)
+	}
 	defer stream.Close()
 
 	newLayer, err := rl.layerStore.Register(stream, chainID, layer.OS(os))
@@ -153,16 +158,9 @@ func (daemon *Daemon) pullForBuilder(ctx context.Context, name string, authConfi
 	}
 	ref = reference.TagNameOnly(ref)
 
-	pullRegistryAuth := &types.AuthConfig{}
-	if len(authConfigs) > 0 {
-		// The request came with a full auth config, use it
-		repoInfo, err := daemon.RegistryService.ResolveRepository(ref)
-		if err != nil {
-			return nil, err
-		}
-
-		resolvedConfig := registry.ResolveAuthConfig(authConfigs, repoInfo.Index)
-		pullRegistryAuth = &resolvedConfig
+	pullRegistryAuth, err := daemon.resolveBuilderAuthConfig(ref, authConfigs)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := daemon.pullImageWithReference(ctx, ref, platform, nil, pullRegistryAuth, output); err != nil {
@@ -171,6 +169,22 @@ func (daemon *Daemon) pullForBuilder(ctx context.Context, name string, authConfi
 	return daemon.GetImage(name)
 }
 
+// resolveBuilderAuthConfig returns the auth config for the registry of ref
+// from the auth configs sent with a build request
+func (daemon *Daemon) resolveBuilderAuthConfig(ref reference.Named, authConfigs map[string]types.AuthConfig) (*types.AuthConfig, error) {
+	if len(authConfigs) == 0 {
+		return &types.AuthConfig{}, nil
+	}
+	// The request came with a full auth config, use it
+	repoInfo, err := daemon.RegistryService.ResolveRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedConfig := registry.ResolveAuthConfig(authConfigs, repoInfo.Index)
+	return &resolvedConfig, nil
+}
+
 // GetImageAndReleasableLayer returns an image and releaseable layer for a reference or ID.
 // Every call to GetImageAndReleasableLayer MUST call releasableLayer.Release() to prevent
 // leaking of layers.
@@ -221,6 +235,31 @@ func (daemon *Daemon) CreateImage(config []byte, parent string, platform string)
 	return daemon.stores[platform].imageStore.Get(id)
 }
 
+// ImageTopLayer returns the diff ID and size of the layer added by the last
+// history entry of an image. The diff ID is empty if the entry did not add a
+// layer.
+func (daemon *Daemon) ImageTopLayer(imageID string) (layer.DiffID, int64, error) {
+	img, err := daemon.GetImage(imageID)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(img.RootFS.DiffIDs) == 0 || len(img.History) > 0 && img.History[len(img.History)-1].EmptyLayer {
+		return "", 0, nil
+	}
+
+	layerStore := daemon.stores[img.OperatingSystem()].layerStore
+	l, err := layerStore.Get(img.RootFS.ChainID())
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to get top layer of image %s", imageID)
+	}
+	defer layer.ReleaseAndLog(layerStore, l)
+	size, err := l.DiffSize()
+	if err != nil {
+		return "", 0, err
+	}
+	return l.DiffID(), size, nil
+}
+
 // IDMappings returns uid/gid mappings for the builder
 func (daemon *Daemon) IDMappings() *idtools.IDMappings {
 	return daemon.idMappings
@@ -1,9 +1,23 @@
 package daemon
 
 import (
+	"io"
+	"io/ioutil"
+	"runtime"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/errdefs"
+	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/distribution"
+	progressutils "github.com/docker/docker/distribution/utils"
+	"github.com/docker/docker/image"
 	"github.com/docker/docker/image/cache"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
 )
 
 // MakeImageCache creates a stateful image cache.
@@ -11,7 +25,10 @@ func (daemon *Daemon) MakeImageCache(sourceRefs []string, platform string) build
 	if len(sourceRefs) == 0 {
 		return cache.NewLocal(daemon.stores[platform].imageStore)
 	}
+	return daemon.newImageCache(sourceRefs, platform)
+}
 
+func (daemon *Daemon) newImageCache(sourceRefs []string, platform string) *cache.ImageCache {
 	cache := cache.New(daemon.stores[platform].imageStore)
 
 	for _, ref := range sourceRefs {
@@ -25,3 +42,216 @@ func (daemon *Daemon) MakeImageCache(sourceRefs []string, platform string) build
 
 	return cache
 }
+
+// importedBuildCache is a build cache pulled from a registry
+type importedBuildCache struct {
+	ref    string
+	cache  *distribution.BuildCache
+	images []*image.Image
+}
+
+// buildCacheImageCacheBuilder makes image caches that match the images of
+// build caches pulled from a registry in addition to local images
+type buildCacheImageCacheBuilder struct {
+	daemon *Daemon
+	ctx    context.Context
+	output io.Writer
+	caches []importedBuildCache
+}
+
+// ImportBuildCache pulls the build cache metadata for the references in
+// cacheFrom that are not available locally. Local images are always used
+// as they are, the registry is only asked for references that do not exist
+// locally. The layers of the build caches are pulled when they are used by
+// a cache hit.
+func (daemon *Daemon) ImportBuildCache(ctx context.Context, cacheFrom []string, opts backend.BuildCacheOptions) builder.ImageCacheBuilder {
+	icb := &buildCacheImageCacheBuilder{daemon: daemon, ctx: ctx, output: opts.Output}
+	for _, ref := range cacheFrom {
+		_, err := daemon.GetImage(ref)
+		if err == nil {
+			continue
+		}
+		if !errdefs.IsNotFound(err) {
+			logrus.Warnf("Could not look up %s for cache resolution, skipping: %+v", ref, err)
+			continue
+		}
+		imported, err := daemon.pullBuildCache(ctx, ref, opts)
+		if err != nil {
+			logrus.Warnf("Could not import build cache %s, skipping: %+v", ref, err)
+			continue
+		}
+		icb.caches = append(icb.caches, *imported)
+	}
+	if len(icb.caches) == 0 {
+		return daemon
+	}
+	return icb
+}
+
+func (daemon *Daemon) pullBuildCache(ctx context.Context, name string, opts backend.BuildCacheOptions) (*importedBuildCache, error) {
+	ref, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return nil, err
+	}
+	ref = reference.TagNameOnly(ref)
+
+	authConfig, err := daemon.resolveBuilderAuthConfig(ref, opts.AuthConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	platform := opts.OS
+	if platform == "" {
+		platform = runtime.GOOS
+	}
+
+	imagePullConfig := &distribution.ImagePullConfig{
+		Config: distribution.Config{
+			AuthConfig:      authConfig,
+			ProgressOutput:  progress.DiscardOutput(),
+			RegistryService: daemon.RegistryService,
+			MetadataStore:   daemon.stores[platform].distributionMetadataStore,
+			ImageStore:      distribution.NewImageConfigStoreFromStore(daemon.stores[platform].imageStore),
+		},
+		DownloadManager:  daemon.downloadManager,
+		Schema2Types:     distribution.BuildCacheTypes,
+		Platform:         platform,
+		DownloadDir:      daemon.layerDownloadDir(platform),
+		MaxDownloadParts: daemon.configStore.MaxDownloadParts,
+	}
+
+	bc, err := distribution.PullBuildCache(ctx, ref, imagePullConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := &importedBuildCache{ref: name, cache: bc}
+	for _, cacheImage := range bc.Images {
+		img, err := image.NewFromJSON(cacheImage.Config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid image in build cache %s", name)
+		}
+		imported.images = append(imported.images, img)
+	}
+	return imported, nil
+}
+
+func (icb *buildCacheImageCacheBuilder) MakeImageCache(sourceRefs []string, platform string) builder.ImageCache {
+	var localRefs []string
+	for _, ref := range sourceRefs {
+		if !icb.imported(ref) {
+			localRefs = append(localRefs, ref)
+		}
+	}
+
+	cache := icb.daemon.newImageCache(localRefs, platform)
+	for _, imported := range icb.caches {
+		bc := imported.cache
+		for i, img := range imported.images {
+			i := i
+			cache.PopulateRemote(img, func(n int) (func(), error) {
+				return icb.downloadLayers(bc, i, n)
+			})
+		}
+	}
+	return cache
+}
+
+func (icb *buildCacheImageCacheBuilder) imported(ref string) bool {
+	for _, imported := range icb.caches {
+		if imported.ref == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func (icb *buildCacheImageCacheBuilder) downloadLayers(bc *distribution.BuildCache, i, n int) (func(), error) {
+	output := icb.output
+	if output == nil {
+		output = ioutil.Discard
+	}
+
+	// Include a buffer so that slow client connections don't affect
+	// transfer performance.
+	progressChan := make(chan progress.Progress, 100)
+
+	writesDone := make(chan struct{})
+
+	ctx, cancelFunc := context.WithCancel(icb.ctx)
+
+	go func() {
+		progressutils.WriteDistributionProgress(cancelFunc, output, progressChan)
+		close(writesDone)
+	}()
+
+	release, err := bc.DownloadLayers(ctx, i, n, progress.ChanOutput(progressChan))
+	close(progressChan)
+	<-writesDone
+	return release, err
+}
+
+// ExportBuildCache pushes the configs of the images with the given IDs and
+// their layers to the registry as a build cache manifest tagged name.
+func (daemon *Daemon) ExportBuildCache(ctx context.Context, name string, imageIDs []string, opts backend.BuildCacheOptions) error {
+	ref, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return validationError{err}
+	}
+	tagged, ok := reference.TagNameOnly(ref).(reference.NamedTagged)
+	if !ok {
+		return validationError{errors.Errorf("build cache reference %s must be a tag", name)}
+	}
+
+	authConfig, err := daemon.resolveBuilderAuthConfig(tagged, opts.AuthConfig)
+	if err != nil {
+		return err
+	}
+
+	platform := opts.OS
+	if platform == "" {
+		platform = runtime.GOOS
+	}
+
+	images := make([]digest.Digest, 0, len(imageIDs))
+	for _, id := range imageIDs {
+		images = append(images, digest.Digest(id))
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = ioutil.Discard
+	}
+
+	// Include a buffer so that slow client connections don't affect
+	// transfer performance.
+	progressChan := make(chan progress.Progress, 100)
+
+	writesDone := make(chan struct{})
+
+	ctx, cancelFunc := context.WithCancel(ctx)
+
+	go func() {
+		progressutils.WriteDistributionProgress(cancelFunc, output, progressChan)
+		close(writesDone)
+	}()
+
+	imagePushConfig := &distribution.ImagePushConfig{
+		Config: distribution.Config{
+			AuthConfig:      authConfig,
+			ProgressOutput:  progress.ChanOutput(progressChan),
+			RegistryService: daemon.RegistryService,
+			MetadataStore:   daemon.stores[platform].distributionMetadataStore,
+			ImageStore:      distribution.NewImageConfigStoreFromStore(daemon.stores[platform].imageStore),
+		},
+		ConfigMediaType: distribution.BuildCacheConfigMediaType,
+		LayerStore:      distribution.NewLayerProviderFromStore(daemon.stores[platform].layerStore),
+		TrustKey:        daemon.trustKey,
+		UploadManager:   daemon.uploadManager,
+	}
+
+	err = distribution.PushBuildCache(ctx, tagged, images, imagePushConfig)
+	close(progressChan)
+	<-writesDone
+	return err
+}
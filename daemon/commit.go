@@ -15,6 +15,7 @@ import (
 	"github.com/docker/docker/container"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/pkg/errors"
 )
@@ -164,6 +165,9 @@ func (daemon *Daemon) Commit(name string, c *backend.ContainerCommitConfig) (str
 	if err != nil {
 		return "", err
 	}
+	if c.SourceDateEpoch != nil {
+		rwTar = archive.ClampTimesTarWrapper(rwTar, *c.SourceDateEpoch)
+	}
 	defer func() {
 		if rwTar != nil {
 			rwTar.Close()
@@ -199,6 +203,11 @@ func (daemon *Daemon) Commit(name string, c *backend.ContainerCommitConfig) (str
 		Config:          newConfig,
 		DiffID:          l.DiffID(),
 	}
+	if c.SourceDateEpoch != nil {
+		// the ID of the container would make the image differ between builds
+		cc.ContainerID = ""
+		cc.Created = *c.SourceDateEpoch
+	}
 	config, err := json.Marshal(image.NewChildImage(parent, cc, container.OS))
 	if err != nil {
 		return "", err
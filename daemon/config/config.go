@@ -12,8 +12,10 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	daemondiscovery "github.com/docker/docker/daemon/discovery"
+	"github.com/docker/docker/daemon/events/sinkconfig"
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/pkg/authorization"
 	"github.com/docker/docker/pkg/discovery"
@@ -54,6 +56,7 @@ var flatOptions = map[string]bool{
 	"log-opts":           true,
 	"runtimes":           true,
 	"default-ulimits":    true,
+	"registry-mirrors":   true,
 }
 
 // LogConfig represents the default log configuration.
@@ -138,10 +141,28 @@ type CommonConfig struct {
 	// may take place at a time for each push.
 	MaxConcurrentUploads *int `json:"max-concurrent-uploads,omitempty"`
 
+	// MaxDownloadParts is the maximum number of concurrent range requests
+	// a large layer is downloaded with. Layers are downloaded with a
+	// single request if it is 0 or 1.
+	MaxDownloadParts int `json:"max-download-parts,omitempty"`
+
 	// ShutdownTimeout is the timeout value (in seconds) the daemon will wait for the container
 	// to stop when daemon is being shutdown
 	ShutdownTimeout int `json:"shutdown-timeout,omitempty"`
 
+	// EventsJournalMaxSize is the maximum size of the on-disk journal that keeps
+	// engine events across daemon restarts. The journal is disabled if it is 0.
+	EventsJournalMaxSize opts.MemBytes `json:"events-journal-max-size,omitempty"`
+
+	// EventsJournalMaxAge is the maximum age of the events kept in the events
+	// journal, as a duration string (e.g. "72h"). Events are kept until the
+	// journal reaches its maximum size if it is empty.
+	EventsJournalMaxAge string `json:"events-journal-max-age,omitempty"`
+
+	// EventSinks holds the plugins that engine events are forwarded to, in the
+	// form `name[,filter=key=value...][,buffer-size=N]`.
+	EventSinks []string `json:"event-sinks,omitempty"`
+
 	Debug     bool     `json:"debug,omitempty"`
 	Hosts     []string `json:"hosts,omitempty"`
 	LogLevel  string   `json:"log-level,omitempty"`
@@ -469,7 +490,8 @@ func findConfigurationConflicts(config map[string]interface{}, flags *pflag.Flag
 
 // Validate validates some specific configs.
 // such as config.DNS, config.Labels, config.DNSSearch,
-// as well as config.MaxConcurrentDownloads, config.MaxConcurrentUploads.
+// as well as config.MaxConcurrentDownloads, config.MaxConcurrentUploads,
+// config.MaxDownloadParts.
 func Validate(config *Config) error {
 	// validate DNS
 	for _, dns := range config.DNS {
@@ -499,6 +521,31 @@ func Validate(config *Config) error {
 	if config.MaxConcurrentUploads != nil && *config.MaxConcurrentUploads < 0 {
 		return fmt.Errorf("invalid max concurrent uploads: %d", *config.MaxConcurrentUploads)
 	}
+	// validate MaxDownloadParts
+	if config.MaxDownloadParts < 0 {
+		return fmt.Errorf("invalid max download parts: %d", config.MaxDownloadParts)
+	}
+	// validate events journal retention
+	if config.EventsJournalMaxSize < 0 {
+		return fmt.Errorf("invalid events journal max size: %d", config.EventsJournalMaxSize)
+	}
+	if config.EventsJournalMaxAge != "" {
+		if d, err := time.ParseDuration(config.EventsJournalMaxAge); err != nil || d < 0 {
+			return fmt.Errorf("invalid events journal max age: %s", config.EventsJournalMaxAge)
+		}
+	}
+	// validate EventSinks, whose names identify their spool files
+	sinkNames := make(map[string]bool)
+	for _, sink := range config.EventSinks {
+		cfg, err := sinkconfig.Parse(sink)
+		if err != nil {
+			return err
+		}
+		if sinkNames[cfg.Name] {
+			return fmt.Errorf("duplicate event sink %q", cfg.Name)
+		}
+		sinkNames[cfg.Name] = true
+	}
 
 	// validate that "default" runtime is not reset
 	if runtimes := config.GetAllRuntimes(); len(runtimes) > 0 {
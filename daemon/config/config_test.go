@@ -132,6 +132,28 @@ func TestDaemonConfigurationMergeConcurrentError(t *testing.T) {
 	}
 }
 
+func TestDaemonConfigurationMergeRegistryMirrors(t *testing.T) {
+	f, err := ioutil.TempFile("", "docker-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	configFile := f.Name()
+	f.Write([]byte(`{"registry-mirrors": {"quay.io": ["https://mirror-1.com"], "docker.io": ["https://mirror-2.com"]}}`))
+	f.Close()
+
+	var mirrors []string
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Var(opts.NewNamedListOptsRef("registry-mirrors", &mirrors, nil), "registry-mirror", "")
+
+	cc, err := MergeDaemonConfigurations(&Config{}, flags, configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"docker.io=https://mirror-2.com", "quay.io=https://mirror-1.com"}, []string(cc.Mirrors))
+}
+
 func TestDaemonConfigurationMergeConflictsWithInnerStructs(t *testing.T) {
 	f, err := ioutil.TempFile("", "docker-config-")
 	if err != nil {
@@ -259,6 +281,20 @@ func TestValidateConfigurationErrors(t *testing.T) {
 				},
 			},
 		},
+		{
+			config: &Config{
+				CommonConfig: CommonConfig{
+					MaxDownloadParts: -1,
+				},
+			},
+		},
+		{
+			config: &Config{
+				CommonConfig: CommonConfig{
+					EventSinks: []string{"mysink,filter=type=container", "mysink,filter=type=image"},
+				},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		err := Validate(tc.config)
@@ -282,6 +282,10 @@ func (daemon *Daemon) verifyContainerSettings(platform string, hostConfig *conta
 			if config.Healthcheck.StartPeriod != 0 && config.Healthcheck.StartPeriod < containertypes.MinimumDuration {
 				return nil, errors.Errorf("StartPeriod in Healthcheck cannot be less than %s", containertypes.MinimumDuration)
 			}
+
+			if err := validateHealthcheckTest(config.Healthcheck.Test); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -319,7 +323,7 @@ func (daemon *Daemon) verifyContainerSettings(platform string, hostConfig *conta
 		if p.MaximumRetryCount != 0 {
 			return nil, errors.Errorf("maximum retry count cannot be used with restart policy '%s'", p.Name)
 		}
-	case "on-failure":
+	case "on-failure", "on-unhealthy":
 		if p.MaximumRetryCount < 0 {
 			return nil, errors.Errorf("maximum retry count cannot be negative")
 		}
@@ -813,7 +813,10 @@ func NewDaemon(config *config.Config, registryService registry.Service, containe
 		return nil, err
 	}
 
-	eventsService := events.New()
+	eventsService, err := newEventsService(config)
+	if err != nil {
+		return nil, err
+	}
 
 	// We have a single tag/reference store for the daemon globally. However, it's
 	// stored under the graphdriver. On host platforms which only support a single
@@ -879,6 +882,7 @@ func NewDaemon(config *config.Config, registryService registry.Service, containe
 		Config: config.LogConfig.Config,
 	}
 	d.EventsService = eventsService
+	d.EventsService.SetSinks(newEventSinks(config.EventSinks, eventSinksSpoolDir(config.Root), d.PluginStore, nil))
 	d.volumes = volStore
 	d.root = config.Root
 	d.idMappings = idMappings
@@ -1041,6 +1045,12 @@ func (daemon *Daemon) Shutdown() error {
 		daemon.netController.Stop()
 	}
 
+	if daemon.EventsService != nil {
+		if err := daemon.EventsService.Close(); err != nil {
+			logrus.Errorf("Error closing events journal: %v", err)
+		}
+	}
+
 	if err := daemon.cleanupMounts(); err != nil {
 		return err
 	}
@@ -2,6 +2,8 @@ package daemon
 
 import (
 	"context"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -9,7 +11,10 @@ import (
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/daemon/config"
 	daemonevents "github.com/docker/docker/daemon/events"
+	"github.com/docker/docker/daemon/events/sinkconfig"
+	"github.com/docker/docker/pkg/plugingetter"
 	"github.com/docker/libnetwork"
 	swarmapi "github.com/docker/swarmkit/api"
 	gogotypes "github.com/gogo/protobuf/types"
@@ -24,6 +29,68 @@ var (
 	}
 )
 
+// newEventsService returns the events service for the daemon, backed by an
+// on-disk journal if one is configured.
+func newEventsService(config *config.Config) (*daemonevents.Events, error) {
+	if config.EventsJournalMaxSize == 0 {
+		return daemonevents.New(), nil
+	}
+
+	var maxAge time.Duration
+	if config.EventsJournalMaxAge != "" {
+		var err error
+		maxAge, err = time.ParseDuration(config.EventsJournalMaxAge)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	journal, err := daemonevents.OpenJournal(filepath.Join(config.Root, "events"), daemonevents.JournalOptions{
+		MaxSize: config.EventsJournalMaxSize.Value(),
+		MaxAge:  maxAge,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return daemonevents.NewWithJournal(journal), nil
+}
+
+// eventSinksSpoolDir returns the directory the event sinks spool the events
+// they can't buffer to, and save the events they did not deliver to. It is
+// kept across daemon restarts.
+func eventSinksSpoolDir(root string) string {
+	return filepath.Join(root, "event-sinks")
+}
+
+// newEventSinks returns the event sinks described by the given
+// configurations, which must have been validated. The current sinks whose
+// configuration is unchanged are reused. The sinks replacing the others
+// deliver the events these did not deliver, once passed to SetSinks.
+func newEventSinks(configs []string, root string, getter plugingetter.PluginGetter, current []*daemonevents.Sink) []*daemonevents.Sink {
+	var sinks []*daemonevents.Sink
+	reused := make(map[*daemonevents.Sink]bool)
+	for _, c := range configs {
+		cfg, err := sinkconfig.Parse(c)
+		if err != nil {
+			logrus.WithError(err).Errorf("ignoring invalid event sink %q", c)
+			continue
+		}
+		var sink *daemonevents.Sink
+		for _, s := range current {
+			if !reused[s] && reflect.DeepEqual(s.Config(), cfg) {
+				sink = s
+				reused[s] = true
+				break
+			}
+		}
+		if sink == nil {
+			sink = daemonevents.NewSink(cfg, root, getter)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks
+}
+
 // LogContainerEvent generates an event related to a container with only the default attributes.
 func (daemon *Daemon) LogContainerEvent(container *container.Container, action string) {
 	daemon.LogContainerEventWithAttributes(container, action, map[string]string{})
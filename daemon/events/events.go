@@ -6,6 +6,7 @@ import (
 
 	eventtypes "github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/pkg/pubsub"
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -15,9 +16,11 @@ const (
 
 // Events is pubsub channel for events generated by the engine.
 type Events struct {
-	mu     sync.Mutex
-	events []eventtypes.Message
-	pub    *pubsub.Publisher
+	mu      sync.Mutex
+	events  []eventtypes.Message
+	pub     *pubsub.Publisher
+	journal *Journal // optional on-disk store of past events
+	sinks   []*Sink  // plugins events are forwarded to
 }
 
 // New returns new *Events instance
@@ -28,6 +31,57 @@ func New() *Events {
 	}
 }
 
+// NewWithJournal returns new *Events instance which persists events to the
+// given journal, so that they can be replayed beyond the in-memory buffer
+// and across daemon restarts.
+func NewWithJournal(j *Journal) *Events {
+	e := New()
+	e.journal = j
+	return e
+}
+
+// SetSinks replaces the event sinks events are forwarded to, and starts the
+// new ones. Sinks that are not part of the new ones are closed first, with
+// no event published in between, so that a new sink with the same name
+// delivers the events they could not deliver.
+func (e *Events) SetSinks(sinks []*Sink) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	kept := make(map[*Sink]bool, len(sinks))
+	for _, s := range sinks {
+		kept[s] = true
+	}
+	for _, s := range e.sinks {
+		if !kept[s] {
+			s.Close()
+		}
+	}
+	for _, s := range sinks {
+		s.start()
+	}
+	e.sinks = sinks
+}
+
+// Sinks returns the event sinks events are forwarded to.
+func (e *Events) Sinks() []*Sink {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Sink(nil), e.sinks...)
+}
+
+// Close stops forwarding events to sinks and closes the events journal, if any.
+func (e *Events) Close() error {
+	e.SetSinks(nil)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.journal == nil {
+		return nil
+	}
+	return e.journal.Close()
+}
+
 // Subscribe adds new listener to events, returns slice of 64 stored
 // last events, a channel in which you can expect new events (in form
 // of interface{}, so you need type assertion), and a function to call
@@ -60,6 +114,15 @@ func (e *Events) SubscribeTopic(since, until time.Time, ef *Filter) ([]eventtype
 
 	buffered := e.loadBufferedEvents(since, until, topic)
 
+	// Events older than the oldest buffered event are read from the
+	// journal, if any. The journal is read outside of the lock, up to the
+	// events published until now, which the subscription receives after.
+	var snapshot *journalSnapshot
+	if e.journal != nil && !(since.IsZero() && until.IsZero()) &&
+		(len(e.events) == 0 || since.IsZero() || since.UnixNano() < e.events[0].TimeNano) {
+		snapshot = e.journal.snapshot()
+	}
+
 	var ch chan interface{}
 	if topic != nil {
 		ch = e.pub.SubscribeTopic(topic)
@@ -69,6 +132,15 @@ func (e *Events) SubscribeTopic(since, until time.Time, ef *Filter) ([]eventtype
 	}
 
 	e.mu.Unlock()
+
+	if snapshot != nil {
+		// the journal holds all the buffered events too
+		messages, err := snapshot.read(since, until, topic)
+		if err == nil {
+			return messages, ch
+		}
+		logrus.WithError(err).Error("error reading events journal, only returning buffered events")
+	}
 	return buffered, ch
 }
 
@@ -117,6 +189,14 @@ func (e *Events) PublishMessage(jm eventtypes.Message) {
 	} else {
 		e.events = append(e.events, jm)
 	}
+	if e.journal != nil {
+		if err := e.journal.Append(jm); err != nil {
+			logrus.WithError(err).Error("error adding event to journal")
+		}
+	}
+	for _, s := range e.sinks {
+		s.enqueue(jm)
+	}
 	e.mu.Unlock()
 	e.pub.Publish(jm)
 }
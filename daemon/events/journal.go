@@ -0,0 +1,455 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	eventtypes "github.com/docker/docker/api/types/events"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// journalSegments is the number of segment files the journal's maximum
+	// size is split into. Retention is enforced by removing whole segments.
+	journalSegments = 4
+	segmentSuffix   = ".log"
+
+	// maxPendingEvents is the number of events that can be waiting to be
+	// written to the journal, past which events are dropped.
+	maxPendingEvents = 4096
+	// journalExpireInterval is how often the events older than MaxAge are
+	// removed, if it is less than MaxAge.
+	journalExpireInterval = time.Minute
+)
+
+// JournalOptions configures the retention of a Journal.
+type JournalOptions struct {
+	// MaxSize is the maximum size in bytes of all segments of the journal.
+	MaxSize int64
+	// MaxAge is the maximum age of the events kept in the journal. A zero
+	// MaxAge keeps events until the size limit is reached.
+	MaxAge time.Duration
+}
+
+// Journal is an on-disk, size-bounded log of events which makes events
+// available across daemon restarts. Events are stored as JSON lines in
+// numbered segment files; the oldest segments are removed when the journal
+// outgrows its retention limits.
+//
+// Events are written to disk by a goroutine, so that appending an event
+// never waits for the disk.
+type Journal struct {
+	root        string
+	opts        JournalOptions
+	segmentSize int64
+
+	// current is only used by the writer goroutine once the journal is
+	// open.
+	current *os.File
+
+	// mu protects the fields below. It is never held while doing I/O.
+	mu          sync.Mutex
+	segments    []int64 // sequence numbers of the segments, oldest first
+	currentSize int64   // size of the newest segment
+	pending     []eventtypes.Message
+	closed      bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OpenJournal opens the journal stored in root, creating it if needed.
+func OpenJournal(root string, opts JournalOptions) (*Journal, error) {
+	if opts.MaxSize <= 0 {
+		return nil, errors.New("events journal size must be greater than 0")
+	}
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, errors.Wrap(err, "error creating events journal directory")
+	}
+
+	j := &Journal{
+		root:        root,
+		opts:        opts,
+		segmentSize: opts.MaxSize / journalSegments,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	if j.segmentSize == 0 {
+		j.segmentSize = 1
+	}
+
+	fis, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading events journal directory")
+	}
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(fi.Name(), segmentSuffix), 10, 64)
+		if err != nil {
+			logrus.WithField("file", fi.Name()).Warn("ignoring unknown file in events journal")
+			continue
+		}
+		j.segments = append(j.segments, seq)
+	}
+	sort.Slice(j.segments, func(a, b int) bool { return j.segments[a] < j.segments[b] })
+
+	if len(j.segments) == 0 {
+		j.segments = append(j.segments, 0)
+	}
+	if err := j.openCurrent(j.segments[len(j.segments)-1]); err != nil {
+		return nil, err
+	}
+	if err := j.prune(); err != nil {
+		j.current.Close()
+		return nil, err
+	}
+	go j.run()
+	return j, nil
+}
+
+func (j *Journal) segmentPath(seq int64) string {
+	return filepath.Join(j.root, fmt.Sprintf("%d%s", seq, segmentSuffix))
+}
+
+// openCurrent opens the segment seq for appending, as the newest segment.
+func (j *Journal) openCurrent(seq int64) error {
+	f, err := os.OpenFile(j.segmentPath(seq), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error opening events journal segment")
+	}
+	size, err := f.Seek(0, os.SEEK_END)
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "error opening events journal segment")
+	}
+
+	j.current = f
+	j.mu.Lock()
+	if j.segments[len(j.segments)-1] != seq {
+		j.segments = append(j.segments, seq)
+	}
+	j.currentSize = size
+	j.mu.Unlock()
+	return nil
+}
+
+// Append queues a message to be written to the journal.
+func (j *Journal) Append(m eventtypes.Message) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return errors.New("events journal is closed")
+	}
+	if len(j.pending) >= maxPendingEvents {
+		return errors.New("events journal is falling behind, dropping event")
+	}
+	j.pending = append(j.pending, m)
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// run writes the queued messages to disk until the journal is closed, and
+// regularly removes the events that are older than MaxAge.
+func (j *Journal) run() {
+	defer close(j.done)
+
+	interval := journalExpireInterval
+	if j.opts.MaxAge > 0 && j.opts.MaxAge < interval {
+		interval = j.opts.MaxAge
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.wake:
+			j.flush()
+		case <-ticker.C:
+			if err := j.expire(); err != nil {
+				logrus.WithError(err).Error("error removing expired events from journal")
+			}
+		case <-j.stop:
+			j.flush()
+			return
+		}
+	}
+}
+
+// flush writes the queued messages to disk. A message is only removed from
+// the queue once it's written, along with the update of the size of the
+// newest segment, so that snapshots see each message exactly once.
+func (j *Journal) flush() {
+	for {
+		j.mu.Lock()
+		if len(j.pending) == 0 {
+			j.mu.Unlock()
+			return
+		}
+		m := j.pending[0]
+		size := j.currentSize
+		j.mu.Unlock()
+
+		n, err := j.write(m, size)
+		if err != nil {
+			logrus.WithError(err).Error("error writing event to journal")
+		}
+
+		j.mu.Lock()
+		j.pending = j.pending[1:]
+		j.currentSize += int64(n)
+		j.mu.Unlock()
+	}
+}
+
+func (j *Journal) write(m eventtypes.Message, size int64) (int, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	b = append(b, '\n')
+
+	if j.current == nil {
+		return 0, errors.New("events journal segment is not open")
+	}
+	if size > 0 && size+int64(len(b)) > j.segmentSize {
+		if err := j.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := j.current.Write(b)
+	return n, errors.Wrap(err, "error writing to events journal")
+}
+
+// rotate starts a new segment and removes the segments that fall outside
+// of the retention limits.
+func (j *Journal) rotate() error {
+	if err := j.current.Close(); err != nil {
+		return errors.Wrap(err, "error closing events journal segment")
+	}
+	j.mu.Lock()
+	next := j.segments[len(j.segments)-1] + 1
+	j.mu.Unlock()
+	if err := j.openCurrent(next); err != nil {
+		j.current = nil
+		return err
+	}
+	return j.prune()
+}
+
+// expire starts a new segment if the newest one only holds events older
+// than MaxAge, so that they can be removed, and prunes the journal.
+func (j *Journal) expire() error {
+	if j.opts.MaxAge <= 0 || j.current == nil {
+		return nil
+	}
+	j.mu.Lock()
+	size := j.currentSize
+	j.mu.Unlock()
+	if size > 0 {
+		fi, err := j.current.Stat()
+		if err != nil {
+			return err
+		}
+		if time.Since(fi.ModTime()) > j.opts.MaxAge {
+			return j.rotate()
+		}
+	}
+	return j.prune()
+}
+
+// prune removes the oldest segments while the journal exceeds MaxSize or
+// while they only hold events older than MaxAge. The current segment is
+// never removed. It must only be called by the writer goroutine, or with
+// exclusive access to the journal.
+func (j *Journal) prune() error {
+	j.mu.Lock()
+	segments := j.segments
+	j.mu.Unlock()
+
+	var total int64
+	keep := len(segments) - 1
+	for ; keep > 0; keep-- {
+		fi, err := os.Stat(j.segmentPath(segments[keep-1]))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		// segments are only ever appended to, so the modification time is
+		// the time of their newest event.
+		if j.opts.MaxAge > 0 && time.Since(fi.ModTime()) > j.opts.MaxAge {
+			break
+		}
+		// leave room for the current segment to fill up
+		total += fi.Size()
+		if total+j.segmentSize > j.opts.MaxSize {
+			break
+		}
+	}
+
+	// remove the segments from the list first, so that they are not read
+	// while being removed
+	j.mu.Lock()
+	j.segments = j.segments[keep:]
+	j.mu.Unlock()
+	for _, seq := range segments[:keep] {
+		if err := os.Remove(j.segmentPath(seq)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "error removing events journal segment")
+		}
+	}
+	return nil
+}
+
+// journalSnapshot is the content of the journal at a point in time: the
+// segments up to the given size of the newest one, followed by the messages
+// that were not written yet.
+type journalSnapshot struct {
+	j        *Journal
+	segments []int64
+	size     int64
+	pending  []eventtypes.Message
+}
+
+// snapshot returns the current content of the journal, which can be read
+// while events are appended.
+func (j *Journal) snapshot() *journalSnapshot {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := &journalSnapshot{
+		j:        j,
+		segments: make([]int64, len(j.segments)),
+		size:     j.currentSize,
+		pending:  make([]eventtypes.Message, len(j.pending)),
+	}
+	copy(s.segments, j.segments)
+	copy(s.pending, j.pending)
+	return s
+}
+
+// Read returns the messages in the journal that were emitted between since
+// and until and match the topic function, if it is not nil. A zero until
+// means no upper bound.
+func (j *Journal) Read(since, until time.Time, topic func(interface{}) bool) ([]eventtypes.Message, error) {
+	return j.snapshot().read(since, until, topic)
+}
+
+func (s *journalSnapshot) read(since, until time.Time, topic func(interface{}) bool) ([]eventtypes.Message, error) {
+	var (
+		sinceNanoUnix int64
+		untilNanoUnix int64
+		messages      []eventtypes.Message
+	)
+	if !since.IsZero() {
+		sinceNanoUnix = since.UnixNano()
+	}
+	if !until.IsZero() {
+		untilNanoUnix = until.UnixNano()
+	}
+
+	handle := func(m eventtypes.Message) bool {
+		if m.TimeNano < sinceNanoUnix {
+			return true
+		}
+		if untilNanoUnix > 0 && m.TimeNano > untilNanoUnix {
+			return false
+		}
+		if topic == nil || topic(m) {
+			messages = append(messages, m)
+		}
+		return true
+	}
+
+	for i, seq := range s.segments {
+		size := int64(-1)
+		if i == len(s.segments)-1 {
+			size = s.size
+		}
+		done, err := s.j.readSegment(seq, size, handle)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return messages, nil
+		}
+	}
+	for _, m := range s.pending {
+		if !handle(m) {
+			break
+		}
+	}
+	return messages, nil
+}
+
+// readSegment decodes the messages in the first size bytes of a segment, or
+// the whole segment if size is negative, passing each of them to the handler
+// until it returns false, in which case done is true.
+func (j *Journal) readSegment(seq int64, size int64, handle func(eventtypes.Message) bool) (done bool, err error) {
+	f, err := os.Open(j.segmentPath(seq))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "error opening events journal segment")
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if size >= 0 {
+		r = io.LimitReader(f, size)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var m eventtypes.Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			// a partially written event, e.g. if the daemon crashed
+			logrus.WithError(err).WithField("file", f.Name()).Debug("skipping corrupted entry in events journal")
+			continue
+		}
+		if !handle(m) {
+			return true, nil
+		}
+	}
+	return false, errors.Wrap(scanner.Err(), "error reading events journal segment")
+}
+
+// Close writes the queued messages and closes the journal. Further calls
+// to Append fail.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return nil
+	}
+	j.closed = true
+	j.mu.Unlock()
+
+	close(j.stop)
+	<-j.done
+	if j.current == nil {
+		return nil
+	}
+	err := j.current.Close()
+	j.current = nil
+	return err
+}
@@ -0,0 +1,185 @@
+package events
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	eventtypes "github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMessage(id string, ts time.Time) eventtypes.Message {
+	return eventtypes.Message{
+		Type:     eventtypes.ContainerEventType,
+		Action:   "start",
+		Actor:    eventtypes.Actor{ID: id},
+		Time:     ts.Unix(),
+		TimeNano: ts.UnixNano(),
+	}
+}
+
+// waitWritten waits for the writer goroutine of the journal to write the
+// appended events to disk.
+func waitWritten(t *testing.T, j *Journal) {
+	for i := 0; ; i++ {
+		j.mu.Lock()
+		pending := len(j.pending)
+		j.mu.Unlock()
+		if pending == 0 {
+			return
+		}
+		if i == 100 {
+			t.Fatalf("%d events were not written to the journal", pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJournalReplayAfterReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events-journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j, err := OpenJournal(dir, JournalOptions{MaxSize: 1024 * 1024})
+	require.NoError(t, err)
+
+	start := time.Now().Add(-time.Hour)
+	for i, id := range []string{"a", "b", "c"} {
+		require.NoError(t, j.Append(newTestMessage(id, start.Add(time.Duration(i)*time.Minute))))
+	}
+	require.NoError(t, j.Close())
+
+	j, err = OpenJournal(dir, JournalOptions{MaxSize: 1024 * 1024})
+	require.NoError(t, err)
+	defer j.Close()
+
+	messages, err := j.Read(start.Add(time.Minute), time.Time{}, nil)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "b", messages[0].Actor.ID)
+	assert.Equal(t, "c", messages[1].Actor.ID)
+
+	messages, err = j.Read(start, start.Add(time.Minute), func(m interface{}) bool {
+		return m.(eventtypes.Message).Actor.ID != "a"
+	})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "b", messages[0].Actor.ID)
+}
+
+func TestJournalRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events-journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j, err := OpenJournal(dir, JournalOptions{MaxSize: 2048})
+	require.NoError(t, err)
+	defer j.Close()
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, j.Append(newTestMessage("cont", start.Add(time.Duration(i)*time.Second))))
+	}
+	waitWritten(t, j)
+
+	var total int64
+	fis, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	for _, fi := range fis {
+		total += fi.Size()
+	}
+	assert.True(t, total <= 2048, "journal size %d exceeds limit", total)
+
+	messages, err := j.Read(start, time.Time{}, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, messages)
+	assert.True(t, len(messages) < 100)
+	// the newest events are kept
+	assert.Equal(t, start.Add(99*time.Second).UnixNano(), messages[len(messages)-1].TimeNano)
+}
+
+func TestJournalMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events-journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j, err := OpenJournal(dir, JournalOptions{MaxSize: 1024 * 1024, MaxAge: 200 * time.Millisecond})
+	require.NoError(t, err)
+	defer j.Close()
+
+	start := time.Now()
+	require.NoError(t, j.Append(newTestMessage("cont", start)))
+	waitWritten(t, j)
+
+	// the event expires without any other event being appended
+	for i := 0; ; i++ {
+		messages, err := j.Read(start, time.Time{}, nil)
+		require.NoError(t, err)
+		if len(messages) == 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("expected the event to expire from the journal")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestJournalSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events-journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j, err := OpenJournal(dir, JournalOptions{MaxSize: 1024 * 1024})
+	require.NoError(t, err)
+	defer j.Close()
+
+	start := time.Now()
+	require.NoError(t, j.Append(newTestMessage("a", start)))
+	waitWritten(t, j)
+
+	// events that are not written yet are part of the snapshot; the writer
+	// is not woken up for this one
+	j.mu.Lock()
+	j.pending = append(j.pending, newTestMessage("b", start.Add(time.Second)))
+	j.mu.Unlock()
+	s := j.snapshot()
+	j.mu.Lock()
+	j.pending = j.pending[:0]
+	j.mu.Unlock()
+
+	// events written after the snapshot is taken are not
+	require.NoError(t, j.Append(newTestMessage("c", start.Add(2*time.Second))))
+	waitWritten(t, j)
+
+	messages, err := s.read(start, time.Time{}, nil)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "a", messages[0].Actor.ID)
+	assert.Equal(t, "b", messages[1].Actor.ID)
+}
+
+func TestLoadBufferedEventsFromJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events-journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j, err := OpenJournal(dir, JournalOptions{MaxSize: 1024 * 1024})
+	require.NoError(t, err)
+	e := NewWithJournal(j)
+	defer e.Close()
+
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < eventsLimit+10; i++ {
+		e.PublishMessage(newTestMessage("cont", start.Add(time.Duration(i)*time.Second)))
+	}
+
+	// the oldest events are not buffered in memory anymore
+	messages, ch := e.SubscribeTopic(start, time.Time{}, nil)
+	defer e.Evict(ch)
+	assert.Len(t, messages, eventsLimit+10)
+	assert.Equal(t, start.UnixNano(), messages[0].TimeNano)
+}
@@ -0,0 +1,609 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	eventtypes "github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/daemon/events/sinkconfig"
+	"github.com/docker/docker/pkg/plugingetter"
+	"github.com/docker/docker/pkg/plugins"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SinkAPIImplements is the name of the interface that event sink
+	// plugins implement.
+	SinkAPIImplements = "EventSink"
+
+	// sinkAPIEvents is the url for delivering a batch of events to a plugin.
+	sinkAPIEvents = SinkAPIImplements + ".Events"
+
+	maxSinkBatchSize  = 100
+	maxSinkOverflow   = 4096 // maximum number of events waiting to be spooled
+	minSinkRetryDelay = 100 * time.Millisecond
+	maxSinkRetryDelay = 30 * time.Second
+)
+
+// SinkRequest is the request sent to event sink plugins.
+type SinkRequest struct {
+	// Events are the events to deliver, oldest first.
+	Events []eventtypes.Message
+}
+
+// SinkResponse is the response of event sink plugins.
+type SinkResponse struct {
+	// Err is set if the plugin failed to handle the events. The events are
+	// delivered again after a delay.
+	Err string
+}
+
+// Sink forwards events to an event sink plugin.
+//
+// Events are queued in a bounded buffer and delivered in batches, one batch
+// at a time. A batch is only removed from the buffer once the plugin
+// acknowledged it, so events are delivered at least once and a slow plugin
+// controls the pace of delivery. If the plugin falls so far behind that the
+// buffer is full, the following events are spooled to a file, from which
+// they are delivered once the buffer drains.
+//
+// When the sink is closed, the events that were not delivered yet are saved
+// to the spool file, and delivered by the next sink with the same name,
+// after a configuration reload or a daemon restart.
+type Sink struct {
+	config sinkconfig.Config
+	filter *Filter
+	getter plugingetter.PluginGetter
+	root   string
+
+	mu       sync.Mutex
+	started  bool
+	queue    []sinkEntry
+	seq      uint64 // sequence number of the last queued event
+	size     int
+	overflow []eventtypes.Message // events waiting to be spooled
+	dropped  int                  // number of events dropped because overflow was full
+	spooling int                  // number of events being spooled
+	spooled  int                  // number of spooled events not queued yet
+
+	notify      chan struct{}
+	spoolNotify chan struct{}
+	closed      chan struct{}
+	closeOnce   sync.Once
+	done        chan struct{}
+	spoolDone   chan struct{}
+
+	// ctx is canceled when the sink is closed, to abandon the delivery in
+	// progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// spoolMu protects the spool file, which is written by the spooler
+	// goroutine and read by the delivery goroutine. It is never held while
+	// enqueueing events.
+	spoolMu     sync.Mutex
+	spool       *os.File
+	spoolOffset int64 // offset of the first spooled event not queued yet
+	spoolSize   int64
+
+	plugin *plugins.Client
+}
+
+type sinkEntry struct {
+	seq uint64
+	msg eventtypes.Message
+}
+
+// NewSink returns a Sink forwarding events to the plugin described by cfg.
+// Events that don't fit in the buffer are spooled to a file in root. The
+// plugin is looked up through the given plugin getter, or among legacy
+// plugins if it is nil. The sink starts delivering events, beginning with
+// the ones saved by a previous sink with the same name, once it is passed
+// to Events.SetSinks.
+func NewSink(cfg sinkconfig.Config, root string, getter plugingetter.PluginGetter) *Sink {
+	if cfg.BufferSize < 1 {
+		cfg.BufferSize = sinkconfig.DefaultBufferSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Sink{
+		config:      cfg,
+		filter:      NewFilter(cfg.Filters),
+		getter:      getter,
+		root:        root,
+		size:        cfg.BufferSize,
+		notify:      make(chan struct{}, 1),
+		spoolNotify: make(chan struct{}, 1),
+		closed:      make(chan struct{}),
+		done:        make(chan struct{}),
+		spoolDone:   make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Name returns the name of the plugin events are forwarded to.
+func (s *Sink) Name() string {
+	return s.config.Name
+}
+
+// Config returns the configuration of the sink.
+func (s *Sink) Config() sinkconfig.Config {
+	return s.config
+}
+
+// spoolPath returns the path of the spool file of the sink, which only
+// depends on its name.
+func (s *Sink) spoolPath() string {
+	return filepath.Join(s.root, url.QueryEscape(s.Name())+".spool")
+}
+
+// start loads the events saved by a previous sink with the same name and
+// starts delivering events. It does nothing if the sink was already started
+// or closed.
+func (s *Sink) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	if err := s.loadSpool(); err != nil {
+		logrus.WithError(err).WithField("sink", s.Name()).Error("error loading saved events, dropping them")
+		os.Remove(s.spoolPath())
+	}
+	go s.run()
+	go s.runSpooler()
+}
+
+// loadSpool opens the spool file left by a previous sink, if any, so that
+// its events are delivered first. An incomplete last event, written while
+// the daemon crashed, is discarded.
+func (s *Sink) loadSpool() error {
+	f, err := os.OpenFile(s.spoolPath(), os.O_RDWR, 0600)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "error opening event sink spool")
+	}
+
+	var (
+		size int64
+		n    int
+	)
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return errors.Wrap(err, "error reading event sink spool")
+		}
+		size += int64(len(line))
+		n++
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return errors.Wrap(err, "error truncating event sink spool")
+	}
+
+	s.spool = f
+	s.spoolSize = size
+	s.spooled = n
+	return nil
+}
+
+func wakeUp(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue adds a message to the delivery queue if it matches the filters,
+// or to the events to spool if the queue is full. If the spooler doesn't
+// keep up either, the message is dropped. It never blocks.
+func (s *Sink) enqueue(m eventtypes.Message) {
+	if !s.filter.Include(m) {
+		return
+	}
+
+	s.mu.Lock()
+	if len(s.queue) < s.size && len(s.overflow) == 0 && s.spooling == 0 && s.spooled == 0 {
+		s.seq++
+		s.queue = append(s.queue, sinkEntry{seq: s.seq, msg: m})
+		s.mu.Unlock()
+		wakeUp(s.notify)
+		return
+	}
+	if len(s.overflow) < maxSinkOverflow {
+		s.overflow = append(s.overflow, m)
+	} else {
+		s.dropped++
+	}
+	s.mu.Unlock()
+	wakeUp(s.spoolNotify)
+}
+
+// next returns the next batch of events to deliver and the sequence number
+// of its last event, waiting for events to be queued. It returns nil if the
+// sink was closed.
+func (s *Sink) next() ([]eventtypes.Message, uint64) {
+	for {
+		s.mu.Lock()
+		refill := len(s.queue) == 0 && (len(s.overflow) > 0 || s.spooled > 0)
+		s.mu.Unlock()
+		if refill {
+			s.refill()
+		}
+
+		s.mu.Lock()
+		if n := len(s.queue); n > 0 {
+			if n > maxSinkBatchSize {
+				n = maxSinkBatchSize
+			}
+			batch := make([]eventtypes.Message, n)
+			for i := range batch {
+				batch[i] = s.queue[i].msg
+			}
+			last := s.queue[n-1].seq
+			s.mu.Unlock()
+			return batch, last
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-s.closed:
+			return nil, 0
+		}
+	}
+}
+
+// ack removes the events up to the given sequence number from the queue.
+func (s *Sink) ack(last uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := 0
+	for i < len(s.queue) && s.queue[i].seq <= last {
+		i++
+	}
+	s.queue = s.queue[:copy(s.queue, s.queue[i:])]
+}
+
+// refill fills the queue with the spooled events, oldest first, or with
+// the events waiting to be spooled if there are none.
+func (s *Sink) refill() {
+	s.spoolMu.Lock()
+	defer s.spoolMu.Unlock()
+
+	s.mu.Lock()
+	room := s.size - len(s.queue)
+	if s.spooled == 0 {
+		if room > len(s.overflow) {
+			room = len(s.overflow)
+		}
+		for _, m := range s.overflow[:room] {
+			s.seq++
+			s.queue = append(s.queue, sinkEntry{seq: s.seq, msg: m})
+		}
+		s.overflow = s.overflow[room:]
+		s.mu.Unlock()
+		return
+	}
+	if room > s.spooled {
+		room = s.spooled
+	}
+	s.mu.Unlock()
+
+	msgs, err := s.readSpool(room)
+	if err != nil {
+		logrus.WithError(err).WithField("sink", s.Name()).Error("error reading spooled events, dropping them")
+	}
+
+	s.mu.Lock()
+	for _, m := range msgs {
+		s.seq++
+		s.queue = append(s.queue, sinkEntry{seq: s.seq, msg: m})
+	}
+	if err != nil {
+		s.spooled = 0
+	} else {
+		s.spooled -= len(msgs)
+	}
+	empty := s.spooled == 0
+	s.mu.Unlock()
+
+	if empty {
+		s.resetSpool()
+	}
+}
+
+// readSpool reads the next n spooled events. It must be called with
+// spoolMu held.
+func (s *Sink) readSpool(n int) ([]eventtypes.Message, error) {
+	r := bufio.NewReader(io.NewSectionReader(s.spool, s.spoolOffset, s.spoolSize-s.spoolOffset))
+	msgs := make([]eventtypes.Message, 0, n)
+	for len(msgs) < n {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return msgs, errors.Wrap(err, "error reading event sink spool")
+		}
+		var m eventtypes.Message
+		if err := json.Unmarshal(line, &m); err != nil {
+			return msgs, errors.Wrap(err, "error decoding spooled event")
+		}
+		s.spoolOffset += int64(len(line))
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+// resetSpool empties the spool file once all its events are queued. It
+// must be called with spoolMu held.
+func (s *Sink) resetSpool() {
+	s.spoolOffset = 0
+	s.spoolSize = 0
+	if s.spool == nil {
+		return
+	}
+	if err := s.spool.Truncate(0); err != nil {
+		logrus.WithError(err).WithField("sink", s.Name()).Warn("error truncating event sink spool")
+	}
+}
+
+// runSpooler writes the events that don't fit in the queue to the spool
+// file until the sink is closed.
+func (s *Sink) runSpooler() {
+	defer close(s.spoolDone)
+
+	for {
+		select {
+		case <-s.spoolNotify:
+			s.writeSpool()
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func (s *Sink) writeSpool() {
+	s.spoolMu.Lock()
+	defer s.spoolMu.Unlock()
+
+	s.mu.Lock()
+	msgs := s.overflow
+	s.overflow = nil
+	s.spooling = len(msgs)
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+	if dropped > 0 {
+		logrus.WithField("sink", s.Name()).Errorf("event sink spool can't keep up, dropped %d events", dropped)
+	}
+	if len(msgs) == 0 {
+		return
+	}
+
+	err := s.appendSpool(msgs)
+	if err != nil {
+		logrus.WithError(err).WithField("sink", s.Name()).Errorf("error spooling events, dropped %d events", len(msgs))
+	}
+
+	s.mu.Lock()
+	s.spooling = 0
+	if err == nil {
+		s.spooled += len(msgs)
+	}
+	s.mu.Unlock()
+	wakeUp(s.notify)
+}
+
+// appendSpool appends the events to the spool file, creating it if needed.
+// It must be called with spoolMu held.
+func (s *Sink) appendSpool(msgs []eventtypes.Message) error {
+	if s.spool == nil {
+		if err := os.MkdirAll(s.root, 0700); err != nil {
+			return errors.Wrap(err, "error creating event sink spool directory")
+		}
+		f, err := os.OpenFile(s.spoolPath(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return errors.Wrap(err, "error creating event sink spool")
+		}
+		s.spool = f
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, m := range msgs {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	n, err := s.spool.WriteAt(buf.Bytes(), s.spoolSize)
+	if err != nil {
+		return errors.Wrap(err, "error writing event sink spool")
+	}
+	s.spoolSize += int64(n)
+	return nil
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+
+	for {
+		batch, last := s.next()
+		if batch == nil {
+			return
+		}
+
+		delay := minSinkRetryDelay
+		for {
+			err := s.send(batch)
+			if err == nil {
+				break
+			}
+			select {
+			case <-s.closed:
+				return
+			default:
+			}
+			logrus.WithError(err).WithField("sink", s.Name()).Errorf("error forwarding events, retrying in %s", delay)
+			select {
+			case <-time.After(delay):
+			case <-s.closed:
+				return
+			}
+			delay *= 2
+			if delay > maxSinkRetryDelay {
+				delay = maxSinkRetryDelay
+			}
+		}
+		s.ack(last)
+	}
+}
+
+func (s *Sink) send(batch []eventtypes.Message) error {
+	if s.plugin == nil {
+		var (
+			plugin plugingetter.CompatPlugin
+			err    error
+		)
+		if s.getter != nil {
+			plugin, err = s.getter.Get(s.Name(), SinkAPIImplements, plugingetter.Lookup)
+		} else {
+			plugin, err = plugins.Get(s.Name(), SinkAPIImplements)
+		}
+		if err != nil {
+			return errors.Wrap(err, "error looking up event sink plugin")
+		}
+		s.plugin = plugin.Client()
+	}
+
+	var res SinkResponse
+	if err := s.plugin.CallWithOptions(sinkAPIEvents, &SinkRequest{Events: batch}, &res, plugins.WithRequestContext(s.ctx)); err != nil {
+		// the plugin may have been restarted or replaced, look it up again
+		s.plugin = nil
+		return err
+	}
+	if res.Err != "" {
+		return errors.New(res.Err)
+	}
+	return nil
+}
+
+// Close stops forwarding events, abandoning a delivery in progress, and
+// waits for the sink goroutines to return. The events that were not
+// delivered yet, including the abandoned ones, are saved to the spool file
+// for the next sink with the same name. The spool file is removed if there
+// are none.
+func (s *Sink) Close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		started := s.started
+		s.started = true // a closed sink is never started
+		s.mu.Unlock()
+
+		close(s.closed)
+		s.cancel()
+		if !started {
+			close(s.done)
+			close(s.spoolDone)
+			return
+		}
+		<-s.done
+		<-s.spoolDone
+
+		s.spoolMu.Lock()
+		defer s.spoolMu.Unlock()
+		if err := s.save(); err != nil {
+			logrus.WithError(err).WithField("sink", s.Name()).Error("error saving undelivered events, dropping them")
+		}
+	})
+}
+
+// save writes the events that were not delivered yet to the spool file,
+// oldest first, and closes it. It must be called with spoolMu held, once
+// the sink goroutines returned.
+func (s *Sink) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	spool := s.spool
+	s.spool = nil
+	pending := len(s.queue) > 0 || s.spooled > 0 || len(s.overflow) > 0
+
+	tmp := s.spoolPath() + ".tmp"
+	var err error
+	if pending {
+		err = s.writePending(tmp, spool)
+	}
+	// the spool must be closed before it is replaced or removed on Windows
+	if spool != nil {
+		spool.Close()
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if !pending {
+		if err := os.Remove(s.spoolPath()); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "error removing event sink spool")
+		}
+		return nil
+	}
+	if err := os.Rename(tmp, s.spoolPath()); err != nil {
+		os.Remove(tmp)
+		return errors.Wrap(err, "error saving event sink spool")
+	}
+	return nil
+}
+
+// writePending writes the queued events, the spooled events not queued yet
+// and the events waiting to be spooled to the file at path, in this order.
+func (s *Sink) writePending(path string, spool *os.File) error {
+	if err := os.MkdirAll(s.root, 0700); err != nil {
+		return errors.Wrap(err, "error creating event sink spool directory")
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error creating event sink spool")
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range s.queue {
+		if err := enc.Encode(e.msg); err != nil {
+			return err
+		}
+	}
+	if s.spooled > 0 {
+		if _, err := io.Copy(w, io.NewSectionReader(spool, s.spoolOffset, s.spoolSize-s.spoolOffset)); err != nil {
+			return errors.Wrap(err, "error reading event sink spool")
+		}
+	}
+	for _, m := range s.overflow {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "error writing event sink spool")
+	}
+	return f.Sync()
+}
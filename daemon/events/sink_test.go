@@ -0,0 +1,315 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	eventtypes "github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/daemon/events/sinkconfig"
+	"github.com/docker/docker/pkg/plugingetter"
+	"github.com/docker/docker/pkg/plugins"
+	"github.com/docker/docker/pkg/plugins/transport"
+	"github.com/docker/go-connections/tlsconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSinkPlugin struct {
+	client *plugins.Client
+}
+
+func (p *fakeSinkPlugin) Client() *plugins.Client { return p.client }
+func (p *fakeSinkPlugin) Name() string            { return "fakesink" }
+func (p *fakeSinkPlugin) BasePath() string        { return "" }
+func (p *fakeSinkPlugin) IsV1() bool              { return true }
+
+type fakeSinkGetter struct {
+	plugingetter.PluginGetter
+	plugin *fakeSinkPlugin
+}
+
+func (g *fakeSinkGetter) Get(name, capability string, mode int) (plugingetter.CompatPlugin, error) {
+	return g.plugin, nil
+}
+
+// newFakeSinkServer starts a plugin server that fails the first `failures`
+// deliveries and records the events of the following ones.
+func newFakeSinkServer(t *testing.T, failures int) (*httptest.Server, plugingetter.PluginGetter, func() []eventtypes.Message) {
+	var (
+		mu       sync.Mutex
+		received []eventtypes.Message
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/"+sinkAPIEvents, r.URL.Path)
+
+		var req SinkRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		var res SinkResponse
+		mu.Lock()
+		if failures > 0 {
+			failures--
+			res.Err = "not ready"
+		} else {
+			received = append(received, req.Events...)
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", transport.VersionMimetype)
+		json.NewEncoder(w).Encode(res)
+	}))
+
+	client, err := plugins.NewClient(server.URL, &tlsconfig.Options{InsecureSkipVerify: true})
+	require.NoError(t, err)
+
+	getter := &fakeSinkGetter{plugin: &fakeSinkPlugin{client: client}}
+	return server, getter, func() []eventtypes.Message {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]eventtypes.Message(nil), received...)
+	}
+}
+
+func waitForEvents(t *testing.T, received func() []eventtypes.Message, n int) []eventtypes.Message {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if msgs := received(); len(msgs) >= n {
+			return msgs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for %d events, got %d", n, len(received()))
+	return nil
+}
+
+func TestSinkForwardsFilteredEvents(t *testing.T) {
+	server, getter, received := newFakeSinkServer(t, 0)
+	defer server.Close()
+
+	cfg, err := sinkconfig.Parse("fakesink,filter=type=container")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "event-sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := New()
+	e.SetSinks([]*Sink{NewSink(cfg, dir, getter)})
+	defer e.Close()
+
+	e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: "cont1"})
+	e.Log("pull", eventtypes.ImageEventType, eventtypes.Actor{ID: "image"})
+	e.Log("die", eventtypes.ContainerEventType, eventtypes.Actor{ID: "cont2"})
+
+	msgs := waitForEvents(t, received, 2)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "cont1", msgs[0].Actor.ID)
+	assert.Equal(t, "cont2", msgs[1].Actor.ID)
+}
+
+func TestSinkRetriesUntilDelivered(t *testing.T) {
+	server, getter, received := newFakeSinkServer(t, 2)
+	defer server.Close()
+
+	cfg, err := sinkconfig.Parse("fakesink")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "event-sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := New()
+	e.SetSinks([]*Sink{NewSink(cfg, dir, getter)})
+	defer e.Close()
+
+	e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: "cont"})
+
+	msgs := waitForEvents(t, received, 1)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "start", msgs[0].Action)
+}
+
+func TestSinkSpoolsEventsWhenBufferIsFull(t *testing.T) {
+	server, getter, received := newFakeSinkServer(t, 3)
+	defer server.Close()
+
+	cfg, err := sinkconfig.Parse("fakesink,buffer-size=2")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "event-sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := New()
+	sink := NewSink(cfg, dir, getter)
+	e.SetSinks([]*Sink{sink})
+	defer e.Close()
+
+	for i := 0; i < 50; i++ {
+		e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: fmt.Sprintf("cont%d", i)})
+	}
+
+	// no event is dropped, and they are delivered in order
+	msgs := waitForEvents(t, received, 50)
+	require.Len(t, msgs, 50)
+	for i, m := range msgs {
+		assert.Equal(t, fmt.Sprintf("cont%d", i), m.Actor.ID)
+	}
+
+	sink.Close()
+	fis, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, fis, "the spool file is removed when the sink is closed")
+}
+
+func TestSinkCloseAbandonsDelivery(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	client, err := plugins.NewClient(server.URL, &tlsconfig.Options{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	getter := &fakeSinkGetter{plugin: &fakeSinkPlugin{client: client}}
+
+	cfg, err := sinkconfig.Parse("fakesink")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "event-sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := New()
+	e.SetSinks([]*Sink{NewSink(cfg, dir, getter)})
+	e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: "cont"})
+	time.Sleep(100 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		e.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the sink waited for the delivery in progress")
+	}
+	_, err = os.Stat(filepath.Join(dir, "fakesink.spool"))
+	assert.NoError(t, err, "the abandoned event is saved")
+}
+
+func TestSinkDeliversEventsSavedOnClose(t *testing.T) {
+	server, getter, _ := newFakeSinkServer(t, 1000)
+	defer server.Close()
+
+	cfg, err := sinkconfig.Parse("fakesink,buffer-size=2")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "event-sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the plugin doesn't accept any event before the daemon shuts down
+	e := New()
+	e.SetSinks([]*Sink{NewSink(cfg, dir, getter)})
+	for i := 0; i < 10; i++ {
+		e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: fmt.Sprintf("cont%d", i)})
+	}
+	require.NoError(t, e.Close())
+
+	server, getter, received := newFakeSinkServer(t, 0)
+	defer server.Close()
+
+	e = New()
+	e.SetSinks([]*Sink{NewSink(cfg, dir, getter)})
+	defer e.Close()
+	e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: "cont10"})
+
+	msgs := waitForEvents(t, received, 11)
+	require.Len(t, msgs, 11)
+	for i, m := range msgs {
+		assert.Equal(t, fmt.Sprintf("cont%d", i), m.Actor.ID)
+	}
+}
+
+func TestSetSinksHandsOverPendingEvents(t *testing.T) {
+	failing, failingGetter, _ := newFakeSinkServer(t, 1000)
+	defer failing.Close()
+	server, getter, received := newFakeSinkServer(t, 0)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "event-sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg, err := sinkconfig.Parse("fakesink,buffer-size=2")
+	require.NoError(t, err)
+	e := New()
+	defer e.Close()
+	e.SetSinks([]*Sink{NewSink(cfg, dir, failingGetter)})
+	for i := 0; i < 5; i++ {
+		e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: fmt.Sprintf("cont%d", i)})
+	}
+
+	// the reloaded configuration of the sink only changes its filters
+	cfg, err = sinkconfig.Parse("fakesink,filter=type=container")
+	require.NoError(t, err)
+	e.SetSinks([]*Sink{NewSink(cfg, dir, getter)})
+
+	msgs := waitForEvents(t, received, 5)
+	require.Len(t, msgs, 5)
+	for i, m := range msgs {
+		assert.Equal(t, fmt.Sprintf("cont%d", i), m.Actor.ID)
+	}
+}
+
+func TestSinkOverflowIsBounded(t *testing.T) {
+	cfg, err := sinkconfig.Parse("fakesink,buffer-size=2")
+	require.NoError(t, err)
+
+	// the sink is not started, so nothing is delivered or spooled
+	s := NewSink(cfg, "", nil)
+	for i := 0; i < maxSinkOverflow+10; i++ {
+		s.enqueue(eventtypes.Message{Type: eventtypes.ContainerEventType, Action: "start"})
+	}
+	assert.Len(t, s.queue, 2)
+	assert.Len(t, s.overflow, maxSinkOverflow)
+	assert.Equal(t, 8, s.dropped)
+}
+
+func TestSetSinksKeepsUnchangedSinks(t *testing.T) {
+	cfg, err := sinkconfig.Parse("fakesink")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "event-sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := New()
+	defer e.Close()
+	kept := NewSink(cfg, dir, nil)
+	removed := NewSink(cfg, dir, nil)
+	e.SetSinks([]*Sink{kept, removed})
+	e.SetSinks([]*Sink{kept})
+
+	assert.Equal(t, []*Sink{kept}, e.Sinks())
+	select {
+	case <-removed.done:
+	default:
+		t.Fatal("expected the removed sink to be closed")
+	}
+	select {
+	case <-kept.closed:
+		t.Fatal("expected the kept sink not to be closed")
+	default:
+	}
+}
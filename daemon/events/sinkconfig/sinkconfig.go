@@ -0,0 +1,72 @@
+// Package sinkconfig parses the configuration of the event sinks, without
+// depending on the implementation of the sinks.
+package sinkconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/filters"
+)
+
+// DefaultBufferSize is the number of events queued in memory for delivery
+// to a sink when its configuration doesn't set it.
+const DefaultBufferSize = 1024
+
+// Config is the configuration of an event sink.
+type Config struct {
+	// Name is the name of the plugin events are forwarded to.
+	Name string
+	// Filters restricts the events that are forwarded to the plugin.
+	Filters filters.Args
+	// BufferSize is the maximum number of events queued in memory for
+	// delivery. Further events are spooled to disk.
+	BufferSize int
+}
+
+// Parse parses an event sink configuration of the form
+// `name[,filter=key=value...][,buffer-size=N]`, for example
+// `mysink,filter=type=container,filter=event=die`.
+func Parse(value string) (Config, error) {
+	fields := strings.Split(value, ",")
+	cfg := Config{
+		Name:       strings.TrimSpace(fields[0]),
+		Filters:    filters.NewArgs(),
+		BufferSize: DefaultBufferSize,
+	}
+	if cfg.Name == "" {
+		return Config{}, fmt.Errorf("invalid event sink %q: missing plugin name", value)
+	}
+
+	for _, field := range fields[1:] {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return Config{}, fmt.Errorf("invalid event sink option %q", field)
+		}
+		switch kv[0] {
+		case "filter":
+			f := strings.SplitN(kv[1], "=", 2)
+			if len(f) != 2 {
+				return Config{}, fmt.Errorf("invalid event sink filter %q", kv[1])
+			}
+			cfg.Filters.Add(f[0], f[1])
+		case "buffer-size":
+			var n int
+			if _, err := fmt.Sscanf(kv[1], "%d", &n); err != nil || n < 1 {
+				return Config{}, fmt.Errorf("invalid event sink buffer size %q", kv[1])
+			}
+			cfg.BufferSize = n
+		default:
+			return Config{}, fmt.Errorf("unknown event sink option %q", kv[0])
+		}
+	}
+	return cfg, nil
+}
+
+// Validate validates an event sink configuration string.
+func Validate(value string) (string, error) {
+	if _, err := Parse(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
@@ -0,0 +1,22 @@
+package sinkconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse("mysink,filter=type=container,filter=event=die,buffer-size=10")
+	require.NoError(t, err)
+	assert.Equal(t, "mysink", cfg.Name)
+	assert.Equal(t, 10, cfg.BufferSize)
+	assert.True(t, cfg.Filters.ExactMatch("type", "container"))
+	assert.True(t, cfg.Filters.ExactMatch("event", "die"))
+
+	for _, invalid := range []string{"", ",filter=type=container", "mysink,filter=type", "mysink,buffer-size=0", "mysink,foo=bar"} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -88,3 +88,22 @@ func validateTestAttributes(t *testing.T, l chan interface{}, expectedAttributes
 		t.Fatal("LogEvent test timed out")
 	}
 }
+
+func TestNewEventSinksKeepsUnchangedSinks(t *testing.T) {
+	current := newEventSinks([]string{"sink1", "sink2,filter=type=container"}, "", nil, nil)
+	defer func() {
+		for _, s := range current {
+			s.Close()
+		}
+	}()
+	kept := current[1]
+
+	sinks := newEventSinks([]string{"sink2,filter=type=container", "sink1,buffer-size=10"}, "", nil, current)
+	defer sinks[1].Close()
+	if sinks[0] != kept {
+		t.Fatal("expected the sink with an unchanged configuration to be kept")
+	}
+	if sinks[1] == current[0] {
+		t.Fatal("expected the sink with a changed configuration to be replaced")
+	}
+}
@@ -0,0 +1,14 @@
+package quota
+
+// ErrQuotaNotSupported is returned when project quotas are not available
+// on the platform or the backing filesystem.
+var ErrQuotaNotSupported = errQuotaNotSupported{}
+
+type errQuotaNotSupported struct {
+}
+
+func (e errQuotaNotSupported) NotImplemented() {}
+
+func (e errQuotaNotSupported) Error() string {
+	return "filesystem does not support, or has not enabled quotas"
+}
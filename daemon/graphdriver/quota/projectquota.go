@@ -54,33 +54,47 @@ import (
 	"io/ioutil"
 	"path"
 	"path/filepath"
+	"sync"
 	"unsafe"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 )
 
-// Quota limit params - currently we only control blocks hard limit
-type Quota struct {
-	Size uint64
-}
+// xfsMagic is the filesystem id of xfs, see graphdriver.FsMagicXfs.
+const xfsMagic = 0x58465342
 
 // Control - Context to be used by storage driver (e.g. overlay)
 // who wants to apply project quotas to container dirs
 type Control struct {
 	backingFsBlockDev string
-	nextProjectID     uint32
+	projectIDs        *projectIDs
 	quotas            map[string]uint32
 }
 
+// projectIDs holds the next project id to be used on a backing filesystem.
+// Project ids are shared by all the Controls on the same filesystem, e.g.
+// those of the graph driver and of the local volumes, so that they never
+// assign the same project id to different directories.
+type projectIDs struct {
+	next uint32
+}
+
+var (
+	// projectIDsMu protects backingFsProjectIDs and the projectIDs in it
+	projectIDsMu        sync.Mutex
+	backingFsProjectIDs = make(map[uint64]*projectIDs)
+)
+
 // NewControl - initialize project quota support.
 // Test to make sure that quota can be set on a test dir and find
 // the first project id to be used for the next container create.
 //
 // Returns nil (and error) if project quota is not supported.
 //
-// First get the project id of the home directory.
-// This test will fail if the backing fs is not xfs.
+// First check that the backing fs is xfs, so that the backing filesystem
+// device node is only created in the base path of filesystems that may
+// support project quotas. Then get the project id of the home directory.
 //
 // xfs_quota tool can be used to assign a project id to the driver home directory, e.g.:
 //    echo 999:/var/lib/docker/overlay2 >> /etc/projects
@@ -96,6 +110,14 @@ type Control struct {
 // project ids.
 //
 func NewControl(basePath string) (*Control, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(basePath, &stat); err != nil {
+		return nil, err
+	}
+	if uint32(stat.Type) != xfsMagic {
+		return nil, ErrQuotaNotSupported
+	}
+
 	//
 	// Get project id of parent dir as minimal id to be used by driver
 	//
@@ -108,25 +130,37 @@ func NewControl(basePath string) (*Control, error) {
 	//
 	// create backing filesystem device node
 	//
-	backingFsBlockDev, err := makeBackingFsDev(basePath)
+	backingFsBlockDev, backingFsDev, err := makeBackingFsDev(basePath)
 	if err != nil {
 		return nil, err
 	}
 
-	//
-	// Test if filesystem supports project quotas by trying to set
-	// a quota on the first available project id
-	//
-	quota := Quota{
-		Size: 0,
-	}
-	if err := setProjectQuota(backingFsBlockDev, minProjectID, quota); err != nil {
-		return nil, err
+	projectIDsMu.Lock()
+	defer projectIDsMu.Unlock()
+
+	ids, ok := backingFsProjectIDs[backingFsDev]
+	if !ok {
+		//
+		// Test if filesystem supports project quotas by trying to set
+		// a quota on the first available project id
+		//
+		quota := Quota{
+			Size: 0,
+		}
+		if err := setProjectQuota(backingFsBlockDev, minProjectID, quota); err != nil {
+			return nil, err
+		}
+		ids = &projectIDs{next: minProjectID + 1}
+	} else if ids.next <= minProjectID {
+		// Another Control already uses the filesystem, so it supports
+		// project quotas, and the first available project id may already
+		// be assigned.
+		ids.next = minProjectID + 1
 	}
 
 	q := Control{
 		backingFsBlockDev: backingFsBlockDev,
-		nextProjectID:     minProjectID + 1,
+		projectIDs:        ids,
 		quotas:            make(map[string]uint32),
 	}
 
@@ -137,8 +171,9 @@ func NewControl(basePath string) (*Control, error) {
 	if err != nil {
 		return nil, err
 	}
+	backingFsProjectIDs[backingFsDev] = ids
 
-	logrus.Debugf("NewControl(%s): nextProjectID = %d", basePath, q.nextProjectID)
+	logrus.Debugf("NewControl(%s): nextProjectID = %d", basePath, ids.next)
 	return &q, nil
 }
 
@@ -148,18 +183,21 @@ func (q *Control) SetQuota(targetPath string, quota Quota) error {
 
 	projectID, ok := q.quotas[targetPath]
 	if !ok {
-		projectID = q.nextProjectID
+		projectIDsMu.Lock()
+		projectID = q.projectIDs.next
 
 		//
 		// assign project id to new container directory
 		//
 		err := setProjectID(targetPath, projectID)
 		if err != nil {
+			projectIDsMu.Unlock()
 			return err
 		}
 
 		q.quotas[targetPath] = projectID
-		q.nextProjectID++
+		q.projectIDs.next++
+		projectIDsMu.Unlock()
 	}
 
 	//
@@ -266,7 +304,8 @@ func setProjectID(targetPath string, projectID uint32) error {
 }
 
 // findNextProjectID - find the next project id to be used for containers
-// by scanning driver home directory to find used project ids
+// by scanning driver home directory to find used project ids. It must be
+// called with projectIDsMu held.
 func (q *Control) findNextProjectID(home string) error {
 	files, err := ioutil.ReadDir(home)
 	if err != nil {
@@ -284,8 +323,8 @@ func (q *Control) findNextProjectID(home string) error {
 		if projid > 0 {
 			q.quotas[path] = projid
 		}
-		if q.nextProjectID <= projid {
-			q.nextProjectID = projid + 1
+		if q.projectIDs.next <= projid {
+			q.projectIDs.next = projid + 1
 		}
 	}
 
@@ -319,19 +358,19 @@ func getDirFd(dir *C.DIR) uintptr {
 
 // Get the backing block device of the driver home directory
 // and create a block device node under the home directory
-// to be used by quotactl commands
-func makeBackingFsDev(home string) (string, error) {
+// to be used by quotactl commands. The device number is returned too.
+func makeBackingFsDev(home string) (string, uint64, error) {
 	var stat unix.Stat_t
 	if err := unix.Stat(home, &stat); err != nil {
-		return "", err
+		return "", 0, err
 	}
 
 	backingFsBlockDev := path.Join(home, "backingFsBlockDev")
 	// Re-create just in case someone copied the home directory over to a new device
 	unix.Unlink(backingFsBlockDev)
 	if err := unix.Mknod(backingFsBlockDev, unix.S_IFBLK|0600, int(stat.Dev)); err != nil {
-		return "", fmt.Errorf("Failed to mknod %s: %v", backingFsBlockDev, err)
+		return "", 0, fmt.Errorf("Failed to mknod %s: %v", backingFsBlockDev, err)
 	}
 
-	return backingFsBlockDev, nil
+	return backingFsBlockDev, uint64(stat.Dev), nil
 }
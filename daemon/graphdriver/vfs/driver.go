@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/quota"
 	"github.com/docker/docker/pkg/chrootarchive"
 	"github.com/docker/docker/pkg/containerfs"
 	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/system"
+	units "github.com/docker/go-units"
 	"github.com/opencontainers/selinux/go-selinux/label"
+	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -29,13 +34,51 @@ func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (grap
 		home:       home,
 		idMappings: idtools.NewIDMappingsFromMaps(uidMaps, gidMaps),
 	}
+	if err := d.parseOptions(options); err != nil {
+		return nil, err
+	}
 	rootIDs := d.idMappings.RootPair()
-	if err := idtools.MkdirAllAndChown(home, 0700, rootIDs); err != nil {
+	if err := idtools.MkdirAllAndChown(filepath.Join(home, "dir"), 0700, rootIDs); err != nil {
 		return nil, err
 	}
+
+	// Try to enable project quota support, which is only available if the
+	// driver home is on xfs mounted with the 'pquota' option. The layers
+	// directory is used as base so that the project ids already assigned to
+	// layers are found again after a restart. NewControl fails without
+	// touching the directory if it is not on xfs.
+	quotaCtl, err := quota.NewControl(filepath.Join(home, "dir"))
+	if err == nil {
+		d.quotaCtl = quotaCtl
+	} else if d.quota.Size > 0 {
+		return nil, fmt.Errorf("Storage option vfs.size not supported. Filesystem does not support Project Quota: %v", err)
+	}
+	logrus.Debugf("vfs: projectQuotaSupported=%v", d.quotaCtl != nil)
+
 	return graphdriver.NewNaiveDiffDriver(d, uidMaps, gidMaps), nil
 }
 
+func (d *Driver) parseOptions(options []string) error {
+	for _, option := range options {
+		key, val, err := parsers.ParseKeyValueOpt(option)
+		if err != nil {
+			return err
+		}
+		key = strings.ToLower(key)
+		switch key {
+		case "vfs.size":
+			size, err := units.RAMInBytes(val)
+			if err != nil {
+				return err
+			}
+			d.quota.Size = uint64(size)
+		default:
+			logrus.Warnf("vfs: ignoring unknown option %s", key)
+		}
+	}
+	return nil
+}
+
 // Driver holds information about the driver, home directory of the driver.
 // Driver implements graphdriver.ProtoDriver. It uses only basic vfs operations.
 // In order to support layering, files are copied from the parent layer into the new layer. There is no copy-on-write support.
@@ -43,6 +86,9 @@ func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (grap
 type Driver struct {
 	home       string
 	idMappings *idtools.IDMappings
+	// quota is the default quota applied to writable layers
+	quota    quota.Quota
+	quotaCtl *quota.Control
 }
 
 func (d *Driver) String() string {
@@ -67,15 +113,36 @@ func (d *Driver) Cleanup() error {
 // CreateReadWrite creates a layer that is writable for use as a container
 // file system.
 func (d *Driver) CreateReadWrite(id, parent string, opts *graphdriver.CreateOpts) error {
-	return d.Create(id, parent, opts)
+	q := d.quota
+	if opts != nil {
+		for key, val := range opts.StorageOpt {
+			switch strings.ToLower(key) {
+			case "size":
+				size, err := units.RAMInBytes(val)
+				if err != nil {
+					return err
+				}
+				q.Size = uint64(size)
+			default:
+				return fmt.Errorf("Unknown option %s", key)
+			}
+		}
+	}
+	if q.Size > 0 && d.quotaCtl == nil {
+		return fmt.Errorf("--storage-opt is supported only for vfs over xfs with 'pquota' mount option")
+	}
+	return d.create(id, parent, q)
 }
 
 // Create prepares the filesystem for the VFS driver and copies the directory for the given id under the parent.
 func (d *Driver) Create(id, parent string, opts *graphdriver.CreateOpts) error {
 	if opts != nil && len(opts.StorageOpt) != 0 {
-		return fmt.Errorf("--storage-opt is not supported for vfs")
+		return fmt.Errorf("--storage-opt is supported only for ReadWrite Layers")
 	}
+	return d.create(id, parent, quota.Quota{})
+}
 
+func (d *Driver) create(id, parent string, q quota.Quota) (retErr error) {
 	dir := d.dir(id)
 	rootIDs := d.idMappings.RootPair()
 	if err := idtools.MkdirAllAndChown(filepath.Dir(dir), 0700, rootIDs); err != nil {
@@ -84,6 +151,20 @@ func (d *Driver) Create(id, parent string, opts *graphdriver.CreateOpts) error {
 	if err := idtools.MkdirAndChown(dir, 0755, rootIDs); err != nil {
 		return err
 	}
+	defer func() {
+		// Clean up on failure
+		if retErr != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	if q.Size > 0 {
+		// Set container disk quota limit before anything is copied in, so
+		// that the copied files inherit the project id of the directory.
+		if err := d.quotaCtl.SetQuota(dir, q); err != nil {
+			return err
+		}
+	}
 	labelOpts := []string{"level:s0"}
 	if _, mountLabel, err := label.InitLabels(labelOpts); err == nil {
 		label.SetFileLabel(dir, mountLabel)
@@ -2,10 +2,17 @@ package daemon
 
 import (
 	"bytes"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"golang.org/x/net/context"
@@ -15,6 +22,7 @@ import (
 	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/container"
 	"github.com/docker/docker/daemon/exec"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -45,7 +53,8 @@ const (
 const (
 	// Exit status codes that can be returned by the probe command.
 
-	exitStatusHealthy = 0 // Container is healthy
+	exitStatusHealthy   = 0 // Container is healthy
+	exitStatusUnhealthy = 1 // Container is unhealthy
 )
 
 // probe implementations know how to run a particular type of probe.
@@ -111,6 +120,114 @@ func (p *cmdProbe) run(ctx context.Context, d *Daemon, cntr *container.Container
 	}, nil
 }
 
+// httpProbe implements the "HTTP" probe type. The container is healthy if
+// a GET request to the URL returns a 2xx or 3xx status. Redirects are not
+// followed, and certificates are not verified.
+type httpProbe struct{}
+
+// Send the healthcheck request from inside the container's network namespace.
+func (p *httpProbe) run(ctx context.Context, d *Daemon, cntr *container.Container) (*types.HealthcheckResult, error) {
+	return probeInContainer(ctx, cntr)
+}
+
+// tcpProbe implements the "TCP" probe type. The container is healthy if a
+// TCP connection to the address can be established.
+type tcpProbe struct{}
+
+// Connect to the address from inside the container's network namespace.
+func (p *tcpProbe) run(ctx context.Context, d *Daemon, cntr *container.Container) (*types.HealthcheckResult, error) {
+	return probeInContainer(ctx, cntr)
+}
+
+// checkHTTP sends the request of an HTTP probe. It is run by the process
+// started by probeInContainer.
+func checkHTTP(ctx context.Context, url string) *types.HealthcheckResult {
+	client := &http.Client{
+		Transport: &http.Transport{
+			// Disable dual stack fast fallback, the probe only needs to
+			// reach the service once.
+			DialContext:       (&net.Dialer{FallbackDelay: -1}).DialContext,
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = client.Do(req.WithContext(ctx))
+		if err == nil {
+			defer resp.Body.Close()
+
+			exitCode := exitStatusUnhealthy
+			if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+				exitCode = exitStatusHealthy
+			}
+			output := &limitedBuffer{}
+			fmt.Fprintf(output, "HTTP %s\n", resp.Status)
+			io.Copy(output, io.LimitReader(resp.Body, maxOutputLen))
+			return &types.HealthcheckResult{
+				End:      time.Now(),
+				ExitCode: exitCode,
+				Output:   output.String(),
+			}
+		}
+	}
+	return &types.HealthcheckResult{
+		End:      time.Now(),
+		ExitCode: exitStatusUnhealthy,
+		Output:   err.Error(),
+	}
+}
+
+// checkTCP connects to the address of a TCP probe. It is run by the process
+// started by probeInContainer.
+func checkTCP(ctx context.Context, addr string) *types.HealthcheckResult {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return &types.HealthcheckResult{
+			End:      time.Now(),
+			ExitCode: exitStatusUnhealthy,
+			Output:   err.Error(),
+		}
+	}
+	conn.Close()
+	return &types.HealthcheckResult{
+		End:      time.Now(),
+		ExitCode: exitStatusHealthy,
+		Output:   "connected to " + addr,
+	}
+}
+
+// validateHealthcheckTest validates the arguments of the probe types which
+// are run by the daemon itself.
+func validateHealthcheckTest(test []string) error {
+	if len(test) == 0 {
+		return nil
+	}
+	switch test[0] {
+	case "HTTP":
+		if len(test) != 2 {
+			return errors.New("HTTP healthcheck requires exactly one URL")
+		}
+		u, err := url.Parse(test[1])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Errorf("invalid URL %q in HTTP healthcheck, expected an http or https URL", test[1])
+		}
+	case "TCP":
+		if len(test) != 2 {
+			return errors.New("TCP healthcheck requires exactly one address")
+		}
+		if _, _, err := net.SplitHostPort(test[1]); err != nil {
+			return errors.Errorf("invalid address %q in TCP healthcheck: %v", test[1], err)
+		}
+	}
+	return nil
+}
+
 // Update the container's Status.Health struct based on the latest probe's result.
 func handleProbeResult(d *Daemon, c *container.Container, result *types.HealthcheckResult, done chan struct{}) {
 	c.Lock()
@@ -176,6 +293,67 @@ func handleProbeResult(d *Daemon, c *container.Container, result *types.Healthch
 	if oldStatus != h.Status {
 		d.LogContainerEvent(c, "health_status: "+h.Status)
 	}
+	// checked after every failing probe, as the container may not be
+	// restartable when it becomes unhealthy, e.g. because it is paused
+	if h.Status == types.Unhealthy {
+		d.restartUnhealthy(c)
+	}
+}
+
+// restartUnhealthy stops an unhealthy container if its restart policy asks
+// for it. The restart manager then restarts it like any other container
+// exiting with a non-zero status, including its backoff. The health checks
+// are stopped in the meantime, and restarted with the container.
+// Must be called with the container locked.
+func (d *Daemon) restartUnhealthy(c *container.Container) {
+	if c.Paused || c.Restarting || !c.RestartManager().ShouldRestartUnhealthy() {
+		return
+	}
+	d.stopHealthchecks(c)
+	attributes := map[string]string{
+		"failingStreak": strconv.Itoa(c.State.Health.FailingStreak),
+	}
+	d.LogContainerEventWithAttributes(c, "health_restart", attributes)
+	go d.stopUnhealthy(c)
+}
+
+// stopUnhealthy stops a container with its stop signal and timeout, like
+// containerStop does. Unlike containerStop, it doesn't mark the container as
+// stopped by the user, which would prevent its restart.
+func (d *Daemon) stopUnhealthy(c *container.Container) {
+	stopSignal := c.StopSignal()
+	logrus.Infof("Stopping unhealthy container %s to restart it", c.ID)
+	if err := d.signalUnhealthy(c, stopSignal); err != nil {
+		logrus.WithError(err).Errorf("Error stopping unhealthy container %s", c.ID)
+		// check the container again on the next probe
+		c.Lock()
+		d.updateHealthMonitor(c)
+		c.Unlock()
+		return
+	}
+
+	seconds := c.StopTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second)
+	defer cancel()
+	if status := <-c.Wait(ctx, container.WaitConditionNotRunning); status.Err() == nil {
+		return
+	}
+
+	logrus.Infof("Unhealthy container %s failed to exit within %d seconds of signal %d - using the force", c.ID, seconds, stopSignal)
+	if err := d.signalUnhealthy(c, int(syscall.SIGKILL)); err != nil {
+		logrus.WithError(err).Errorf("Error killing unhealthy container %s", c.ID)
+	}
+}
+
+// signalUnhealthy sends the given signal to the container, unless it was
+// stopped, paused or is restarting in the meantime.
+func (d *Daemon) signalUnhealthy(c *container.Container, sig int) error {
+	c.Lock()
+	defer c.Unlock()
+	if !c.Running || c.Paused || c.Restarting {
+		return nil
+	}
+	return d.kill(c, sig)
 }
 
 // Run the container's monitoring thread until notified via "stop".
@@ -253,8 +431,12 @@ func getProbe(c *container.Container) probe {
 		return &cmdProbe{shell: false}
 	case "CMD-SHELL":
 		return &cmdProbe{shell: true}
+	case "HTTP":
+		return &httpProbe{}
+	case "TCP":
+		return &tcpProbe{}
 	default:
-		logrus.Warnf("Unknown healthcheck type '%s' (expected 'CMD') in container %s", config.Test[0], c.ID)
+		logrus.Warnf("Unknown healthcheck type '%s' (expected 'CMD', 'CMD-SHELL', 'HTTP' or 'TCP') in container %s", config.Test[0], c.ID)
 		return nil
 	}
 }
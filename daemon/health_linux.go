@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/container"
+	"github.com/docker/docker/pkg/nsenter"
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	reexec.Register("docker-healthcheck", healthcheckMain)
+}
+
+// probeInContainer runs the HTTP or TCP probe of the container in a process
+// which is in the network and mount namespaces of the container, so that
+// the probe reaches the services listening on the container's loopback
+// interface and resolves names with the container's configuration.
+func probeInContainer(ctx context.Context, c *container.Container) (*types.HealthcheckResult, error) {
+	pid := c.State.GetPID()
+	if pid == 0 {
+		return nil, errors.Errorf("container %s is not running", c.ID)
+	}
+
+	cmd := reexec.Command(append([]string{"docker-healthcheck"}, c.Config.Healthcheck.Test...)...)
+	// The pure Go resolver only reads the configuration files of the
+	// container, unlike the libc one which may load modules.
+	cmd.Env = []string{nsenter.PidEnv + "=" + strconv.Itoa(pid), "GODEBUG=netdns=go"}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "error starting health check")
+	}
+
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- cmd.Wait()
+	}()
+	select {
+	case err := <-waitErr:
+		if err != nil {
+			return nil, errors.Errorf("error running health check: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+		}
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		<-waitErr
+		return nil, ctx.Err()
+	}
+
+	var result types.HealthcheckResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, errors.Wrap(err, "error decoding health check result")
+	}
+	return &result, nil
+}
+
+// healthcheckMain is the entry-point for docker-healthcheck on re-exec. The
+// process is started in the namespaces of the container by pkg/nsenter.
+func healthcheckMain() {
+	flag.Parse()
+
+	if !nsenter.Entered() {
+		fmt.Fprint(os.Stderr, "not in the namespaces of the container")
+		os.Exit(1)
+	}
+
+	var result *types.HealthcheckResult
+	switch flag.Arg(0) {
+	case "HTTP":
+		result = checkHTTP(context.Background(), flag.Arg(1))
+	case "TCP":
+		result = checkTCP(context.Background(), flag.Arg(1))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown health check type %q", flag.Arg(0))
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/container"
+	"github.com/docker/docker/pkg/reexec"
+	"golang.org/x/net/context"
+)
+
+func TestMain(m *testing.M) {
+	// the probes are run by re-executing the test binary
+	if reexec.Init() {
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func newProbedContainer(test ...string) *container.Container {
+	c := &container.Container{
+		ID: "container_id",
+		Config: &containertypes.Config{
+			Healthcheck: &containertypes.HealthConfig{Test: test},
+		},
+		State: container.NewState(),
+	}
+	// probe the test's own network namespace
+	c.State.SetRunning(os.Getpid(), true)
+	return c
+}
+
+func TestHTTPProbe(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("entering a network namespace requires root")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	result, err := (&httpProbe{}).run(context.Background(), nil, newProbedContainer("HTTP", server.URL+"/health"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusHealthy {
+		t.Fatalf("Expecting healthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+
+	result, err = (&httpProbe{}).run(context.Background(), nil, newProbedContainer("HTTP", server.URL+"/other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusUnhealthy {
+		t.Fatalf("Expecting unhealthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+}
+
+func TestTCPProbe(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("entering a network namespace requires root")
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+
+	result, err := (&tcpProbe{}).run(context.Background(), nil, newProbedContainer("TCP", addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusHealthy {
+		t.Fatalf("Expecting healthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+
+	// names are resolved with the configuration of the container
+	_, port, _ := net.SplitHostPort(addr)
+	result, err = (&tcpProbe{}).run(context.Background(), nil, newProbedContainer("TCP", "localhost:"+port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusHealthy {
+		t.Fatalf("Expecting healthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+
+	l.Close()
+	result, err = (&tcpProbe{}).run(context.Background(), nil, newProbedContainer("TCP", addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusUnhealthy {
+		t.Fatalf("Expecting unhealthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+}
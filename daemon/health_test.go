@@ -67,6 +67,7 @@ func TestHealthStates(t *testing.T) {
 		Config: &containertypes.Config{
 			Image: "image_name",
 		},
+		HostConfig: &containertypes.HostConfig{},
 	}
 
 	store, err := container.NewViewDB()
@@ -152,3 +153,94 @@ func TestHealthStates(t *testing.T) {
 		t.Errorf("Expecting FailingStreak=0, but got %d\n", c.State.Health.FailingStreak)
 	}
 }
+
+func TestRestartUnhealthyAfterPause(t *testing.T) {
+	e := events.New()
+	_, l, _ := e.Subscribe()
+	defer e.Evict(l)
+
+	c := &container.Container{
+		ID:   "container_id",
+		Name: "container_name",
+		Config: &containertypes.Config{
+			Image:       "image_name",
+			Healthcheck: &containertypes.HealthConfig{Retries: 1},
+		},
+		HostConfig: &containertypes.HostConfig{
+			RestartPolicy: containertypes.RestartPolicy{Name: "on-unhealthy"},
+		},
+	}
+	reset(c)
+
+	store, err := container.NewViewDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemon := &Daemon{
+		EventsService:     e,
+		containersReplica: store,
+	}
+
+	failProbe := func() {
+		handleProbeResult(daemon, c, &types.HealthcheckResult{
+			Start:    c.State.StartedAt.Add(time.Second),
+			End:      c.State.StartedAt.Add(time.Second),
+			ExitCode: 1,
+		}, nil)
+	}
+	nextEvent := func() string {
+		select {
+		case event := <-l:
+			return event.(eventtypes.Message).Status
+		case <-time.After(1 * time.Second):
+			return ""
+		}
+	}
+
+	// the container becomes unhealthy while it is paused
+	c.Paused = true
+	failProbe()
+	if ev := nextEvent(); ev != "health_status: unhealthy" {
+		t.Fatalf("Expecting event health_status: unhealthy, but got %#v", ev)
+	}
+	if ev := nextEvent(); ev != "" {
+		t.Fatalf("Expecting no restart of the paused container, but got %#v", ev)
+	}
+
+	// it is restarted on the next failing probe once unpaused
+	c.Paused = false
+	failProbe()
+	if ev := nextEvent(); ev != "health_restart" {
+		t.Fatalf("Expecting event health_restart, but got %#v", ev)
+	}
+}
+
+func TestValidateHealthcheckTest(t *testing.T) {
+	valid := [][]string{
+		nil,
+		{"NONE"},
+		{"CMD", "true"},
+		{"HTTP", "http://localhost:8080/health"},
+		{"HTTP", "https://127.0.0.1/"},
+		{"TCP", "localhost:6379"},
+	}
+	for _, test := range valid {
+		if err := validateHealthcheckTest(test); err != nil {
+			t.Errorf("Expecting %v to be valid, got %v", test, err)
+		}
+	}
+
+	invalid := [][]string{
+		{"HTTP"},
+		{"HTTP", "localhost:8080/health"},
+		{"HTTP", "ftp://localhost/"},
+		{"HTTP", "http://localhost/a", "http://localhost/b"},
+		{"TCP"},
+		{"TCP", "localhost"},
+	}
+	for _, test := range invalid {
+		if err := validateHealthcheckTest(test); err == nil {
+			t.Errorf("Expecting %v to be invalid", test)
+		}
+	}
+}
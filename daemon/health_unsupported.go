@@ -0,0 +1,15 @@
+// +build !linux
+
+package daemon
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/container"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// probeInContainer is not supported on this platform.
+func probeInContainer(ctx context.Context, c *container.Container) (*types.HealthcheckResult, error) {
+	return nil, errors.New("HTTP and TCP health checks are not supported on this platform")
+}
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"io"
+	"path/filepath"
 	"runtime"
 	"strings"
 
@@ -76,9 +77,11 @@ func (daemon *Daemon) pullImageWithReference(ctx context.Context, ref reference.
 			ImageStore:       distribution.NewImageConfigStoreFromStore(daemon.stores[platform].imageStore),
 			ReferenceStore:   daemon.referenceStore,
 		},
-		DownloadManager: daemon.downloadManager,
-		Schema2Types:    distribution.ImageTypes,
-		Platform:        platform,
+		DownloadManager:  daemon.downloadManager,
+		Schema2Types:     distribution.ImageTypes,
+		Platform:         platform,
+		DownloadDir:      daemon.layerDownloadDir(platform),
+		MaxDownloadParts: daemon.configStore.MaxDownloadParts,
 	}
 
 	err := distribution.Pull(ctx, ref, imagePullConfig)
@@ -87,6 +90,13 @@ func (daemon *Daemon) pullImageWithReference(ctx context.Context, ref reference.
 	return err
 }
 
+// layerDownloadDir returns the directory where the layers of the images of
+// platform are downloaded, keeping the partial downloads of the pulls that
+// were interrupted.
+func (daemon *Daemon) layerDownloadDir(platform string) string {
+	return filepath.Join(daemon.stores[platform].imageRoot, "downloads")
+}
+
 // GetRepository returns a repository from the registry.
 func (daemon *Daemon) GetRepository(ctx context.Context, ref reference.Named, authConfig *types.AuthConfig) (dist.Repository, bool, error) {
 	// get repository info
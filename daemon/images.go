@@ -15,6 +15,7 @@ import (
 	"github.com/docker/docker/container"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/system"
 )
 
@@ -255,7 +256,8 @@ func (daemon *Daemon) Images(imageFilters filters.Args, all bool, withExtraAttrs
 // This new image contains only the layers from it's parent + 1 extra layer which contains the diff of all the layers in between.
 // The existing image(s) is not destroyed.
 // If no parent is specified, a new image with the diff of all the specified image's layers merged into a new layer that has no parents.
-func (daemon *Daemon) SquashImage(id, parent string) (string, error) {
+// If sourceDateEpoch is set, it is used as the creation time of the new image and the file times later than it are set to it.
+func (daemon *Daemon) SquashImage(id, parent string, sourceDateEpoch *time.Time) (string, error) {
 
 	var (
 		img *image.Image
@@ -293,6 +295,9 @@ func (daemon *Daemon) SquashImage(id, parent string) (string, error) {
 	if err != nil {
 		return "", errors.Wrapf(err, "error getting tar stream to parent")
 	}
+	if sourceDateEpoch != nil {
+		ts = archive.ClampTimesTarWrapper(ts, *sourceDateEpoch)
+	}
 	defer ts.Close()
 
 	newL, err := daemon.stores[img.OperatingSystem()].layerStore.Register(ts, parentChainID, layer.OS(img.OperatingSystem()))
@@ -316,6 +321,9 @@ func (daemon *Daemon) SquashImage(id, parent string) (string, error) {
 	}
 
 	now := time.Now()
+	if sourceDateEpoch != nil {
+		now = *sourceDateEpoch
+	}
 	var historyComment string
 	if len(parent) > 0 {
 		historyComment = fmt.Sprintf("merge %s to %s", id, parent)
@@ -0,0 +1,68 @@
+// +build linux,cgo,!static_build,journald
+
+package journald
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/journal"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/loggertest"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLogsUntil(t *testing.T) {
+	if !journal.Enabled() {
+		t.Skip("journald is not enabled on this host")
+	}
+
+	l, err := New(logger.Info{
+		ContainerID:   stringid.GenerateRandomID(),
+		ContainerName: "/journald-until",
+	})
+	require.NoError(t, err)
+	defer l.Close()
+	lr := l.(logger.LogReader)
+
+	logLines := func(lines ...string) {
+		for _, line := range lines {
+			msg := logger.NewMessage()
+			msg.Source = "stdout"
+			msg.Line = append(msg.Line, line...)
+			require.NoError(t, l.Log(msg))
+		}
+	}
+
+	// journald timestamps the entries when it receives them
+	logLines("line0", "line1", "line2")
+	time.Sleep(time.Second)
+	until := time.Now()
+	time.Sleep(time.Second)
+	logLines("line3")
+
+	all := []string{"line0", "line1", "line2", "line3"}
+	for i := 0; ; i++ {
+		lines := loggertest.ReadAll(t, lr.ReadLogs(logger.ReadConfig{Tail: -1}))
+		if len(lines) == len(all) {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("expected %v to be logged, got %v", all, lines)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	lines := loggertest.ReadAll(t, lr.ReadLogs(logger.ReadConfig{Tail: -1, Until: until}))
+	assert.Equal(t, []string{"line0", "line1", "line2"}, lines)
+
+	// the last lines before until are returned
+	lines = loggertest.ReadAll(t, lr.ReadLogs(logger.ReadConfig{Tail: 2, Until: until}))
+	assert.Equal(t, []string{"line1", "line2"}, lines)
+
+	// following stops once until is reached, even if nothing is logged
+	lines = loggertest.ReadAll(t, lr.ReadLogs(logger.ReadConfig{Tail: -1, Follow: true, Until: time.Now().Add(200 * time.Millisecond)}))
+	assert.Equal(t, all, lines)
+}
@@ -98,7 +98,7 @@ func (l *JSONFileLogger) readLogs(logWatcher *logger.LogWatcher, config logger.R
 
 	if config.Tail != 0 {
 		tailer := multireader.MultiReadSeeker(append(files, latestChunk)...)
-		tailFile(tailer, logWatcher, config.Tail, config.Since)
+		tailFile(tailer, logWatcher, config.Tail, config.Since, config.Until)
 	}
 
 	// close all the rotated files
@@ -112,6 +112,11 @@ func (l *JSONFileLogger) readLogs(logWatcher *logger.LogWatcher, config logger.R
 		return
 	}
 
+	// no need to follow when the requested time window is already over
+	if !config.Until.IsZero() && config.Until.Before(time.Now()) {
+		return
+	}
+
 	notifyRotate := l.writer.NotifyRotate()
 	defer l.writer.NotifyRotateEvict(notifyRotate)
 
@@ -119,7 +124,7 @@ func (l *JSONFileLogger) readLogs(logWatcher *logger.LogWatcher, config logger.R
 	l.readers[logWatcher] = struct{}{}
 	l.mu.Unlock()
 
-	followLogs(latestFile, logWatcher, notifyRotate, config.Since)
+	followLogs(latestFile, logWatcher, notifyRotate, config.Since, config.Until)
 
 	l.mu.Lock()
 	delete(l.readers, logWatcher)
@@ -136,9 +141,9 @@ func newSectionReader(f *os.File) (*io.SectionReader, error) {
 	return io.NewSectionReader(f, 0, size), nil
 }
 
-func tailFile(f io.ReadSeeker, logWatcher *logger.LogWatcher, tail int, since time.Time) {
+func tailFile(f io.ReadSeeker, logWatcher *logger.LogWatcher, tail int, since, until time.Time) {
 	rdr := io.Reader(f)
-	if tail > 0 {
+	if tail > 0 && until.IsZero() {
 		ls, err := tailfile.TailFile(f, tail)
 		if err != nil {
 			logWatcher.Err <- err
@@ -146,18 +151,48 @@ func tailFile(f io.ReadSeeker, logWatcher *logger.LogWatcher, tail int, since ti
 		}
 		rdr = bytes.NewBuffer(bytes.Join(ls, []byte("\n")))
 	}
+
+	// The last lines before until are only known once until is reached,
+	// so keep a ring of the last tail lines until then.
+	var (
+		ring []*logger.Message
+		next int
+	)
+	keepLast := tail > 0 && !until.IsZero()
+
 	dec := json.NewDecoder(rdr)
 	for {
 		msg, err := decodeLogLine(dec, &jsonlog.JSONLog{})
 		if err != nil {
 			if err != io.EOF {
 				logWatcher.Err <- err
+				return
 			}
-			return
+			break
 		}
 		if !since.IsZero() && msg.Timestamp.Before(since) {
 			continue
 		}
+		if !until.IsZero() && msg.Timestamp.After(until) {
+			break
+		}
+		if keepLast {
+			if len(ring) < tail {
+				ring = append(ring, msg)
+				continue
+			}
+			ring[next] = msg
+			next = (next + 1) % tail
+			continue
+		}
+		select {
+		case <-logWatcher.WatchClose():
+			return
+		case logWatcher.Msg <- msg:
+		}
+	}
+
+	for _, msg := range append(ring[next:], ring[:next]...) {
 		select {
 		case <-logWatcher.WatchClose():
 			return
@@ -186,7 +221,7 @@ func watchFile(name string) (filenotify.FileWatcher, error) {
 	return fileWatcher, nil
 }
 
-func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan interface{}, since time.Time) {
+func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan interface{}, since, until time.Time) {
 	dec := json.NewDecoder(f)
 	l := &jsonlog.JSONLog{}
 
@@ -214,6 +249,16 @@ func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan int
 		}
 	}()
 
+	// stop following once until is reached, even if nothing is logged
+	// after it
+	var untilReached bool
+	var untilTimer <-chan time.Time
+	if !until.IsZero() {
+		timer := time.NewTimer(time.Until(until))
+		defer timer.Stop()
+		untilTimer = timer.C
+	}
+
 	var retries int
 	handleRotate := func() error {
 		f.Close()
@@ -240,6 +285,11 @@ func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan int
 	errDone := errors.New("done")
 	waitRead := func() error {
 		select {
+		case <-untilTimer:
+			// read what was logged until now one last time
+			untilReached = true
+			dec = json.NewDecoder(f)
+			return nil
 		case e := <-fileWatcher.Events():
 			switch e.Op {
 			case fsnotify.Write:
@@ -277,6 +327,9 @@ func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan int
 
 	handleDecodeErr := func(err error) error {
 		if err == io.EOF {
+			if untilReached {
+				return errDone
+			}
 			for {
 				err := waitRead()
 				if err == nil {
@@ -328,6 +381,9 @@ func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan int
 		if !since.IsZero() && msg.Timestamp.Before(since) {
 			continue
 		}
+		if !until.IsZero() && msg.Timestamp.After(until) {
+			return
+		}
 		select {
 		case logWatcher.Msg <- msg:
 		case <-ctx.Done():
@@ -340,6 +396,9 @@ func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan int
 				if !since.IsZero() && msg.Timestamp.Before(since) {
 					continue
 				}
+				if !until.IsZero() && msg.Timestamp.After(until) {
+					return
+				}
 				logWatcher.Msg <- msg
 			}
 		}
@@ -2,11 +2,14 @@ package jsonfilelog
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/loggertest"
 	"github.com/gotestyourself/gotestyourself/fs"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -62,3 +65,41 @@ func BenchmarkJSONFileLoggerReadLogs(b *testing.B) {
 		}
 	}
 }
+
+func TestReadLogsUntil(t *testing.T) {
+	tmp := fs.NewDir(t, "jsonfilelog-until")
+	defer tmp.Remove()
+
+	l, err := New(logger.Info{
+		ContainerID: "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657",
+		LogPath:     tmp.Join("container.log"),
+	})
+	require.NoError(t, err)
+	defer l.Close()
+
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 6; i++ {
+		msg := &logger.Message{
+			Line:      []byte(fmt.Sprintf("line%d", i)),
+			Source:    "stdout",
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+		}
+		require.NoError(t, l.Log(msg))
+	}
+	lr := l.(logger.LogReader)
+
+	until := start.Add(3 * time.Second)
+	lines := loggertest.ReadAll(t, lr.ReadLogs(logger.ReadConfig{Tail: -1, Until: until}))
+	assert.Equal(t, []string{"line0", "line1", "line2", "line3"}, lines)
+
+	// the last lines before until are returned
+	lines = loggertest.ReadAll(t, lr.ReadLogs(logger.ReadConfig{Tail: 2, Until: until}))
+	assert.Equal(t, []string{"line2", "line3"}, lines)
+
+	lines = loggertest.ReadAll(t, lr.ReadLogs(logger.ReadConfig{Tail: 2, Since: start.Add(3 * time.Second), Until: until}))
+	assert.Equal(t, []string{"line3"}, lines)
+
+	// following stops once until is reached, even if nothing is logged
+	lines = loggertest.ReadAll(t, lr.ReadLogs(logger.ReadConfig{Tail: -1, Follow: true, Until: time.Now().Add(200 * time.Millisecond)}))
+	assert.Equal(t, []string{"line0", "line1", "line2", "line3", "line4", "line5"}, lines)
+}
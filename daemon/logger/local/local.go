@@ -0,0 +1,205 @@
+// Package local provides a logger implementation that stores logs on disk
+// in a compact, length-prefixed protobuf format. Rotated log files are
+// compressed to save space.
+package local
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/docker/docker/api/types/plugins/logdriver"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/loggerutils"
+	units "github.com/docker/go-units"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Name is the name of the driver
+	Name = "local"
+
+	encodeBinaryLen = 4
+	initialBufSize  = 2048
+
+	defaultMaxFileSize  int64 = 20 * 1024 * 1024
+	defaultMaxFileCount       = 5
+	defaultCompressLogs       = true
+)
+
+// LogOptKeys are the keys names used for log opts passed in to initialize the driver.
+var LogOptKeys = map[string]bool{
+	"max-file": true,
+	"max-size": true,
+	"compress": true,
+}
+
+// ValidateLogOpt looks for log driver specific options.
+func ValidateLogOpt(cfg map[string]string) error {
+	for key, val := range cfg {
+		if !LogOptKeys[key] {
+			return errors.Errorf("unknown log opt '%s' for log driver %s", key, Name)
+		}
+		switch key {
+		case "max-size":
+			if _, err := units.FromHumanSize(val); err != nil {
+				return errors.Wrapf(err, "invalid value for %s", key)
+			}
+		case "max-file":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return errors.Wrapf(err, "invalid value for %s", key)
+			}
+			if n < 1 {
+				return errors.Errorf("%s cannot be less than 1", key)
+			}
+		case "compress":
+			if _, err := strconv.ParseBool(val); err != nil {
+				return errors.Wrapf(err, "invalid value for %s", key)
+			}
+		}
+	}
+	return nil
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(Name, New); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(Name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+type driver struct {
+	mu      sync.Mutex
+	closed  bool
+	writer  *loggerutils.RotateFileWriter
+	readers map[*logger.LogWatcher]struct{} // stores the active log followers
+
+	// buffers are shared across calls to `Log()` to reduce allocations
+	// and must be protected by mu
+	buffer []byte
+	entry  logdriver.LogEntry
+}
+
+// New creates a new local logger
+// You must provide the `LogPath` in the passed in info argument, this is the
+// file path that logs are written to.
+func New(info logger.Info) (logger.Logger, error) {
+	if info.LogPath == "" {
+		return nil, errors.New("log path is missing -- this is a bug and should not happen")
+	}
+
+	capacity := defaultMaxFileSize
+	if s, ok := info.Config["max-size"]; ok {
+		var err error
+		capacity, err = units.FromHumanSize(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	maxFiles := defaultMaxFileCount
+	if s, ok := info.Config["max-file"]; ok {
+		var err error
+		maxFiles, err = strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	compress := defaultCompressLogs
+	if s, ok := info.Config["compress"]; ok {
+		var err error
+		compress, err = strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(info.LogPath), 0700); err != nil {
+		return nil, errors.Wrap(err, "error creating local logs dir")
+	}
+
+	writer, err := loggerutils.NewRotateFileWriter(info.LogPath, capacity, maxFiles, compress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &driver{
+		writer:  writer,
+		readers: make(map[*logger.LogWatcher]struct{}),
+		buffer:  make([]byte, initialBufSize),
+	}, nil
+}
+
+// Log encodes the message to the on-disk format and writes it to the
+// current log file.
+func (d *driver) Log(msg *logger.Message) error {
+	d.mu.Lock()
+	err := d.writeMessage(msg)
+	d.mu.Unlock()
+	logger.PutMessage(msg)
+	return err
+}
+
+// writeMessage writes a single frame to the log file.
+// A frame looks like:
+//
+// [uint32 size][protobuf message][uint32 size]
+//
+// The trailing size allows the file to be read backwards.
+// Callers must hold d.mu.
+func (d *driver) writeMessage(msg *logger.Message) error {
+	if d.closed {
+		return errors.New("cannot write because the output file was closed")
+	}
+
+	d.entry.Source = msg.Source
+	d.entry.TimeNano = msg.Timestamp.UnixNano()
+	d.entry.Line = msg.Line
+	d.entry.Partial = msg.Partial
+
+	size := d.entry.Size()
+	total := size + 2*encodeBinaryLen
+	if total > len(d.buffer) {
+		d.buffer = make([]byte, total)
+	}
+
+	binary.BigEndian.PutUint32(d.buffer[:encodeBinaryLen], uint32(size))
+	n, err := d.entry.MarshalTo(d.buffer[encodeBinaryLen:])
+	d.entry.Reset()
+	if err != nil {
+		return errors.Wrap(err, "error marshalling log entry")
+	}
+	if n != size {
+		return fmt.Errorf("unexpected log entry size: expected %d, got %d", size, n)
+	}
+	binary.BigEndian.PutUint32(d.buffer[encodeBinaryLen+size:total], uint32(size))
+
+	_, err = d.writer.Write(d.buffer[:total])
+	return errors.Wrap(err, "error writing log entry")
+}
+
+// Name returns the name of this driver
+func (d *driver) Name() string {
+	return Name
+}
+
+// Close closes the underlying file and signals all readers to stop.
+func (d *driver) Close() error {
+	d.mu.Lock()
+	d.closed = true
+	err := d.writer.Close()
+	for r := range d.readers {
+		r.Close()
+		delete(d.readers, r)
+	}
+	d.mu.Unlock()
+	return err
+}
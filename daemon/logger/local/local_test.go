@@ -0,0 +1,146 @@
+package local
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/loggertest"
+	"github.com/docker/docker/daemon/logger/loggerutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(t *testing.T, config map[string]string) (*driver, string) {
+	dir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+
+	l, err := New(logger.Info{
+		LogPath: filepath.Join(dir, "container.log"),
+		Config:  config,
+	})
+	require.NoError(t, err)
+	return l.(*driver), dir
+}
+
+func logN(t *testing.T, l logger.Logger, start time.Time, n int) {
+	for i := 0; i < n; i++ {
+		msg := logger.NewMessage()
+		msg.Source = "stdout"
+		msg.Timestamp = start.Add(time.Duration(i) * time.Second)
+		msg.Line = append(msg.Line, fmt.Sprintf("line%d", i)...)
+		require.NoError(t, l.Log(msg))
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	l, dir := newTestLogger(t, nil)
+	defer os.RemoveAll(dir)
+	defer l.Close()
+
+	start := time.Now()
+	logN(t, l, start, 5)
+
+	lines := loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: -1}))
+	assert.Equal(t, []string{"line0", "line1", "line2", "line3", "line4"}, lines)
+
+	lines = loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: 2}))
+	assert.Equal(t, []string{"line3", "line4"}, lines)
+
+	lines = loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: -1, Since: start.Add(3 * time.Second)}))
+	assert.Equal(t, []string{"line3", "line4"}, lines)
+
+	lines = loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: 10, Since: start.Add(4 * time.Second)}))
+	assert.Equal(t, []string{"line4"}, lines)
+}
+
+func TestReadUntil(t *testing.T) {
+	l, dir := newTestLogger(t, map[string]string{"max-size": "100", "max-file": "3"})
+	defer os.RemoveAll(dir)
+	defer l.Close()
+
+	start := time.Now().Add(-time.Hour)
+	logN(t, l, start, 6)
+
+	until := start.Add(3 * time.Second)
+	lines := loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: -1, Until: until}))
+	assert.Equal(t, []string{"line0", "line1", "line2", "line3"}, lines)
+
+	lines = loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: 2, Until: until}))
+	assert.Equal(t, []string{"line2", "line3"}, lines)
+
+	lines = loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: -1, Since: start.Add(time.Second), Until: until}))
+	assert.Equal(t, []string{"line1", "line2", "line3"}, lines)
+
+	// nothing is followed once until has passed
+	lines = loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: -1, Follow: true, Until: until}))
+	assert.Equal(t, []string{"line0", "line1", "line2", "line3"}, lines)
+
+	// following stops once until is reached, even if nothing is logged
+	lines = loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: -1, Follow: true, Until: time.Now().Add(200 * time.Millisecond)}))
+	assert.Equal(t, []string{"line0", "line1", "line2", "line3", "line4", "line5"}, lines)
+}
+
+func TestReadCompressedRotatedFiles(t *testing.T) {
+	l, dir := newTestLogger(t, map[string]string{"max-size": "1", "max-file": "3"})
+	defer os.RemoveAll(dir)
+	defer l.Close()
+
+	logN(t, l, time.Now(), 4)
+
+	// the last rotated file may still be being compressed
+	lines := loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: -1}))
+	assert.Equal(t, []string{"line1", "line2", "line3"}, lines)
+
+	lines = loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: 2}))
+	assert.Equal(t, []string{"line2", "line3"}, lines)
+
+	// closing waits for the compression to complete
+	require.NoError(t, l.Close())
+	_, err := os.Stat(filepath.Join(dir, "container.log.1"+loggerutils.CompressedFileSuffix))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "container.log.2"+loggerutils.CompressedFileSuffix))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "container.log.1"))
+	assert.True(t, os.IsNotExist(err))
+
+	lines = loggertest.ReadAll(t, l.ReadLogs(logger.ReadConfig{Tail: -1}))
+	assert.Equal(t, []string{"line1", "line2", "line3"}, lines)
+}
+
+func TestFollowLogs(t *testing.T) {
+	l, dir := newTestLogger(t, map[string]string{"max-size": "200", "max-file": "2"})
+	defer os.RemoveAll(dir)
+
+	start := time.Now()
+	logN(t, l, start, 2)
+
+	lw := l.ReadLogs(logger.ReadConfig{Tail: -1, Follow: true})
+	done := make(chan []string)
+	go func() {
+		done <- loggertest.ReadAll(t, lw)
+	}()
+
+	// give the reader a chance to start following so that rotation is
+	// exercised while following
+	time.Sleep(100 * time.Millisecond)
+	logN(t, l, start.Add(10*time.Second), 10)
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, l.Close())
+
+	lines := <-done
+	require.Len(t, lines, 12)
+	assert.Equal(t, "line0", lines[0])
+	assert.Equal(t, "line9", lines[11])
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	assert.NoError(t, ValidateLogOpt(map[string]string{"max-size": "10m", "max-file": "3", "compress": "false"}))
+	assert.Error(t, ValidateLogOpt(map[string]string{"max-file": "0"}))
+	assert.Error(t, ValidateLogOpt(map[string]string{"compress": "maybe"}))
+	assert.Error(t, ValidateLogOpt(map[string]string{"labels": "foo"}))
+}
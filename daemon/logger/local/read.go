@@ -0,0 +1,557 @@
+package local
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/docker/docker/api/types/plugins/logdriver"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/loggerutils"
+	"github.com/docker/docker/pkg/filenotify"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxMsgLen is the maximum size of a single encoded log entry. Anything
+// larger is treated as corruption of the log file.
+const maxMsgLen int = 1e6 // 1MB.
+
+// errDone is returned when an entry past the end of the requested time
+// window was reached.
+var errDone = errors.New("done")
+
+// ReadLogs implements the logger's LogReader interface for the logs
+// created by this driver.
+func (d *driver) ReadLogs(config logger.ReadConfig) *logger.LogWatcher {
+	watcher := logger.NewLogWatcher()
+
+	go d.readLogs(watcher, config)
+	return watcher
+}
+
+func (d *driver) readLogs(watcher *logger.LogWatcher, config logger.ReadConfig) {
+	defer close(watcher.Msg)
+
+	// lock so the read stream doesn't get corrupted due to rotations or other
+	// log data written while we open these files.
+	// This will block writes!!!
+	d.mu.Lock()
+
+	pth := d.writer.LogPath()
+	var rotated []io.ReadCloser
+	defer func() {
+		for _, f := range rotated {
+			f.Close()
+		}
+	}()
+	for i := d.writer.MaxFiles(); i > 1; i-- {
+		f, err := openRotatedFile(fmt.Sprintf("%s.%d", pth, i-1))
+		if err != nil {
+			d.mu.Unlock()
+			watcher.Err <- err
+			return
+		}
+		if f != nil {
+			rotated = append(rotated, f)
+		}
+	}
+
+	latestFile, err := os.Open(pth)
+	if err != nil {
+		d.mu.Unlock()
+		watcher.Err <- errors.Wrap(err, "error opening latest log file")
+		return
+	}
+	defer latestFile.Close()
+
+	// seek to the end to get the size; the file offset is left at the end so
+	// that following the file picks up at the first unread entry.
+	size, err := latestFile.Seek(0, os.SEEK_END)
+	closed := d.closed
+
+	// Now we have all fd's opened, we can unlock.
+	// New writes/rotates will not affect reading these files.
+	d.mu.Unlock()
+
+	if err != nil {
+		watcher.Err <- errors.Wrap(err, "error getting current file size")
+		return
+	}
+
+	if config.Tail != 0 {
+		msgs, err := tailLogs(rotated, io.NewSectionReader(latestFile, 0, size), config)
+		if err != nil {
+			watcher.Err <- err
+			return
+		}
+		for _, msg := range msgs {
+			select {
+			case <-watcher.WatchClose():
+				return
+			case watcher.Msg <- msg:
+			}
+		}
+	}
+
+	for _, f := range rotated {
+		if err := f.Close(); err != nil {
+			logrus.WithField("logger", Name).Warnf("error closing tailed log file: %v", err)
+		}
+	}
+	rotated = nil
+
+	if !config.Follow || closed {
+		return
+	}
+
+	// no need to follow when the requested time window is already over
+	if !config.Until.IsZero() && config.Until.Before(time.Now()) {
+		return
+	}
+
+	notifyRotate := d.writer.NotifyRotate()
+	defer d.writer.NotifyRotateEvict(notifyRotate)
+
+	d.mu.Lock()
+	d.readers[watcher] = struct{}{}
+	d.mu.Unlock()
+
+	followLogs(latestFile, watcher, notifyRotate, config.Since, config.Until)
+
+	d.mu.Lock()
+	delete(d.readers, watcher)
+	d.mu.Unlock()
+}
+
+// openRotatedFile opens a rotated log file, transparently decompressing it
+// if a compressed version exists. It returns nil if neither exists. The
+// uncompressed file is read while it's being compressed in the background.
+func openRotatedFile(name string) (io.ReadCloser, error) {
+	f, err := openCompressedFile(name)
+	if f != nil || err != nil {
+		return f, err
+	}
+
+	plain, err := os.Open(name)
+	if err == nil {
+		return plain, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// the compression may have completed in the meantime
+	return openCompressedFile(name)
+}
+
+// openCompressedFile opens the compressed version of a rotated log file, or
+// returns nil if it doesn't exist.
+func openCompressedFile(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name + loggerutils.CompressedFileSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "error reading compressed log file %s", f.Name())
+	}
+	return &gzipFile{Reader: gz, f: f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// tailLogs collects the messages requested by config from the rotated files
+// (oldest first) and the current log file.
+//
+// When only the last N entries are requested, the current log file is read
+// backwards and rotated files are only decoded if it does not hold enough
+// entries.
+func tailLogs(rotated []io.ReadCloser, latest *io.SectionReader, config logger.ReadConfig) ([]*logger.Message, error) {
+	if config.Tail < 0 {
+		var msgs []*logger.Message
+		for _, r := range append(toReaders(rotated), latest) {
+			err := decodeAll(r, config.Since, config.Until, func(msg *logger.Message) {
+				msgs = append(msgs, msg)
+			})
+			if err == errDone {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return msgs, nil
+	}
+
+	msgs, done, err := tailBackwards(latest, config.Tail, config.Since, config.Until)
+	if err != nil || done || len(rotated) == 0 {
+		return msgs, err
+	}
+
+	// The current file does not hold enough entries, so keep a ring of the
+	// last `remaining` entries found in the rotated files.
+	remaining := config.Tail - len(msgs)
+	ring := make([]*logger.Message, 0, remaining)
+	var next int
+	for _, r := range rotated {
+		err := decodeAll(r, config.Since, config.Until, func(msg *logger.Message) {
+			if len(ring) < remaining {
+				ring = append(ring, msg)
+				return
+			}
+			ring[next] = msg
+			next = (next + 1) % remaining
+		})
+		if err == errDone {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(append(ring[next:], ring[:next]...), msgs...), nil
+}
+
+func toReaders(rcs []io.ReadCloser) []io.Reader {
+	readers := make([]io.Reader, 0, len(rcs))
+	for _, rc := range rcs {
+		readers = append(readers, rc)
+	}
+	return readers
+}
+
+// decodeAll decodes every entry in r, passing those between since and until
+// to the handler. errDone is returned once an entry after until is found.
+func decodeAll(r io.Reader, since, until time.Time, handle func(*logger.Message)) error {
+	dec := newDecoder(r)
+	for {
+		msg, err := dec.Decode()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if !since.IsZero() && msg.Timestamp.Before(since) {
+			continue
+		}
+		if !until.IsZero() && msg.Timestamp.After(until) {
+			return errDone
+		}
+		handle(msg)
+	}
+}
+
+// tailBackwards reads up to n entries from the end of r, using the size that
+// trails each entry. Entries after until are skipped. The returned messages
+// are in chronological order. done reports whether no older entries need to
+// be looked at, either because n entries were found or because an entry older
+// than since was reached.
+func tailBackwards(r *io.SectionReader, n int, since, until time.Time) (msgs []*logger.Message, done bool, err error) {
+	var (
+		sizeBuf = make([]byte, encodeBinaryLen)
+		buf     []byte
+		entry   logdriver.LogEntry
+		offset  = r.Size()
+	)
+
+	for offset > 0 && len(msgs) < n {
+		if offset < 2*encodeBinaryLen {
+			return nil, false, errors.New("log file is corrupted: truncated entry")
+		}
+		if _, err := r.ReadAt(sizeBuf, offset-encodeBinaryLen); err != nil {
+			return nil, false, errors.Wrap(err, "error reading log entry size")
+		}
+		size := int(binary.BigEndian.Uint32(sizeBuf))
+		if size > maxMsgLen || int64(size+2*encodeBinaryLen) > offset {
+			return nil, false, errors.Errorf("log file is corrupted: invalid entry size %d", size)
+		}
+		if cap(buf) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		offset -= int64(size + 2*encodeBinaryLen)
+		if _, err := r.ReadAt(buf, offset+encodeBinaryLen); err != nil {
+			return nil, false, errors.Wrap(err, "error reading log entry")
+		}
+
+		entry.Reset()
+		if err := entry.Unmarshal(buf); err != nil {
+			return nil, false, errors.Wrap(err, "error decoding log entry")
+		}
+		msg := entryToMessage(&entry)
+		if !until.IsZero() && msg.Timestamp.After(until) {
+			continue
+		}
+		if !since.IsZero() && msg.Timestamp.Before(since) {
+			done = true
+			break
+		}
+		msgs = append(msgs, msg)
+	}
+
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, done || len(msgs) == n, nil
+}
+
+func entryToMessage(entry *logdriver.LogEntry) *logger.Message {
+	msg := &logger.Message{
+		Source:    entry.Source,
+		Timestamp: time.Unix(0, entry.TimeNano),
+		Partial:   entry.Partial,
+	}
+	// copy the line since the entry buffer is reused
+	msg.Line = append(msg.Line, entry.Line...)
+	return msg
+}
+
+// decoder reads entries from a stream written by the driver. Unlike
+// logdriver.LogEntryDecoder it keeps partially read entries around so that
+// decoding can be retried once more data has been written.
+type decoder struct {
+	rdr   io.Reader
+	buf   []byte
+	pos   int // start of unconsumed data in buf
+	entry logdriver.LogEntry
+}
+
+func newDecoder(rdr io.Reader) *decoder {
+	return &decoder{rdr: rdr, buf: make([]byte, 0, initialBufSize)}
+}
+
+// fill reads from the underlying reader until at least n unconsumed bytes
+// are buffered. io.EOF is returned if no data at all is buffered and
+// io.ErrUnexpectedEOF if only part of it is.
+func (d *decoder) fill(n int) error {
+	if d.pos > 0 {
+		d.buf = d.buf[:copy(d.buf, d.buf[d.pos:])]
+		d.pos = 0
+	}
+	if cap(d.buf) < n {
+		buf := make([]byte, len(d.buf), n)
+		copy(buf, d.buf)
+		d.buf = buf
+	}
+	for len(d.buf) < n {
+		nr, err := d.rdr.Read(d.buf[len(d.buf):cap(d.buf)])
+		d.buf = d.buf[:len(d.buf)+nr]
+		if err != nil {
+			if err != io.EOF {
+				return err
+			}
+			if len(d.buf) >= n {
+				break
+			}
+			if len(d.buf) == 0 {
+				return io.EOF
+			}
+			return io.ErrUnexpectedEOF
+		}
+	}
+	return nil
+}
+
+// Decode returns the next entry in the stream.
+func (d *decoder) Decode() (*logger.Message, error) {
+	if err := d.fill(encodeBinaryLen); err != nil {
+		return nil, err
+	}
+	size := int(binary.BigEndian.Uint32(d.buf[:encodeBinaryLen]))
+	if size > maxMsgLen {
+		return nil, errors.Errorf("log entry is too large: %d", size)
+	}
+	total := size + 2*encodeBinaryLen
+	if err := d.fill(total); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	d.entry.Reset()
+	if err := d.entry.Unmarshal(d.buf[encodeBinaryLen : encodeBinaryLen+size]); err != nil {
+		return nil, errors.Wrap(err, "error decoding log entry")
+	}
+	d.pos = total
+	return entryToMessage(&d.entry), nil
+}
+
+func watchFile(name string) (filenotify.FileWatcher, error) {
+	fileWatcher, err := filenotify.New()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fileWatcher.Add(name); err != nil {
+		logrus.WithField("logger", Name).Warnf("falling back to file poller due to error: %v", err)
+		fileWatcher.Close()
+		fileWatcher = filenotify.NewPollingWatcher()
+
+		if err := fileWatcher.Add(name); err != nil {
+			fileWatcher.Close()
+			logrus.Debugf("error watching log file for modifications: %v", err)
+			return nil, err
+		}
+	}
+	return fileWatcher, nil
+}
+
+func followLogs(f *os.File, watcher *logger.LogWatcher, notifyRotate chan interface{}, since, until time.Time) {
+	name := f.Name()
+	fileWatcher, err := watchFile(name)
+	if err != nil {
+		watcher.Err <- err
+		return
+	}
+	defer func() {
+		f.Close()
+		fileWatcher.Remove(name)
+		fileWatcher.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-watcher.WatchClose():
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+	}()
+
+	// stop following once until is reached, even if nothing is logged
+	// after it
+	var untilTimer <-chan time.Time
+	if !until.IsZero() {
+		timer := time.NewTimer(time.Until(until))
+		defer timer.Stop()
+		untilTimer = timer.C
+	}
+
+	dec := newDecoder(f)
+
+	// drain sends every complete entry left in the current file.
+	drain := func() error {
+		for {
+			msg, err := dec.Decode()
+			if err != nil {
+				return nil
+			}
+			if !since.IsZero() && msg.Timestamp.Before(since) {
+				continue
+			}
+			if !until.IsZero() && msg.Timestamp.After(until) {
+				return errDone
+			}
+			watcher.Msg <- msg
+		}
+	}
+
+	handleRotate := func() error {
+		if err := drain(); err != nil {
+			return err
+		}
+		f.Close()
+		// Replace the watcher rather than removing the watch: the rotated
+		// file may be removed by its compression at the same time, and
+		// removing a watch then blocks until the watcher's pending events
+		// are received.
+		fileWatcher.Close()
+
+		// retry when the file doesn't exist
+		for retries := 0; retries <= 5; retries++ {
+			f, err = os.Open(name)
+			if err == nil || !os.IsNotExist(err) {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+		w, err := watchFile(name)
+		if err != nil {
+			return err
+		}
+		fileWatcher = w
+		dec = newDecoder(f)
+		return nil
+	}
+
+	var retries int
+	for {
+		msg, err := dec.Decode()
+		if err == nil {
+			if !since.IsZero() && msg.Timestamp.Before(since) {
+				continue
+			}
+			if !until.IsZero() && msg.Timestamp.After(until) {
+				return
+			}
+			select {
+			case watcher.Msg <- msg:
+			case <-ctx.Done():
+				watcher.Msg <- msg
+				drain()
+				return
+			}
+			continue
+		}
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			watcher.Err <- err
+			return
+		}
+
+		// wait for more data to be written or for the file to be rotated
+		select {
+		case e := <-fileWatcher.Events():
+			logrus.WithField("logger", Name).Debugf("log file event: %v", e)
+		case <-notifyRotate:
+			if err := handleRotate(); err != nil {
+				if err != errDone {
+					watcher.Err <- err
+				}
+				return
+			}
+		case err := <-fileWatcher.Errors():
+			logrus.WithField("logger", Name).Debugf("error watching log file: %v", err)
+			// Something happened, let's try and stay alive and create a new watcher
+			if retries > 5 {
+				watcher.Err <- err
+				return
+			}
+			retries++
+			fileWatcher.Close()
+			fileWatcher, err = watchFile(name)
+			if err != nil {
+				watcher.Err <- err
+				return
+			}
+		case <-untilTimer:
+			drain()
+			return
+		case <-ctx.Done():
+			drain()
+			return
+		}
+	}
+}
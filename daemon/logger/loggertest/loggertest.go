@@ -0,0 +1,31 @@
+// Package loggertest provides helpers for the tests of the log drivers.
+package loggertest
+
+import (
+	"strings"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// ReadAll returns the lines of the messages sent to lw until it is closed,
+// without their trailing newline. It fails the test if an error is sent to
+// lw, or if no message is received for 10 seconds.
+func ReadAll(t require.TestingT, lw *logger.LogWatcher) []string {
+	var lines []string
+	for {
+		select {
+		case msg, ok := <-lw.Msg:
+			if !ok {
+				return lines
+			}
+			lines = append(lines, strings.TrimSuffix(string(msg.Line), "\n"))
+		case err := <-lw.Err:
+			require.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Errorf("timeout waiting for log messages")
+			t.FailNow()
+		}
+	}
+}
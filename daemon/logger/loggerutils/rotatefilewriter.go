@@ -1,12 +1,15 @@
 package loggerutils
 
 import (
+	"compress/gzip"
 	"errors"
+	"io"
 	"os"
 	"strconv"
 	"sync"
 
 	"github.com/docker/docker/pkg/pubsub"
+	"github.com/sirupsen/logrus"
 )
 
 // RotateFileWriter is Logger implementation for default Docker logging.
@@ -17,11 +20,23 @@ type RotateFileWriter struct {
 	capacity     int64 //maximum size of each file
 	currentSize  int64 // current size of the latest file
 	maxFiles     int   //maximum number of files
+	compress     bool  // whether rotated files are gzip compressed
 	notifyRotate *pubsub.Publisher
+
+	// compressing is set while the rotated files are compressed in the
+	// background, compressIndex is the index of the file being compressed,
+	// which rotations shift as they do its name
+	compressing   bool
+	compressIndex int
+	compressWg    sync.WaitGroup
 }
 
+// CompressedFileSuffix is appended to the name of rotated log files when
+// compression is enabled.
+const CompressedFileSuffix = ".gz"
+
 //NewRotateFileWriter creates new RotateFileWriter
-func NewRotateFileWriter(logPath string, capacity int64, maxFiles int) (*RotateFileWriter, error) {
+func NewRotateFileWriter(logPath string, capacity int64, maxFiles int, compress bool) (*RotateFileWriter, error) {
 	log, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
 	if err != nil {
 		return nil, err
@@ -37,6 +52,7 @@ func NewRotateFileWriter(logPath string, capacity int64, maxFiles int) (*RotateF
 		capacity:     capacity,
 		currentSize:  size,
 		maxFiles:     maxFiles,
+		compress:     compress,
 		notifyRotate: pubsub.NewPublisher(0, 1),
 	}, nil
 }
@@ -74,6 +90,9 @@ func (w *RotateFileWriter) checkCapacityAndRotate() error {
 		if err := rotate(name, w.maxFiles); err != nil {
 			return err
 		}
+		if w.compressIndex > 0 {
+			w.compressIndex++
+		}
 		file, err := os.OpenFile(name, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0640)
 		if err != nil {
 			return err
@@ -81,20 +100,42 @@ func (w *RotateFileWriter) checkCapacityAndRotate() error {
 		w.f = file
 		w.currentSize = 0
 		w.notifyRotate.Publish(struct{}{})
+
+		if w.maxFiles < 2 || !w.compress || w.compressing {
+			return nil
+		}
+		// compress in the background so that writes aren't blocked
+		w.compressing = true
+		w.compressWg.Add(1)
+		go func() {
+			defer w.compressWg.Done()
+			w.compressRotatedFiles(name)
+		}()
 	}
 
 	return nil
 }
 
+// rotate shifts the rotated files, compressed or not, and renames the
+// current file to name.1. Rotated files which were left uncompressed, because
+// their compression failed or was interrupted, are kept.
 func rotate(name string, maxFiles int) error {
 	if maxFiles < 2 {
 		return nil
 	}
+
 	for i := maxFiles - 1; i > 1; i-- {
 		toPath := name + "." + strconv.Itoa(i)
 		fromPath := name + "." + strconv.Itoa(i-1)
-		if err := os.Rename(fromPath, toPath); err != nil && !os.IsNotExist(err) {
-			return err
+		for _, suffix := range []string{"", CompressedFileSuffix} {
+			if err := os.Remove(toPath + suffix); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		for _, suffix := range []string{"", CompressedFileSuffix} {
+			if err := os.Rename(fromPath+suffix, toPath+suffix); err != nil && !os.IsNotExist(err) {
+				return err
+			}
 		}
 	}
 
@@ -104,6 +145,88 @@ func rotate(name string, maxFiles int) error {
 	return nil
 }
 
+// compressRotatedFiles compresses the rotated files which are not compressed
+// yet, that is the files rotated since it was started and the ones whose
+// compression failed or was interrupted. An uncompressed file is only removed
+// once its compressed version is written.
+func (w *RotateFileWriter) compressRotatedFiles(name string) {
+	for {
+		w.mu.Lock()
+		file, i := openUncompressedFile(name, w.maxFiles)
+		if file == nil {
+			w.compressing = false
+			w.mu.Unlock()
+			return
+		}
+		w.compressIndex = i
+		w.mu.Unlock()
+
+		tmpName := name + CompressedFileSuffix + ".tmp"
+		err := compressFile(file, tmpName)
+		file.Close()
+
+		w.mu.Lock()
+		i = w.compressIndex
+		w.compressIndex = 0
+		if err == nil {
+			err = finishCompression(name, i, w.maxFiles, tmpName)
+		}
+		if err != nil {
+			os.Remove(tmpName)
+			logrus.Errorf("Failed to compress rotated log file %s.%d: %v", name, i, err)
+			// it is compressed again after the next rotation
+			w.compressing = false
+			w.mu.Unlock()
+			return
+		}
+		w.mu.Unlock()
+	}
+}
+
+// openUncompressedFile opens the first rotated file which is not compressed,
+// and returns it with its index. It returns nil if there is none.
+func openUncompressedFile(name string, maxFiles int) (*os.File, int) {
+	for i := 1; i < maxFiles; i++ {
+		file, err := os.Open(name + "." + strconv.Itoa(i))
+		if err == nil {
+			return file, i
+		}
+		if !os.IsNotExist(err) {
+			logrus.Errorf("Failed to open rotated log file %s.%d: %v", name, i, err)
+		}
+	}
+	return nil, 0
+}
+
+// finishCompression renames the compressed file tmpName to the compressed
+// rotated file i and removes the uncompressed one. The rotated file was
+// removed by a rotation if i is maxFiles or more.
+func finishCompression(name string, i, maxFiles int, tmpName string) error {
+	if i >= maxFiles {
+		return os.Remove(tmpName)
+	}
+	fileName := name + "." + strconv.Itoa(i)
+	if err := os.Rename(tmpName, fileName+CompressedFileSuffix); err != nil {
+		return err
+	}
+	return os.Remove(fileName)
+}
+
+// compressFile gzips file to tmpName.
+func compressFile(file *os.File, tmpName string) error {
+	outFile, err := os.OpenFile(tmpName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0640)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	compressWriter := gzip.NewWriter(outFile)
+	if _, err := io.Copy(compressWriter, file); err != nil {
+		return err
+	}
+	return compressWriter.Close()
+}
+
 // LogPath returns the location the given writer logs to.
 func (w *RotateFileWriter) LogPath() string {
 	w.mu.Lock()
@@ -116,6 +239,11 @@ func (w *RotateFileWriter) MaxFiles() int {
 	return w.maxFiles
 }
 
+// Compress returns whether rotated files are gzip compressed
+func (w *RotateFileWriter) Compress() bool {
+	return w.compress
+}
+
 //NotifyRotate returns the new subscriber
 func (w *RotateFileWriter) NotifyRotate() chan interface{} {
 	return w.notifyRotate.Subscribe()
@@ -126,16 +254,21 @@ func (w *RotateFileWriter) NotifyRotateEvict(sub chan interface{}) {
 	w.notifyRotate.Evict(sub)
 }
 
-// Close closes underlying file and signals all readers to stop.
+// Close closes underlying file and signals all readers to stop. It waits
+// for the rotated files to be compressed.
 func (w *RotateFileWriter) Close() error {
 	w.mu.Lock()
-	defer w.mu.Unlock()
 	if w.closed {
+		w.mu.Unlock()
 		return nil
 	}
 	if err := w.f.Close(); err != nil {
+		w.mu.Unlock()
 		return err
 	}
 	w.closed = true
+	w.mu.Unlock()
+
+	w.compressWg.Wait()
 	return nil
 }
@@ -0,0 +1,52 @@
+package loggerutils
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readCompressedFile(t *testing.T, name string) string {
+	f, err := os.Open(name + CompressedFileSuffix)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	defer gz.Close()
+	b, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestRotateKeepsUncompressedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotatefilewriter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "container.log")
+
+	// a rotated file whose compression was interrupted
+	require.NoError(t, ioutil.WriteFile(name+".1", []byte("old"), 0640))
+
+	w, err := NewRotateFileWriter(name, 1, 3, true)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("a"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("b"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "a", readCompressedFile(t, name+".1"))
+	assert.Equal(t, "old", readCompressedFile(t, name+".2"))
+	for _, p := range []string{name + ".1", name + ".2"} {
+		_, err := os.Stat(p)
+		assert.True(t, os.IsNotExist(err), p)
+	}
+	b, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(b))
+}
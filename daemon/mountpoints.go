@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/pkg/archive"
+)
+
+// ContainerRemoveMountpoints removes the mountpoints of the given targets
+// which were created in the filesystem of the container when it was started,
+// along with the parent directories created for them, so that they are not
+// committed into the layer of the container. This is used by the builder
+// for the mounts of a RUN instruction. Mountpoints which were part of the
+// image, or which are not empty, are kept.
+func (daemon *Daemon) ContainerRemoveMountpoints(cID string, targets []string) error {
+	container, err := daemon.GetContainer(cID)
+	if err != nil {
+		return err
+	}
+
+	changes, err := container.RWLayer.Changes()
+	if err != nil {
+		return err
+	}
+	added := make(map[string]bool)
+	for _, c := range changes {
+		if c.Kind == archive.ChangeAdd {
+			added[c.Path] = true
+		}
+	}
+
+	if err := daemon.Mount(container); err != nil {
+		return err
+	}
+	defer daemon.Unmount(container)
+
+	root := container.BaseFS.Path()
+	for _, target := range targets {
+		p, err := container.GetResourcePath(target)
+		if err != nil {
+			return err
+		}
+		if err := removeMountpoint(root, p, added); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeMountpoint removes the mountpoint p, and then its parent
+// directories, as long as they were added to the root and are empty.
+func removeMountpoint(root, p string, added map[string]bool) error {
+	for {
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !added[string(filepath.Separator)+rel] {
+			return nil
+		}
+		fi, err := os.Lstat(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() {
+			if empty, err := isEmptyDir(p); err != nil || !empty {
+				return err
+			}
+		} else if !fi.Mode().IsRegular() || fi.Size() > 0 {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+		p = filepath.Dir(p)
+	}
+}
+
+func isEmptyDir(p string) (bool, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if _, err := f.Readdirnames(1); err != io.EOF {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveMountpoints(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "mountpoints")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	// the image and the container filesystem after a RUN instruction
+	image := filepath.Join(tmp, "image")
+	rootfs := filepath.Join(tmp, "rootfs")
+	for _, root := range []string{image, rootfs} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "run"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(root, "empty"), nil, 0644))
+	}
+	// mountpoints created when the container was started
+	require.NoError(t, os.MkdirAll(filepath.Join(rootfs, "run", "secrets"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootfs, "run", "secrets", "token"), nil, 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(rootfs, "cache", "dir"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(rootfs, "app"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootfs, "app", "secret"), nil, 0644))
+	// written by the command
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootfs, "app", "bin"), []byte("bin"), 0755))
+
+	changes, err := archive.ChangesDirs(rootfs, image)
+	require.NoError(t, err)
+	added := make(map[string]bool)
+	for _, c := range changes {
+		if c.Kind == archive.ChangeAdd {
+			added[c.Path] = true
+		}
+	}
+
+	for _, target := range []string{"/run/secrets/token", "/cache/dir", "/app/secret", "/empty"} {
+		require.NoError(t, removeMountpoint(rootfs, filepath.Join(rootfs, target), added))
+	}
+
+	// only the result of the command is added to the layer
+	changes, err = archive.ChangesDirs(rootfs, image)
+	require.NoError(t, err)
+	var paths []string
+	for _, c := range changes {
+		if c.Kind == archive.ChangeAdd {
+			paths = append(paths, c.Path)
+		}
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{"/app", "/app/bin"}, paths)
+
+	_, err = os.Stat(filepath.Join(rootfs, "empty"))
+	assert.NoError(t, err, "mountpoints which are part of the image are kept")
+}
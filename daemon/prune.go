@@ -11,6 +11,7 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 	timetypes "github.com/docker/docker/api/types/time"
+	"github.com/docker/docker/distribution"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
 	"github.com/docker/docker/pkg/directory"
@@ -305,6 +306,14 @@ deleteImagesLoop:
 		}
 	}
 
+	// The downloads of the pulls that were interrupted are not resumed
+	// anymore
+	reclaimed, err := distribution.PrunePartialDownloads(daemon.layerDownloadDir(platform))
+	if err != nil {
+		logrus.Warnf("could not prune partial downloads: %v", err)
+	}
+	rep.SpaceReclaimed += reclaimed
+
 	if canceled {
 		logrus.Debugf("ImagesPrune operation cancelled: %#v", *rep)
 	}
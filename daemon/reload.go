@@ -16,12 +16,14 @@ import (
 // - Daemon debug log level
 // - Daemon max concurrent downloads
 // - Daemon max concurrent uploads
+// - Daemon max download parts
 // - Daemon shutdown timeout (in seconds)
 // - Cluster discovery (reconfigure and restart)
 // - Daemon labels
 // - Insecure registries
 // - Registry mirrors
 // - Daemon live restore
+// - Event sinks
 func (daemon *Daemon) Reload(conf *config.Config) (err error) {
 	daemon.configStore.Lock()
 	attributes := map[string]string{}
@@ -41,6 +43,7 @@ func (daemon *Daemon) Reload(conf *config.Config) (err error) {
 	}
 	daemon.reloadDebug(conf, attributes)
 	daemon.reloadMaxConcurrentDownloadsAndUploads(conf, attributes)
+	daemon.reloadMaxDownloadParts(conf, attributes)
 	daemon.reloadShutdownTimeout(conf, attributes)
 
 	if err := daemon.reloadClusterDiscovery(conf, attributes); err != nil {
@@ -61,6 +64,9 @@ func (daemon *Daemon) Reload(conf *config.Config) (err error) {
 	if err := daemon.reloadLiveRestore(conf, attributes); err != nil {
 		return err
 	}
+	if err := daemon.reloadEventSinks(conf, attributes); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -111,6 +117,19 @@ func (daemon *Daemon) reloadMaxConcurrentDownloadsAndUploads(conf *config.Config
 	attributes["max-concurrent-uploads"] = fmt.Sprintf("%d", *daemon.configStore.MaxConcurrentUploads)
 }
 
+// reloadMaxDownloadParts updates configuration with max download parts
+// option and updates the passed attributes
+func (daemon *Daemon) reloadMaxDownloadParts(conf *config.Config, attributes map[string]string) {
+	// update corresponding configuration
+	if conf.IsValueSet("max-download-parts") {
+		daemon.configStore.MaxDownloadParts = conf.MaxDownloadParts
+		logrus.Debugf("Reset Max Download Parts: %d", daemon.configStore.MaxDownloadParts)
+	}
+
+	// prepare reload event attributes with updatable configurations
+	attributes["max-download-parts"] = fmt.Sprintf("%d", daemon.configStore.MaxDownloadParts)
+}
+
 // reloadShutdownTimeout updates configuration with daemon shutdown timeout option
 // and updates the passed attributes
 func (daemon *Daemon) reloadShutdownTimeout(conf *config.Config, attributes map[string]string) {
@@ -308,3 +327,25 @@ func (daemon *Daemon) reloadLiveRestore(conf *config.Config, attributes map[stri
 	attributes["live-restore"] = fmt.Sprintf("%t", daemon.configStore.LiveRestoreEnabled)
 	return nil
 }
+
+// reloadEventSinks updates configuration with event sink plugins
+// and updates the passed attributes
+func (daemon *Daemon) reloadEventSinks(conf *config.Config, attributes map[string]string) error {
+	// update corresponding configuration
+	if conf.IsValueSet("event-sinks") {
+		daemon.configStore.EventSinks = conf.EventSinks
+		daemon.EventsService.SetSinks(newEventSinks(conf.EventSinks, eventSinksSpoolDir(daemon.configStore.Root), daemon.PluginStore, daemon.EventsService.Sinks()))
+	}
+
+	// prepare reload event attributes with updatable configurations
+	if daemon.configStore.EventSinks != nil {
+		sinks, err := json.Marshal(daemon.configStore.EventSinks)
+		if err != nil {
+			return err
+		}
+		attributes["event-sinks"] = string(sinks)
+	} else {
+		attributes["event-sinks"] = "[]"
+	}
+	return nil
+}
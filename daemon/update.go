@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	volumestore "github.com/docker/docker/volume/store"
 	"github.com/pkg/errors"
 )
 
@@ -92,3 +94,21 @@ func (daemon *Daemon) update(name string, hostConfig *container.HostConfig) erro
 func errCannotUpdate(containerID string, err error) error {
 	return errors.Wrap(err, "Cannot update container "+containerID)
 }
+
+// VolumeUpdate changes the labels and the driver options of a volume. Nil
+// labels or options are left unchanged.
+// This is called directly from the Engine API
+func (daemon *Daemon) VolumeUpdate(name string, opts, labels map[string]string) (*types.Volume, error) {
+	v, err := daemon.volumes.Update(name, opts, labels)
+	if err != nil {
+		if volumestore.IsNotExist(err) {
+			return nil, volumeNotFound(name)
+		}
+		return nil, err
+	}
+
+	daemon.LogVolumeEvent(v.Name(), "update", map[string]string{"driver": v.DriverName()})
+	apiV := volumeToAPIType(v)
+	apiV.Mountpoint = v.Path()
+	return apiV, nil
+}
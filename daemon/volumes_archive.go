@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/stringid"
+	volumestore "github.com/docker/docker/volume/store"
+	"github.com/sirupsen/logrus"
+)
+
+// VolumeExport writes the contents of the volume to the given writer as a
+// tar archive. The volume is archived while chrooted to its path, so that
+// symlinks in the volume cannot escape it. An error is returned if the
+// volume cannot be found.
+func (daemon *Daemon) VolumeExport(name string, out io.Writer) error {
+	return daemon.withMountedVolume(name, "export", func(path string) error {
+		data, err := chrootarchive.Tar(path, &archive.TarOptions{
+			Compression: archive.Uncompressed,
+			UIDMaps:     daemon.idMappings.UIDs(),
+			GIDMaps:     daemon.idMappings.GIDs(),
+		}, path)
+		if err != nil {
+			return fmt.Errorf("Error exporting volume %s: %v", name, err)
+		}
+		defer data.Close()
+
+		if _, err := io.Copy(out, data); err != nil {
+			return fmt.Errorf("Error exporting volume %s: %v", name, err)
+		}
+		return nil
+	})
+}
+
+// VolumeImport extracts the given tar archive into the volume. Existing
+// files of the volume are overwritten by the files of the archive. If
+// noOverwriteDirNonDir is true then it will be an error if unpacking the
+// given content would cause an existing directory to be replaced with a
+// non-directory and vice versa.
+func (daemon *Daemon) VolumeImport(name string, noOverwriteDirNonDir bool, content io.Reader) error {
+	return daemon.withMountedVolume(name, "import", func(path string) error {
+		options := &archive.TarOptions{
+			NoOverwriteDirNonDir: noOverwriteDirNonDir,
+			UIDMaps:              daemon.idMappings.UIDs(),
+			GIDMaps:              daemon.idMappings.GIDs(),
+		}
+		if err := chrootarchive.Untar(content, path, options); err != nil {
+			return fmt.Errorf("Error importing into volume %s: %v", name, err)
+		}
+		return nil
+	})
+}
+
+// withMountedVolume mounts the volume with the given name and calls fn with
+// the path of its data. The volume is referenced while it is mounted, so
+// that it cannot be removed in the meantime. An event is emitted with the
+// given action if fn succeeds.
+func (daemon *Daemon) withMountedVolume(name, action string, fn func(path string) error) error {
+	v, err := daemon.volumes.Get(name)
+	if err != nil {
+		if volumestore.IsNotExist(err) {
+			return volumeNotFound(name)
+		}
+		return systemError{err}
+	}
+
+	ref := action + ":" + stringid.GenerateNonCryptoID()
+	v, err = daemon.volumes.GetWithRef(v.Name(), v.DriverName(), ref)
+	if err != nil {
+		if volumestore.IsNotExist(err) {
+			return volumeNotFound(name)
+		}
+		return systemError{err}
+	}
+	defer daemon.volumes.Dereference(v, ref)
+
+	path, err := v.Mount(ref)
+	if err != nil {
+		return systemError{err}
+	}
+	defer func() {
+		if err := v.Unmount(ref); err != nil {
+			logrus.WithError(err).WithField("volume", v.Name()).Warnf("error unmounting volume after %s", action)
+		}
+	}()
+
+	if err := fn(path); err != nil {
+		return err
+	}
+	daemon.LogVolumeEvent(v.Name(), action, map[string]string{"driver": v.DriverName()})
+	return nil
+}
@@ -0,0 +1,366 @@
+package distribution
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/distribution/xfer"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/registry"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// BuildCacheConfigMediaType is the media type of the config of a build cache
+// manifest
+const BuildCacheConfigMediaType = "application/vnd.docker.buildcache.config.v1+json"
+
+// BuildCacheTypes represents the schema2 config types for build caches
+var BuildCacheTypes = []string{
+	BuildCacheConfigMediaType,
+}
+
+// BuildCacheConfig is the config of a build cache manifest. It holds the
+// configs of the images produced by a build along with the descriptors of
+// their layers, so that the cache can be matched without pulling the images.
+type BuildCacheConfig struct {
+	Images []BuildCacheImage `json:"images"`
+}
+
+// BuildCacheImage is an image of a build cache
+type BuildCacheImage struct {
+	// Config is the image config
+	Config json.RawMessage `json:"config"`
+	// Layers are the descriptors of the layer blobs, in the order of the
+	// DiffIDs of the image root filesystem
+	Layers []distribution.Descriptor `json:"layers"`
+}
+
+// PushBuildCache pushes the configs of the images with the given IDs and
+// their layers to ref as a build cache manifest. The config media type of the
+// manifest is BuildCacheConfigMediaType, so it cannot be pulled as an image.
+func PushBuildCache(ctx context.Context, ref reference.NamedTagged, images []digest.Digest, imagePushConfig *ImagePushConfig) error {
+	repoInfo, err := imagePushConfig.RegistryService.ResolveRepository(ref)
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := imagePushConfig.RegistryService.LookupPushEndpoints(reference.Domain(repoInfo.Name))
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, endpoint := range endpoints {
+		if endpoint.Version == registry.APIVersion1 {
+			continue
+		}
+
+		logrus.Debugf("Trying to push build cache %s to %s", reference.FamiliarString(ref), endpoint.URL)
+
+		p := &v2Pusher{
+			v2MetadataService: metadata.NewV2MetadataService(imagePushConfig.MetadataStore),
+			ref:               ref,
+			endpoint:          endpoint,
+			repoInfo:          repoInfo,
+			config:            imagePushConfig,
+		}
+		p.pushState.remoteLayers = make(map[layer.DiffID]distribution.Descriptor)
+
+		p.repo, p.pushState.confirmedV2, err = NewV2Repository(ctx, repoInfo, endpoint, imagePushConfig.MetaHeaders, imagePushConfig.AuthConfig, "push", "pull")
+		if err == nil {
+			err = p.pushBuildCache(ctx, ref, images)
+		}
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		default:
+		}
+		if !continueOnError(err) {
+			return err
+		}
+		lastErr = err
+		logrus.Infof("Attempting next endpoint for build cache push after error: %v", err)
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no endpoints found for %s", reference.FamiliarString(ref))
+	}
+	return lastErr
+}
+
+func (p *v2Pusher) pushBuildCache(ctx context.Context, ref reference.NamedTagged, images []digest.Digest) error {
+	hmacKey, err := metadata.ComputeV2MetadataHMACKey(p.config.AuthConfig)
+	if err != nil {
+		return fmt.Errorf("failed to compute hmac key of auth config: %v", err)
+	}
+
+	descriptorTemplate := v2PushDescriptor{
+		v2MetadataService: p.v2MetadataService,
+		hmacKey:           hmacKey,
+		repoInfo:          p.repoInfo.Name,
+		ref:               p.ref,
+		endpoint:          p.endpoint,
+		repo:              p.repo,
+		pushState:         &p.pushState,
+	}
+
+	var (
+		cacheConfig BuildCacheConfig
+		layers      []distribution.Descriptor
+		seen        = make(map[digest.Digest]struct{})
+	)
+	for _, id := range images {
+		imgConfig, err := p.config.ImageStore.Get(id)
+		if err != nil {
+			return fmt.Errorf("could not find image %s: %v", id, err)
+		}
+
+		rootfs, _, err := p.config.ImageStore.RootFSAndOSFromConfig(imgConfig)
+		if err != nil {
+			return fmt.Errorf("unable to get rootfs for image %s: %s", id, err)
+		}
+
+		topLayer, err := p.config.LayerStore.Get(rootfs.ChainID())
+		if err != nil {
+			return fmt.Errorf("failed to get top layer from image: %v", err)
+		}
+
+		var descriptors []xfer.UploadDescriptor
+		l := topLayer
+		for range rootfs.DiffIDs {
+			descriptor := descriptorTemplate
+			descriptor.layer = l
+			descriptor.checkedDigests = make(map[digest.Digest]struct{})
+			descriptors = append(descriptors, &descriptor)
+
+			l = l.Parent()
+		}
+
+		err = p.config.UploadManager.Upload(ctx, descriptors, p.config.ProgressOutput)
+		topLayer.Release()
+		if err != nil {
+			return err
+		}
+
+		cacheImage := BuildCacheImage{Config: imgConfig}
+		// descriptors is in reverse order
+		for i := len(descriptors) - 1; i >= 0; i-- {
+			d := descriptors[i].(*v2PushDescriptor).Descriptor()
+			cacheImage.Layers = append(cacheImage.Layers, d)
+			if _, ok := seen[d.Digest]; !ok {
+				seen[d.Digest] = struct{}{}
+				layers = append(layers, d)
+			}
+		}
+		cacheConfig.Images = append(cacheConfig.Images, cacheImage)
+	}
+
+	configJSON, err := json.Marshal(cacheConfig)
+	if err != nil {
+		return err
+	}
+
+	builder := schema2.NewManifestBuilder(p.repo.Blobs(ctx), BuildCacheConfigMediaType, configJSON)
+	for _, d := range layers {
+		if err := builder.AppendReference(d); err != nil {
+			return err
+		}
+	}
+	manifest, err := builder.Build(ctx)
+	if err != nil {
+		return err
+	}
+
+	manSvc, err := p.repo.Manifests(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := manSvc.Put(ctx, manifest, distribution.WithTag(ref.Tag())); err != nil {
+		return err
+	}
+
+	_, canonicalManifest, err := manifest.Payload()
+	if err != nil {
+		return err
+	}
+	progress.Messagef(p.config.ProgressOutput, "", "%s: digest: %s size: %d", ref.Tag(), digest.FromBytes(canonicalManifest), len(canonicalManifest))
+	return nil
+}
+
+// BuildCache is a build cache pulled from a registry. Only the config of the
+// build cache manifest is pulled, layers are pulled with DownloadLayers when
+// they are needed.
+type BuildCache struct {
+	BuildCacheConfig
+	puller *v2Puller
+}
+
+// PullBuildCache pulls the config of the build cache manifest at ref.
+// The DownloadManager of imagePullConfig is used by DownloadLayers.
+func PullBuildCache(ctx context.Context, ref reference.Named, imagePullConfig *ImagePullConfig) (*BuildCache, error) {
+	repoInfo, err := imagePullConfig.RegistryService.ResolveRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateRepoName(repoInfo.Name); err != nil {
+		return nil, err
+	}
+
+	endpoints, err := imagePullConfig.RegistryService.LookupPullEndpoints(reference.Domain(repoInfo.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	if imagePullConfig.Platform == "" {
+		imagePullConfig.Platform = runtime.GOOS
+	}
+
+	var lastErr error
+	for _, endpoint := range endpoints {
+		if endpoint.Version == registry.APIVersion1 {
+			continue
+		}
+
+		logrus.Debugf("Trying to pull build cache %s from %s", reference.FamiliarString(ref), endpoint.URL)
+
+		p := &v2Puller{
+			V2MetadataService: metadata.NewV2MetadataService(imagePullConfig.MetadataStore),
+			endpoint:          endpoint,
+			config:            imagePullConfig,
+			repoInfo:          repoInfo,
+		}
+
+		p.repo, p.confirmedV2, err = NewV2Repository(ctx, repoInfo, endpoint, imagePullConfig.MetaHeaders, imagePullConfig.AuthConfig, "pull")
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		bc, err := p.pullBuildCacheConfig(ctx, ref)
+		if err == nil {
+			return bc, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		default:
+		}
+		if !continueOnError(err) {
+			return nil, TranslatePullError(err, ref)
+		}
+		lastErr = err
+		logrus.Infof("Attempting next endpoint for build cache pull after error: %v", err)
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no endpoints found for %s", reference.FamiliarString(ref))
+	}
+	return nil, TranslatePullError(lastErr, ref)
+}
+
+func (p *v2Puller) pullBuildCacheConfig(ctx context.Context, ref reference.Named) (*BuildCache, error) {
+	manSvc, err := p.repo.Manifests(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest distribution.Manifest
+	if digested, isDigested := ref.(reference.Canonical); isDigested {
+		manifest, err = manSvc.Get(ctx, digested.Digest())
+	} else if tagged, isTagged := ref.(reference.NamedTagged); isTagged {
+		manifest, err = manSvc.Get(ctx, "", distribution.WithTag(tagged.Tag()))
+	} else {
+		return nil, fmt.Errorf("internal error: reference has neither a tag nor a digest: %s", reference.FamiliarString(ref))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := manifest.(*schema2.DeserializedManifest)
+	if !ok {
+		return nil, invalidManifestFormatError{}
+	}
+	if m.Manifest.Config.MediaType != BuildCacheConfigMediaType {
+		configClass := mediaTypeClasses[m.Manifest.Config.MediaType]
+		if configClass == "" {
+			configClass = "unknown"
+		}
+		return nil, invalidManifestClassError{m.Manifest.Config.MediaType, configClass}
+	}
+	if _, err := schema2ManifestDigest(ref, m); err != nil {
+		return nil, err
+	}
+
+	configJSON, err := p.pullSchema2Config(ctx, m.Manifest.Config.Digest)
+	if err != nil {
+		return nil, ImageConfigPullError{Err: err}
+	}
+
+	bc := &BuildCache{puller: p}
+	if err := json.Unmarshal(configJSON, &bc.BuildCacheConfig); err != nil {
+		return nil, err
+	}
+	return bc, nil
+}
+
+// DownloadLayers pulls the first n layers of the image at index i of the
+// build cache and registers them in the layer store. Layers that are already
+// registered are not pulled again. The returned function releases the
+// resources of the download, it must be called once the layers are referenced
+// by an image.
+func (bc *BuildCache) DownloadLayers(ctx context.Context, i, n int, progressOutput progress.Output) (func(), error) {
+	p := bc.puller
+	img := bc.Images[i]
+
+	rootFS, _, err := p.config.ImageStore.RootFSAndOSFromConfig(img.Config)
+	if err != nil {
+		return nil, err
+	}
+	if rootFS == nil {
+		return nil, errRootFSInvalid
+	}
+	if len(img.Layers) != len(rootFS.DiffIDs) || n > len(img.Layers) {
+		return nil, errRootFSMismatch
+	}
+
+	var descriptors []xfer.DownloadDescriptor
+	for j, d := range img.Layers[:n] {
+		descriptors = append(descriptors, &v2LayerDescriptor{
+			digest:            d.Digest,
+			diffID:            rootFS.DiffIDs[j],
+			repo:              p.repo,
+			repoInfo:          p.repoInfo,
+			V2MetadataService: p.V2MetadataService,
+			downloadDir:       p.config.DownloadDir,
+			maxDownloadParts:  p.config.MaxDownloadParts,
+			src:               d,
+		})
+	}
+
+	downloadedRootFS, release, err := p.config.DownloadManager.Download(ctx, *image.NewRootFS(), layer.OS(p.config.Platform), descriptors, progressOutput)
+	if err != nil {
+		return nil, err
+	}
+	for j, diffID := range downloadedRootFS.DiffIDs {
+		if diffID != rootFS.DiffIDs[j] {
+			release()
+			return nil, errRootFSMismatch
+		}
+	}
+	return release, nil
+}
@@ -62,6 +62,14 @@ type ImagePullConfig struct {
 	// Platform is the requested platform of the image being pulled to ensure it can be validated
 	// when the host platform supports multiple image operating systems.
 	Platform string
+	// DownloadDir is the directory where the layers are downloaded to
+	// partial files named after their digest, so that the downloads that
+	// are interrupted are resumed by the next pull. The layers are
+	// downloaded to temporary files if it is empty.
+	DownloadDir string
+	// MaxDownloadParts is the maximum number of concurrent range requests
+	// each large layer is downloaded with.
+	MaxDownloadParts int
 }
 
 // ImagePushConfig stores push configuration.
@@ -71,6 +79,9 @@ type ImagePushConfig struct {
 	// ConfigMediaType is the configuration media type for
 	// schema2 manifests.
 	ConfigMediaType string
+	// OCIManifest pushes the images with OCI image manifests instead of
+	// schema2 ones, without falling back to schema1.
+	OCIManifest bool
 	// LayerStore manages layers.
 	LayerStore PushLayerProvider
 	// TrustKey is the private key for legacy signatures. This is typically
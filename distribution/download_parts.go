@@ -0,0 +1,280 @@
+package distribution
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/distribution/registry/client"
+	"github.com/docker/distribution/registry/client/transport"
+	"github.com/docker/docker/distribution/xfer"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+)
+
+// minDownloadPartSize is the smallest range of a layer downloaded by a
+// concurrent range request. Smaller layers are downloaded with a single
+// request.
+var minDownloadPartSize int64 = 32 * 1024 * 1024
+
+// downloadParts returns the number of concurrent range requests a layer of
+// size bytes is downloaded with, given the maximum of the daemon.
+func downloadParts(size int64, max int) int {
+	if max <= 1 || size < 2*minDownloadPartSize {
+		return 1
+	}
+	if parts := size / minDownloadPartSize; parts < int64(max) {
+		return int(parts)
+	}
+	return max
+}
+
+// downloadInParts downloads the layer to the download file with parts
+// concurrent range requests, and verifies the whole file once they are all
+// complete. If the download fails, the file is truncated to the content
+// downloaded contiguously from its beginning, which the next attempt
+// resumes from. That content is also saved next to the file while the parts
+// are downloaded, so that the file is truncated the same way when it is
+// opened after the daemon stopped.
+func (ld *v2LayerDescriptor) downloadInParts(ctx context.Context, progressOutput progress.Output, parts int) (io.ReadCloser, int64, error) {
+	size := ld.src.Size
+	partSize := (size + int64(parts) - 1) / int64(parts)
+	logrus.Debugf("downloading %q with %d range requests of %d bytes", ld.digest, parts, partSize)
+
+	f := ld.tmpFile
+	if err := savePartsState(f, 0); err != nil {
+		return nil, 0, xfer.DoNotRetry{Err: err}
+	}
+	defer removePartsState(f)
+
+	partsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writers := make([]*partWriter, parts)
+	pp := &partsProgress{
+		out:         progressOutput,
+		id:          ld.ID(),
+		size:        size,
+		rateLimiter: rate.NewLimiter(rate.Every(100*time.Millisecond), 1),
+		f:           f,
+		writers:     writers,
+		partSize:    partSize,
+	}
+	errs := make([]error, parts)
+	var wg sync.WaitGroup
+	for i := range writers {
+		start := int64(i) * partSize
+		length := partSize
+		if start+length > size {
+			length = size - start
+		}
+		writers[i] = &partWriter{f: f, offset: start, progress: pp}
+		wg.Add(1)
+		go func(i int, length int64) {
+			defer wg.Done()
+			if errs[i] = ld.downloadPart(partsCtx, writers[i], length); errs[i] != nil {
+				cancel()
+			}
+		}(i, length)
+	}
+	wg.Wait()
+
+	// The parts canceled after another one failed return
+	// context.Canceled.
+	err := ctx.Err()
+	for _, partErr := range errs {
+		if partErr != nil && partErr != context.Canceled {
+			err = partErr
+			break
+		}
+	}
+	if err == transport.ErrWrongCodeForByteRange {
+		// The registry doesn't support range requests, the next attempt
+		// downloads the layer with a single request.
+		ld.maxDownloadParts = 1
+		if err := ld.truncateDownloadFile(); err != nil {
+			return nil, 0, xfer.DoNotRetry{Err: err}
+		}
+		return nil, 0, err
+	}
+	if err != nil {
+		if err := ld.truncatePartialDownload(writers, partSize); err != nil {
+			return nil, 0, xfer.DoNotRetry{Err: err}
+		}
+		return nil, 0, retryOnError(err)
+	}
+
+	pp.done()
+	if err := ld.hashDownloadFile(size); err != nil {
+		if err := ld.truncateDownloadFile(); err != nil {
+			return nil, 0, xfer.DoNotRetry{Err: err}
+		}
+		return nil, 0, err
+	}
+	return ld.verifyDownload(progressOutput, 0, size)
+}
+
+// downloadPart downloads length bytes of the layer with a range request
+// starting at the offset of w.
+func (ld *v2LayerDescriptor) downloadPart(ctx context.Context, w *partWriter, length int64) error {
+	layerDownload, err := ld.openRange(ctx, w.offset, length)
+	if err != nil {
+		if ctx.Err() != nil {
+			return context.Canceled
+		}
+		return err
+	}
+	reader := ioutils.NewCancelReadCloser(ctx, layerDownload)
+	defer reader.Close()
+
+	if _, err := io.CopyN(w, reader, length); err != nil {
+		if ctx.Err() != nil {
+			return context.Canceled
+		}
+		return err
+	}
+	return nil
+}
+
+// openRange requests the length bytes of the layer starting at offset, with
+// a bounded range so that the registry doesn't send the rest of the blob.
+func (ld *v2LayerDescriptor) openRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	repo, ok := ld.repo.(*v2Repository)
+	if !ok {
+		// other repositories can only request the rest of the blob from
+		// offset
+		layerDownload, err := ld.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := layerDownload.Seek(offset, os.SEEK_SET); err != nil {
+			layerDownload.Close()
+			return nil, err
+		}
+		return layerDownload, nil
+	}
+
+	ref, err := reference.WithDigest(repo.Named(), ld.digest)
+	if err != nil {
+		return nil, err
+	}
+	blobURL, err := repo.ub.BuildBlobURL(ref)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", blobURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	req.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := repo.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, distribution.ErrBlobUnknown
+	case resp.StatusCode >= 200 && resp.StatusCode < 400:
+		resp.Body.Close()
+		return nil, transport.ErrWrongCodeForByteRange
+	default:
+		defer resp.Body.Close()
+		return nil, client.HandleErrorResponse(resp)
+	}
+	if contentRange := resp.Header.Get("Content-Range"); !strings.HasPrefix(contentRange, fmt.Sprintf("bytes %d-", offset)) {
+		resp.Body.Close()
+		return nil, errors.Errorf("unexpected Content-Range %q in response to a request starting at offset %d", contentRange, offset)
+	}
+	return resp.Body, nil
+}
+
+// truncatePartialDownload truncates the download file to the content
+// downloaded from its beginning by the first parts, so that it can be
+// resumed.
+func (ld *v2LayerDescriptor) truncatePartialDownload(writers []*partWriter, partSize int64) error {
+	ld.verifier = nil
+
+	if err := ld.tmpFile.Truncate(contiguousDownload(writers, partSize)); err != nil {
+		logrus.Errorf("error truncating download file: %v", err)
+		return err
+	}
+	return nil
+}
+
+// contiguousDownload returns the size of the content downloaded from the
+// beginning of the layer by the first parts.
+func contiguousDownload(writers []*partWriter, partSize int64) int64 {
+	var downloaded int64
+	for _, w := range writers {
+		downloaded += w.written
+		if w.written < partSize {
+			break
+		}
+	}
+	return downloaded
+}
+
+// partWriter writes a part of a layer to the download file, at the offset
+// of the part.
+type partWriter struct {
+	f        *os.File
+	offset   int64
+	written  int64
+	progress *partsProgress
+}
+
+func (w *partWriter) Write(b []byte) (int, error) {
+	n, err := w.f.WriteAt(b, w.offset+w.written)
+	w.progress.add(w, int64(n))
+	return n, err
+}
+
+// partsProgress reports the progress of the parts of a layer download as a
+// single progress bar, and saves the content downloaded contiguously next to
+// the download file f at the same rate.
+type partsProgress struct {
+	mu          sync.Mutex
+	out         progress.Output
+	id          string
+	current     int64
+	size        int64
+	rateLimiter *rate.Limiter
+	f           *os.File
+	writers     []*partWriter
+	partSize    int64
+}
+
+// add records that n bytes were written by w.
+func (p *partsProgress) add(w *partWriter, n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	w.written += n
+	p.current += n
+	if p.rateLimiter.Allow() {
+		p.out.WriteProgress(progress.Progress{ID: p.id, Action: "Downloading", Current: p.current, Total: p.size})
+		if err := savePartsState(p.f, contiguousDownload(p.writers, p.partSize)); err != nil {
+			logrus.Debugf("error saving the state of download %s: %v", p.f.Name(), err)
+		}
+	}
+}
+
+func (p *partsProgress) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.out.WriteProgress(progress.Progress{ID: p.id, Action: "Downloading", Current: p.size, Total: p.size})
+}
@@ -0,0 +1,124 @@
+package distribution
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/docker/docker/pkg/progress"
+	"golang.org/x/net/context"
+)
+
+func TestDownloadParts(t *testing.T) {
+	defer func(size int64) { minDownloadPartSize = size }(minDownloadPartSize)
+	minDownloadPartSize = 10
+
+	for _, tc := range []struct {
+		size     int64
+		max      int
+		expected int
+	}{
+		{size: 100, max: 0, expected: 1},
+		{size: 100, max: 1, expected: 1},
+		{size: 19, max: 4, expected: 1},
+		{size: 20, max: 4, expected: 2},
+		{size: 35, max: 4, expected: 3},
+		{size: 100, max: 4, expected: 4},
+	} {
+		if parts := downloadParts(tc.size, tc.max); parts != tc.expected {
+			t.Fatalf("expected %d parts for %d bytes with a max of %d, got %d", tc.expected, tc.size, tc.max, parts)
+		}
+	}
+}
+
+func TestDownloadInParts(t *testing.T) {
+	defer func(size int64) { minDownloadPartSize = size }(minDownloadPartSize)
+	minDownloadPartSize = 16
+
+	reg := newTestRegistry()
+	failRange := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.mu.Lock()
+		fail := failRange != "" && r.Header.Get("Range") == failRange
+		reg.mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	tmpDir, err := ioutil.TempDir("", "download-parts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	blob := bytes.Repeat([]byte("0123456789"), 10)
+	dgst := reg.putBlob(blob).Digest
+
+	ld := testPartialLayerDescriptor(t, ts, tmpDir, blob)
+	ld.maxDownloadParts = 4
+	testDownload(t, ld, blob)
+	sort.Strings(reg.blobRequests)
+	expected := []string{
+		dgst.String() + " bytes=0-24",
+		dgst.String() + " bytes=25-49",
+		dgst.String() + " bytes=50-74",
+		dgst.String() + " bytes=75-99",
+	}
+	if !reflect.DeepEqual(reg.blobRequests, expected) {
+		t.Fatalf("expected blob requests %v, got %v", expected, reg.blobRequests)
+	}
+	partial := partialDownloadPath(tmpDir, dgst)
+	if _, err := os.Stat(partial + partsStateSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected the download state to be removed, got %v", err)
+	}
+
+	// a failed part leaves the content downloaded from the beginning of
+	// the blob, which is resumed from
+	reg.mu.Lock()
+	failRange = "bytes=50-74"
+	reg.mu.Unlock()
+	ld = testPartialLayerDescriptor(t, ts, tmpDir, blob)
+	ld.maxDownloadParts = 4
+	if _, _, err := ld.Download(context.Background(), progress.DiscardOutput()); err == nil {
+		t.Fatal("expected the download to fail")
+	}
+	content, err := ioutil.ReadFile(partial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(content) > 50 || !bytes.HasPrefix(blob, content) {
+		t.Fatalf("expected the partial download to be a prefix of the blob, got %q", content)
+	}
+	reg.mu.Lock()
+	failRange = ""
+	reg.mu.Unlock()
+	testDownload(t, ld, blob)
+
+	// a download in parts interrupted by the daemon stopping leaves holes
+	// in the partial download, which is truncated to the saved state
+	content = append(append([]byte{}, blob[:25]...), make([]byte, 25)...)
+	content = append(content, blob[50:75]...)
+	if err := ioutil.WriteFile(partial, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(partial+partsStateSuffix, []byte("25"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reg.blobRequests = nil
+	testDownload(t, testPartialLayerDescriptor(t, ts, tmpDir, blob), blob)
+	if expected := []string{dgst.String() + " bytes=25-"}; !reflect.DeepEqual(reg.blobRequests, expected) {
+		t.Fatalf("expected blob requests %v, got %v", expected, reg.blobRequests)
+	}
+	if _, err := os.Stat(partial + partsStateSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected the download state to be removed, got %v", err)
+	}
+}
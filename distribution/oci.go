@@ -0,0 +1,277 @@
+package distribution
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func init() {
+	ociManifestFunc := func(b []byte) (distribution.Manifest, distribution.Descriptor, error) {
+		m := new(ociManifest)
+		if err := m.UnmarshalJSON(b); err != nil {
+			return nil, distribution.Descriptor{}, err
+		}
+		return m, distribution.Descriptor{Digest: digest.FromBytes(b), Size: int64(len(b)), MediaType: ocispec.MediaTypeImageManifest}, nil
+	}
+	if err := distribution.RegisterManifestSchema(ocispec.MediaTypeImageManifest, ociManifestFunc); err != nil {
+		panic(fmt.Sprintf("Unable to register OCI image manifest: %s", err))
+	}
+
+	ociIndexFunc := func(b []byte) (distribution.Manifest, distribution.Descriptor, error) {
+		m := new(ociIndex)
+		if err := m.UnmarshalJSON(b); err != nil {
+			return nil, distribution.Descriptor{}, err
+		}
+		return m, distribution.Descriptor{Digest: digest.FromBytes(b), Size: int64(len(b)), MediaType: ocispec.MediaTypeImageIndex}, nil
+	}
+	if err := distribution.RegisterManifestSchema(ocispec.MediaTypeImageIndex, ociIndexFunc); err != nil {
+		panic(fmt.Sprintf("Unable to register OCI image index: %s", err))
+	}
+}
+
+// ociLayerMediaTypes maps the media types of the layers pushed to their
+// OCI equivalent
+var ociLayerMediaTypes = map[string]string{
+	schema2.MediaTypeLayer:                          ocispec.MediaTypeImageLayerGzip,
+	schema2.MediaTypeUncompressedLayer:              ocispec.MediaTypeImageLayer,
+	schema2.MediaTypeForeignLayer:                   ocispec.MediaTypeImageLayerNonDistributableGzip,
+	ocispec.MediaTypeImageLayerNonDistributable:     ocispec.MediaTypeImageLayerNonDistributable,
+	ocispec.MediaTypeImageLayerNonDistributableGzip: ocispec.MediaTypeImageLayerNonDistributableGzip,
+}
+
+// isForeignLayer returns whether mediaType is the media type of a layer that
+// may be downloaded from its URLs instead of the registry
+func isForeignLayer(mediaType string) bool {
+	switch mediaType {
+	case schema2.MediaTypeForeignLayer, ocispec.MediaTypeImageLayerNonDistributable, ocispec.MediaTypeImageLayerNonDistributableGzip:
+		return true
+	}
+	return false
+}
+
+// ociManifest is an OCI image manifest, keeping the canonical
+// representation it was received or built with.
+type ociManifest struct {
+	ocispec.Manifest
+
+	canonical []byte
+}
+
+// UnmarshalJSON populates a new manifest from its canonical representation.
+func (m *ociManifest) UnmarshalJSON(b []byte) error {
+	var mfst ocispec.Manifest
+	if err := json.Unmarshal(b, &mfst); err != nil {
+		return err
+	}
+	if mfst.SchemaVersion != 2 {
+		return fmt.Errorf("unsupported OCI image manifest schema version %d", mfst.SchemaVersion)
+	}
+	m.Manifest = mfst
+	m.canonical = make([]byte, len(b))
+	copy(m.canonical, b)
+	return nil
+}
+
+// MarshalJSON returns the canonical representation of the manifest.
+func (m *ociManifest) MarshalJSON() ([]byte, error) {
+	if len(m.canonical) > 0 {
+		return m.canonical, nil
+	}
+	return nil, errors.New("JSON representation not initialized in OCI image manifest")
+}
+
+// Payload returns the media type and the canonical representation of the
+// manifest.
+func (m *ociManifest) Payload() (string, []byte, error) {
+	return ocispec.MediaTypeImageManifest, m.canonical, nil
+}
+
+// References returns the descriptors of the config and the layers of the
+// image.
+func (m *ociManifest) References() []distribution.Descriptor {
+	return append([]distribution.Descriptor{m.Target()}, m.layers()...)
+}
+
+// Target returns the descriptor of the image config.
+func (m *ociManifest) Target() distribution.Descriptor {
+	return fromOCIDescriptor(m.Config)
+}
+
+func (m *ociManifest) layers() []distribution.Descriptor {
+	layers := make([]distribution.Descriptor, len(m.Layers))
+	for i, l := range m.Layers {
+		layers[i] = fromOCIDescriptor(l)
+	}
+	return layers
+}
+
+// ociIndex is an OCI image index, keeping the canonical representation it
+// was received with.
+type ociIndex struct {
+	ocispec.Index
+
+	canonical []byte
+}
+
+// UnmarshalJSON populates a new index from its canonical representation.
+func (m *ociIndex) UnmarshalJSON(b []byte) error {
+	var index ocispec.Index
+	if err := json.Unmarshal(b, &index); err != nil {
+		return err
+	}
+	if index.SchemaVersion != 2 {
+		return fmt.Errorf("unsupported OCI image index schema version %d", index.SchemaVersion)
+	}
+	m.Index = index
+	m.canonical = make([]byte, len(b))
+	copy(m.canonical, b)
+	return nil
+}
+
+// MarshalJSON returns the canonical representation of the index.
+func (m *ociIndex) MarshalJSON() ([]byte, error) {
+	if len(m.canonical) > 0 {
+		return m.canonical, nil
+	}
+	return nil, errors.New("JSON representation not initialized in OCI image index")
+}
+
+// Payload returns the media type and the canonical representation of the
+// index.
+func (m *ociIndex) Payload() (string, []byte, error) {
+	return ocispec.MediaTypeImageIndex, m.canonical, nil
+}
+
+// References returns the descriptors of the manifests of the index.
+func (m *ociIndex) References() []distribution.Descriptor {
+	references := make([]distribution.Descriptor, len(m.Manifests))
+	for i, d := range m.Manifests {
+		references[i] = fromOCIDescriptor(d)
+	}
+	return references
+}
+
+// manifestDescriptors returns the manifests of the index as the entries of a
+// manifest list, so that they are filtered the same way. The platform of
+// manifests is optional in an index, the manifests without one have an empty
+// platform, see manifestsWithoutPlatform.
+func (m *ociIndex) manifestDescriptors() []manifestlist.ManifestDescriptor {
+	manifests := make([]manifestlist.ManifestDescriptor, len(m.Manifests))
+	for i, d := range m.Manifests {
+		manifests[i].Descriptor = fromOCIDescriptor(d)
+		if p := d.Platform; p != nil {
+			manifests[i].Platform = manifestlist.PlatformSpec{
+				Architecture: p.Architecture,
+				OS:           p.OS,
+				OSVersion:    p.OSVersion,
+				OSFeatures:   p.OSFeatures,
+				Variant:      p.Variant,
+			}
+		}
+	}
+	return manifests
+}
+
+// manifestsWithoutPlatform returns the manifests with an empty platform. They
+// match any platform, after the manifests of the platform.
+func manifestsWithoutPlatform(manifests []manifestlist.ManifestDescriptor) []manifestlist.ManifestDescriptor {
+	var matches []manifestlist.ManifestDescriptor
+	for _, m := range manifests {
+		if m.Platform.Architecture == "" && m.Platform.OS == "" {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func fromOCIDescriptor(d ocispec.Descriptor) distribution.Descriptor {
+	return distribution.Descriptor{
+		MediaType: d.MediaType,
+		Size:      d.Size,
+		Digest:    d.Digest,
+		URLs:      d.URLs,
+	}
+}
+
+// ociManifestBuilder builds OCI image manifests, publishing the image
+// config to its blob service.
+type ociManifestBuilder struct {
+	bs           distribution.BlobService
+	configJSON   []byte
+	dependencies []distribution.Descriptor
+}
+
+func newOCIManifestBuilder(bs distribution.BlobService, configJSON []byte) distribution.ManifestBuilder {
+	return &ociManifestBuilder{
+		bs:         bs,
+		configJSON: configJSON,
+	}
+}
+
+// Build produces the manifest from the layers appended, after making sure
+// that the config is in the blob service.
+func (mb *ociManifestBuilder) Build(ctx context.Context) (distribution.Manifest, error) {
+	config, err := mb.bs.Stat(ctx, digest.FromBytes(mb.configJSON))
+	switch err {
+	case nil:
+	case distribution.ErrBlobUnknown:
+		if config, err = mb.bs.Put(ctx, ocispec.MediaTypeImageConfig, mb.configJSON); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	mfst := ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config: ocispec.Descriptor{
+			// Stat and Put don't return the media type of the config
+			MediaType: ocispec.MediaTypeImageConfig,
+			Digest:    config.Digest,
+			Size:      config.Size,
+		},
+		Layers: make([]ocispec.Descriptor, len(mb.dependencies)),
+	}
+	for i, d := range mb.dependencies {
+		mediaType, ok := ociLayerMediaTypes[d.MediaType]
+		if !ok {
+			return nil, fmt.Errorf("unsupported layer media type %s", d.MediaType)
+		}
+		mfst.Layers[i] = ocispec.Descriptor{
+			MediaType: mediaType,
+			Digest:    d.Digest,
+			Size:      d.Size,
+			URLs:      d.URLs,
+		}
+	}
+
+	canonical, err := json.MarshalIndent(mfst, "", "   ")
+	if err != nil {
+		return nil, err
+	}
+	m := new(ociManifest)
+	if err := m.UnmarshalJSON(canonical); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AppendReference adds a layer to the manifest.
+func (mb *ociManifestBuilder) AppendReference(d distribution.Describable) error {
+	mb.dependencies = append(mb.dependencies, d.Descriptor())
+	return nil
+}
+
+// References returns the layers appended to the manifest.
+func (mb *ociManifestBuilder) References() []distribution.Descriptor {
+	return mb.dependencies
+}
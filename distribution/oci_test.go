@@ -0,0 +1,491 @@
+package distribution
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	registrytypes "github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/distribution/xfer"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/progress"
+	refstore "github.com/docker/docker/reference"
+	"github.com/docker/docker/registry"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/net/context"
+)
+
+type testManifest struct {
+	mediaType string
+	payload   []byte
+}
+
+// testRegistry is an in-process registry serving the manifests and blobs of
+// a single repository
+type testRegistry struct {
+	mu        sync.Mutex
+	manifests map[string]testManifest
+	blobs     map[digest.Digest][]byte
+	uploads   map[string][]byte
+	// blobRequests are the digests and ranges of the blobs fetched
+	blobRequests []string
+}
+
+func newTestRegistry() *testRegistry {
+	return &testRegistry{
+		manifests: map[string]testManifest{},
+		blobs:     map[digest.Digest][]byte{},
+		uploads:   map[string][]byte{},
+	}
+}
+
+func (reg *testRegistry) putBlob(b []byte) ocispec.Descriptor {
+	dgst := digest.FromBytes(b)
+	reg.blobs[dgst] = b
+	return ocispec.Descriptor{Digest: dgst, Size: int64(len(b))}
+}
+
+func (reg *testRegistry) putManifest(t *testing.T, tag, mediaType string, m interface{}) ocispec.Descriptor {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := ocispec.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(payload), Size: int64(len(payload))}
+	reg.manifests[desc.Digest.String()] = testManifest{mediaType, payload}
+	if tag != "" {
+		reg.manifests[tag] = testManifest{mediaType, payload}
+	}
+	return desc
+}
+
+func (reg *testRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	p := r.URL.Path
+	switch {
+	case p == "/v2/":
+	case strings.Contains(p, "/manifests/"):
+		ref := p[strings.LastIndex(p, "/manifests/")+len("/manifests/"):]
+		if r.Method == "PUT" {
+			payload, _ := ioutil.ReadAll(r.Body)
+			dgst := digest.FromBytes(payload)
+			m := testManifest{r.Header.Get("Content-Type"), payload}
+			reg.manifests[ref] = m
+			reg.manifests[dgst.String()] = m
+			w.Header().Set("Docker-Content-Digest", dgst.String())
+			w.Header().Set("Location", p)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		m, ok := reg.manifests[ref]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", m.mediaType)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(m.payload).String())
+		w.Header().Set("Content-Length", fmt.Sprint(len(m.payload)))
+		if r.Method == "GET" {
+			w.Write(m.payload)
+		}
+	case strings.Contains(p, "/blobs/uploads/"):
+		i := strings.LastIndex(p, "/blobs/uploads/") + len("/blobs/uploads/")
+		id := p[i:]
+		if r.Method == "POST" {
+			id = fmt.Sprint(len(reg.uploads) + 1)
+			reg.uploads[id] = nil
+			w.Header().Set("Location", p[:i]+id)
+			w.Header().Set("Docker-Upload-UUID", id)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		b, ok := reg.uploads[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		b = append(b, data...)
+		reg.uploads[id] = b
+		if r.Method == "PATCH" {
+			w.Header().Set("Location", p)
+			w.Header().Set("Docker-Upload-UUID", id)
+			w.Header().Set("Range", fmt.Sprintf("0-%d", len(b)-1))
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		dgst := digest.Digest(r.URL.Query().Get("digest"))
+		if dgst != digest.FromBytes(b) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		delete(reg.uploads, id)
+		reg.blobs[dgst] = b
+		w.Header().Set("Location", p[:strings.LastIndex(p, "/uploads/")+1]+dgst.String())
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(p, "/blobs/"):
+		dgst := digest.Digest(p[strings.LastIndex(p, "/blobs/")+len("/blobs/"):])
+		b, ok := reg.blobs[dgst]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == "GET" {
+			reg.blobRequests = append(reg.blobRequests, strings.TrimSpace(dgst.String()+" "+r.Header.Get("Range")))
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testRepository(t *testing.T, ts *httptest.Server) (registry.APIEndpoint, *registry.RepositoryInfo) {
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("could not parse url from test server: %v", err)
+	}
+	n, _ := reference.ParseNormalizedNamed("testremotename")
+	endpoint := registry.APIEndpoint{
+		URL:          uri,
+		Version:      2,
+		TrimHostname: true,
+	}
+	repoInfo := &registry.RepositoryInfo{
+		Name:  n,
+		Index: &registrytypes.IndexInfo{Name: "testrepo"},
+	}
+	return endpoint, repoInfo
+}
+
+type testImageConfigStore map[digest.Digest][]byte
+
+func (s testImageConfigStore) Put(c []byte) (digest.Digest, error) {
+	dgst := digest.FromBytes(c)
+	s[dgst] = c
+	return dgst, nil
+}
+
+func (s testImageConfigStore) Get(d digest.Digest) ([]byte, error) {
+	c, ok := s[d]
+	if !ok {
+		return nil, fmt.Errorf("image %s not found", d)
+	}
+	return c, nil
+}
+
+func (s testImageConfigStore) RootFSAndOSFromConfig(c []byte) (*image.RootFS, layer.OS, error) {
+	return (&imageConfigStore{}).RootFSAndOSFromConfig(c)
+}
+
+// testDownloadManager downloads the layers and uses the digest of their
+// uncompressed content as their DiffID
+type testDownloadManager struct{}
+
+func (testDownloadManager) Download(ctx context.Context, rootFS image.RootFS, os layer.OS, layers []xfer.DownloadDescriptor, progressOutput progress.Output) (image.RootFS, func(), error) {
+	for _, l := range layers {
+		rc, _, err := l.Download(ctx, progressOutput)
+		if err != nil {
+			return rootFS, nil, err
+		}
+		data, err := archive.DecompressStream(rc)
+		if err != nil {
+			rc.Close()
+			return rootFS, nil, err
+		}
+		dgst, err := digest.FromReader(data)
+		data.Close()
+		rc.Close()
+		l.Close()
+		if err != nil {
+			return rootFS, nil, err
+		}
+		rootFS.Append(layer.DiffID(dgst))
+	}
+	return rootFS, func() {}, nil
+}
+
+type testPushLayer struct {
+	data []byte
+}
+
+func (l *testPushLayer) ChainID() layer.ChainID {
+	return layer.CreateChainID([]layer.DiffID{l.DiffID()})
+}
+
+func (l *testPushLayer) DiffID() layer.DiffID {
+	return layer.DiffID(digest.FromBytes(l.data))
+}
+
+func (l *testPushLayer) Parent() PushLayer {
+	return nil
+}
+
+func (l *testPushLayer) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.data)), nil
+}
+
+func (l *testPushLayer) Size() (int64, error) {
+	return int64(len(l.data)), nil
+}
+
+func (l *testPushLayer) MediaType() string {
+	return schema2.MediaTypeUncompressedLayer
+}
+
+func (l *testPushLayer) Release() {}
+
+type testLayerProvider map[layer.ChainID]PushLayer
+
+func (p testLayerProvider) Get(id layer.ChainID) (PushLayer, error) {
+	l, ok := p[id]
+	if !ok {
+		return nil, fmt.Errorf("layer %s not found", id)
+	}
+	return l, nil
+}
+
+func testImageConfig(t *testing.T, arch string, layers ...[]byte) []byte {
+	img := image.Image{RootFS: image.NewRootFS()}
+	img.Architecture = arch
+	img.OS = runtime.GOOS
+	for _, l := range layers {
+		img.RootFS.Append(layer.DiffID(digest.FromBytes(l)))
+	}
+	config, err := json.Marshal(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func testPull(t *testing.T, ts *httptest.Server, tag string, imageStore ImageConfigStore) error {
+	endpoint, repoInfo := testRepository(t, ts)
+	puller, err := newPuller(endpoint, repoInfo, &ImagePullConfig{
+		Config: Config{
+			MetaHeaders:    http.Header{},
+			AuthConfig:     &types.AuthConfig{},
+			ProgressOutput: &progressSink{t},
+			ImageStore:     imageStore,
+		},
+		DownloadManager: testDownloadManager{},
+		Schema2Types:    ImageTypes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := reference.WithTag(repoInfo.Name, tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return puller.Pull(context.Background(), ref, runtime.GOOS)
+}
+
+func TestPullOCIIndex(t *testing.T) {
+	reg := newTestRegistry()
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+
+	var descriptors []ocispec.Descriptor
+	for _, arch := range []string{"otherarch", runtime.GOARCH} {
+		layerData := []byte("layer of " + arch)
+		config := testImageConfig(t, arch, layerData)
+		configDesc := reg.putBlob(config)
+		configDesc.MediaType = ocispec.MediaTypeImageConfig
+		layerDesc := reg.putBlob(layerData)
+		layerDesc.MediaType = ocispec.MediaTypeImageLayer
+		desc := reg.putManifest(t, arch, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			Config:    configDesc,
+			Layers:    []ocispec.Descriptor{layerDesc},
+		})
+		desc.Platform = &ocispec.Platform{Architecture: arch, OS: runtime.GOOS}
+		descriptors = append(descriptors, desc)
+	}
+	reg.putManifest(t, "latest", ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Manifests: descriptors,
+	})
+	reg.putManifest(t, "other", ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Manifests: descriptors[:1],
+	})
+	// the platform is optional, manifests without one match after the
+	// manifests of the platform
+	otherWithoutPlatform := descriptors[0]
+	otherWithoutPlatform.Platform = nil
+	withoutPlatform := descriptors[1]
+	withoutPlatform.Platform = nil
+	reg.putManifest(t, "preferplatform", ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Manifests: []ocispec.Descriptor{otherWithoutPlatform, descriptors[1]},
+	})
+	reg.putManifest(t, "noplatform", ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Manifests: []ocispec.Descriptor{descriptors[0], withoutPlatform},
+	})
+
+	imageStore := testImageConfigStore{}
+	if err := testPull(t, ts, "latest", imageStore); err != nil {
+		t.Fatal(err)
+	}
+	if len(imageStore) != 1 {
+		t.Fatalf("expected 1 image to be pulled, got %d", len(imageStore))
+	}
+	if _, ok := imageStore[digest.FromBytes(testImageConfig(t, runtime.GOARCH, []byte("layer of "+runtime.GOARCH)))]; !ok {
+		t.Fatalf("expected the image of %s to be pulled", runtime.GOARCH)
+	}
+
+	for _, tag := range []string{"preferplatform", "noplatform"} {
+		imageStore := testImageConfigStore{}
+		if err := testPull(t, ts, tag, imageStore); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := imageStore[digest.FromBytes(testImageConfig(t, runtime.GOARCH, []byte("layer of "+runtime.GOARCH)))]; !ok || len(imageStore) != 1 {
+			t.Fatalf("expected the image of %s to be pulled from %s", runtime.GOARCH, tag)
+		}
+	}
+
+	err := testPull(t, ts, "other", testImageConfigStore{})
+	if err == nil || !strings.Contains(err.Error(), "no matching manifest") {
+		t.Fatalf("expected no matching manifest error, got %v", err)
+	}
+}
+
+func TestPullOCIManifestPluginConfig(t *testing.T) {
+	reg := newTestRegistry()
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+
+	configDesc := reg.putBlob([]byte("{}"))
+	configDesc.MediaType = schema2.MediaTypePluginConfig
+	reg.putManifest(t, "latest", ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    configDesc,
+	})
+
+	err := testPull(t, ts, "latest", testImageConfigStore{})
+	if fallbackErr, ok := err.(fallbackError); ok {
+		err = fallbackErr.err
+	}
+	if _, ok := err.(invalidManifestClassError); !ok {
+		t.Fatalf("expected invalid manifest class error, got %v", err)
+	}
+}
+
+func TestPushOCIManifest(t *testing.T) {
+	reg := newTestRegistry()
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+
+	tmpDir, err := ioutil.TempDir("", "distribution-oci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	l := &testPushLayer{data: []byte("layer")}
+	config := testImageConfig(t, runtime.GOARCH, l.data)
+	imageStore := testImageConfigStore{}
+	id, _ := imageStore.Put(config)
+
+	endpoint, repoInfo := testRepository(t, ts)
+	ref, err := reference.WithTag(repoInfo.Name, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	referenceStore, err := refstore.NewReferenceStore(filepath.Join(tmpDir, "repositories.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := referenceStore.AddTag(ref, id, false); err != nil {
+		t.Fatal(err)
+	}
+	metadataStore, err := metadata.NewFSMetadataStore(filepath.Join(tmpDir, "metadata"), runtime.GOOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pusher, err := NewPusher(ref, endpoint, repoInfo, &ImagePushConfig{
+		Config: Config{
+			MetaHeaders:    http.Header{},
+			AuthConfig:     &types.AuthConfig{},
+			ProgressOutput: &progressSink{t},
+			MetadataStore:  metadataStore,
+			ImageStore:     imageStore,
+			ReferenceStore: referenceStore,
+		},
+		ConfigMediaType: schema2.MediaTypeImageConfig,
+		OCIManifest:     true,
+		LayerStore:      testLayerProvider{l.ChainID(): l},
+		UploadManager:   xfer.NewLayerUploadManager(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pusher.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	m, ok := reg.manifests["latest"]
+	if !ok {
+		t.Fatal("expected a manifest to be pushed")
+	}
+	if m.mediaType != ocispec.MediaTypeImageManifest {
+		t.Fatalf("expected media type %s, got %s", ocispec.MediaTypeImageManifest, m.mediaType)
+	}
+	var mfst ocispec.Manifest
+	if err := json.Unmarshal(m.payload, &mfst); err != nil {
+		t.Fatal(err)
+	}
+	if mfst.Config.MediaType != ocispec.MediaTypeImageConfig || mfst.Config.Digest != id {
+		t.Fatalf("unexpected config descriptor %+v", mfst.Config)
+	}
+	if len(mfst.Layers) != 1 || mfst.Layers[0].MediaType != ocispec.MediaTypeImageLayerGzip {
+		t.Fatalf("unexpected layer descriptors %+v", mfst.Layers)
+	}
+	if _, ok := reg.blobs[mfst.Layers[0].Digest]; !ok {
+		t.Fatal("expected the layer to be pushed")
+	}
+	dgstRef, err := reference.WithDigest(repoInfo.Name, digest.FromBytes(m.payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dgstID, err := referenceStore.Get(dgstRef); err != nil || dgstID != id {
+		t.Fatalf("expected the manifest digest to reference the image, got %v, %v", dgstID, err)
+	}
+
+	pulled := testImageConfigStore{}
+	if err := testPull(t, ts, "latest", pulled); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pulled[id]; !ok {
+		t.Fatal("expected the pushed image to be pulled")
+	}
+}
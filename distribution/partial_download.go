@@ -0,0 +1,171 @@
+package distribution
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/pkg/ioutils"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	partialDownloadSuffix = ".partial"
+	// partsStateSuffix is the suffix of the file next to a partial file
+	// written by concurrent range requests, see savePartsState
+	partsStateSuffix = ".parts"
+)
+
+// activePartialDownloads is the set of the partial files that are being
+// written to, which are not removed by PrunePartialDownloads
+var activePartialDownloads = struct {
+	sync.Mutex
+	files map[string]struct{}
+}{files: make(map[string]struct{})}
+
+func partialDownloadPath(dir string, dgst digest.Digest) string {
+	return filepath.Join(dir, string(dgst.Algorithm())+"-"+dgst.Hex()+partialDownloadSuffix)
+}
+
+// openPartialDownload opens the partial file of the blob dgst in dir, with
+// the content downloaded by the previous attempts, even by another daemon
+// process. A temporary file is returned instead if the partial file is
+// already being written to.
+func openPartialDownload(dir string, dgst digest.Digest) (*os.File, error) {
+	if err := dgst.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	p := partialDownloadPath(dir, dgst)
+
+	activePartialDownloads.Lock()
+	defer activePartialDownloads.Unlock()
+	if _, ok := activePartialDownloads.files[p]; ok {
+		logrus.Debugf("%s is already being downloaded to %s, using a temporary file", dgst, p)
+		return createDownloadFile()
+	}
+	f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := restorePartsState(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	activePartialDownloads.files[p] = struct{}{}
+	return f, nil
+}
+
+// savePartsState records that the first n bytes of the partial file f are
+// downloaded, while the rest of f is written by concurrent range requests
+// and may have holes. It does nothing if f is a temporary file.
+func savePartsState(f *os.File, n int64) error {
+	activePartialDownloads.Lock()
+	_, partial := activePartialDownloads.files[f.Name()]
+	activePartialDownloads.Unlock()
+	if !partial {
+		return nil
+	}
+	return ioutils.AtomicWriteFile(f.Name()+partsStateSuffix, []byte(strconv.FormatInt(n, 10)), 0600)
+}
+
+// removePartsState removes the state saved by savePartsState once f has no
+// holes anymore.
+func removePartsState(f *os.File) error {
+	if err := os.Remove(f.Name() + partsStateSuffix); err != nil && !os.IsNotExist(err) {
+		logrus.Errorf("Failed to remove download state: %v", err)
+		return err
+	}
+	return nil
+}
+
+// restorePartsState truncates the partial file f to the content downloaded
+// contiguously from its beginning, if it was being written by concurrent
+// range requests when the daemon stopped, so that the holes left by the
+// parts are not resumed from.
+func restorePartsState(f *os.File) error {
+	b, err := ioutil.ReadFile(f.Name() + partsStateSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	var n int64
+	if err == nil {
+		n, _ = strconv.ParseInt(string(b), 10, 64)
+	}
+	if fi, err := f.Stat(); err == nil && n > fi.Size() {
+		n = fi.Size()
+	}
+	logrus.Debugf("truncating the download %s written in parts to %d bytes", f.Name(), n)
+	if err := f.Truncate(n); err != nil {
+		return err
+	}
+	return removePartsState(f)
+}
+
+// closeDownloadFile closes f, a temporary or a partial download file. The
+// file is removed if remove is true or if it's a temporary file, so that
+// the partial files are kept for the next attempts.
+func closeDownloadFile(f *os.File, remove bool) error {
+	f.Close()
+
+	activePartialDownloads.Lock()
+	_, partial := activePartialDownloads.files[f.Name()]
+	delete(activePartialDownloads.files, f.Name())
+	activePartialDownloads.Unlock()
+
+	if partial && !remove {
+		return nil
+	}
+	if err := os.RemoveAll(f.Name()); err != nil {
+		logrus.Errorf("Failed to remove download file: %s", f.Name())
+		return err
+	}
+	return nil
+}
+
+// PrunePartialDownloads removes the partial files of the layer downloads
+// that were interrupted from dir, and returns the space reclaimed.
+func PrunePartialDownloads(dir string) (uint64, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+partialDownloadSuffix))
+	if err != nil {
+		return 0, err
+	}
+	states, err := filepath.Glob(filepath.Join(dir, "*"+partialDownloadSuffix+partsStateSuffix))
+	if err != nil {
+		return 0, err
+	}
+
+	activePartialDownloads.Lock()
+	defer activePartialDownloads.Unlock()
+
+	var reclaimed uint64
+	for _, p := range files {
+		if _, ok := activePartialDownloads.files[p]; ok {
+			continue
+		}
+		fi, err := os.Lstat(p)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		if err := os.Remove(p); err != nil {
+			logrus.Warnf("could not remove partial download %s: %v", p, err)
+			continue
+		}
+		reclaimed += uint64(fi.Size())
+	}
+	for _, p := range states {
+		if _, ok := activePartialDownloads.files[strings.TrimSuffix(p, partsStateSuffix)]; ok {
+			continue
+		}
+		if err := os.Remove(p); err != nil {
+			logrus.Warnf("could not remove download state %s: %v", p, err)
+		}
+	}
+	return reclaimed, nil
+}
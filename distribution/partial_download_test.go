@@ -0,0 +1,159 @@
+package distribution
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/progress"
+	digest "github.com/opencontainers/go-digest"
+	"golang.org/x/net/context"
+)
+
+func testPartialLayerDescriptor(t *testing.T, ts *httptest.Server, downloadDir string, blob []byte) *v2LayerDescriptor {
+	endpoint, repoInfo := testRepository(t, ts)
+	repo, _, err := NewV2Repository(context.Background(), repoInfo, endpoint, http.Header{}, &types.AuthConfig{}, "pull")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dgst := digest.FromBytes(blob)
+	return &v2LayerDescriptor{
+		digest:      dgst,
+		repo:        repo,
+		repoInfo:    repoInfo,
+		downloadDir: downloadDir,
+		src:         distribution.Descriptor{Digest: dgst, Size: int64(len(blob))},
+	}
+}
+
+func testDownload(t *testing.T, ld *v2LayerDescriptor, blob []byte) {
+	rc, _, err := ld.Download(context.Background(), progress.DiscardOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, blob) {
+		t.Fatalf("unexpected content downloaded: %q", data)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ld.Close()
+}
+
+func TestResumePartialDownload(t *testing.T) {
+	reg := newTestRegistry()
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+
+	tmpDir, err := ioutil.TempDir("", "partial-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	blob := []byte("layer content resumed from a partial download")
+	reg.putBlob(blob)
+	dgst := digest.FromBytes(blob)
+	partial := partialDownloadPath(tmpDir, dgst)
+	if err := ioutil.WriteFile(partial, blob[:10], 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testDownload(t, testPartialLayerDescriptor(t, ts, tmpDir, blob), blob)
+	if expected := []string{dgst.String() + " bytes=10-"}; !reflect.DeepEqual(reg.blobRequests, expected) {
+		t.Fatalf("expected blob requests %v, got %v", expected, reg.blobRequests)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Fatalf("expected the partial download to be removed once complete, got %v", err)
+	}
+
+	// a complete partial download is only verified
+	reg.blobRequests = nil
+	if err := ioutil.WriteFile(partial, blob, 0600); err != nil {
+		t.Fatal(err)
+	}
+	testDownload(t, testPartialLayerDescriptor(t, ts, tmpDir, blob), blob)
+	if len(reg.blobRequests) != 0 {
+		t.Fatalf("expected no blob request, got %v", reg.blobRequests)
+	}
+
+	// an invalid partial download is downloaded again
+	if err := ioutil.WriteFile(partial, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ld := testPartialLayerDescriptor(t, ts, tmpDir, blob)
+	if _, _, err := ld.Download(context.Background(), progress.DiscardOutput()); err == nil {
+		t.Fatal("expected the resumed download to fail verification")
+	}
+	testDownload(t, ld, blob)
+}
+
+func TestPrunePartialDownloads(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "partial-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	active, err := openPartialDownload(tmpDir, digest.FromString("active"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDownloadFile(active, true)
+	if _, err := active.Write([]byte("active")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a concurrent download of the same blob uses a temporary file
+	tmpFile, err := openPartialDownload(tmpDir, digest.FromString("active"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(tmpFile.Name()) == tmpDir {
+		t.Fatalf("expected a temporary file, got %s", tmpFile.Name())
+	}
+	closeDownloadFile(tmpFile, false)
+	if _, err := os.Stat(tmpFile.Name()); !os.IsNotExist(err) {
+		t.Fatalf("expected the temporary file to be removed, got %v", err)
+	}
+
+	interrupted, err := openPartialDownload(tmpDir, digest.FromString("interrupted"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interrupted.Write([]byte("interrupted")); err != nil {
+		t.Fatal(err)
+	}
+	closeDownloadFile(interrupted, false)
+	if err := ioutil.WriteFile(interrupted.Name()+partsStateSuffix, []byte("0"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	reclaimed, err := PrunePartialDownloads(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclaimed != uint64(len("interrupted")) {
+		t.Fatalf("expected %d bytes to be reclaimed, got %d", len("interrupted"), reclaimed)
+	}
+	if _, err := os.Stat(interrupted.Name()); !os.IsNotExist(err) {
+		t.Fatalf("expected the interrupted download to be removed, got %v", err)
+	}
+	if _, err := os.Stat(interrupted.Name() + partsStateSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected the state of the interrupted download to be removed, got %v", err)
+	}
+	if _, err := os.Stat(active.Name()); err != nil {
+		t.Fatalf("expected the active download to be kept, got %v", err)
+	}
+}
@@ -137,6 +137,8 @@ type v2LayerDescriptor struct {
 	repoInfo          *registry.RepositoryInfo
 	repo              distribution.Repository
 	V2MetadataService metadata.V2MetadataService
+	downloadDir       string
+	maxDownloadParts  int
 	tmpFile           *os.File
 	verifier          digest.Verifier
 	src               distribution.Descriptor
@@ -166,26 +168,45 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 	)
 
 	if ld.tmpFile == nil {
-		ld.tmpFile, err = createDownloadFile()
+		ld.tmpFile, err = ld.openDownloadFile()
 		if err != nil {
 			return nil, 0, xfer.DoNotRetry{Err: err}
 		}
-	} else {
-		offset, err = ld.tmpFile.Seek(0, os.SEEK_END)
+	}
+	offset, err = ld.tmpFile.Seek(0, os.SEEK_END)
+	if err != nil {
+		logrus.Debugf("error seeking to end of download file: %v", err)
+		offset = 0
+
+		closeDownloadFile(ld.tmpFile, true)
+		ld.verifier = nil
+		ld.tmpFile, err = ld.openDownloadFile()
 		if err != nil {
-			logrus.Debugf("error seeking to end of download file: %v", err)
-			offset = 0
+			return nil, 0, xfer.DoNotRetry{Err: err}
+		}
+	} else if offset != 0 {
+		logrus.Debugf("attempting to resume download of %q from %d bytes", ld.digest, offset)
+	}
 
-			ld.tmpFile.Close()
-			if err := os.Remove(ld.tmpFile.Name()); err != nil {
-				logrus.Errorf("Failed to remove temp file: %s", ld.tmpFile.Name())
-			}
-			ld.tmpFile, err = createDownloadFile()
-			if err != nil {
+	if ld.verifier == nil && offset != 0 {
+		// The content resumed from was downloaded by a previous pull.
+		if err := ld.hashDownloadFile(offset); err != nil {
+			if err := ld.truncateDownloadFile(); err != nil {
 				return nil, 0, xfer.DoNotRetry{Err: err}
 			}
-		} else if offset != 0 {
-			logrus.Debugf("attempting to resume download of %q from %d bytes", ld.digest, offset)
+			return nil, 0, err
+		}
+	}
+
+	if offset != 0 && offset == ld.src.Size {
+		// The partial file already has the whole blob, which only needs
+		// to be verified.
+		return ld.verifyDownload(progressOutput, offset, offset)
+	}
+
+	if offset == 0 {
+		if parts := downloadParts(ld.src.Size, ld.maxDownloadParts); parts > 1 {
+			return ld.downloadInParts(ctx, progressOutput, parts)
 		}
 	}
 
@@ -248,10 +269,18 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 		return nil, 0, retryOnError(err)
 	}
 
+	return ld.verifyDownload(progressOutput, offset, size)
+}
+
+// verifyDownload verifies the content of the download file, resumed from
+// offset, and hands it off to the download manager.
+func (ld *v2LayerDescriptor) verifyDownload(progressOutput progress.Output, offset, size int64) (io.ReadCloser, int64, error) {
+	tmpFile := ld.tmpFile
+
 	progress.Update(progressOutput, ld.ID(), "Verifying Checksum")
 
 	if !ld.verifier.Verified() {
-		err = fmt.Errorf("filesystem layer verification failed for digest %s", ld.digest)
+		err := fmt.Errorf("filesystem layer verification failed for digest %s", ld.digest)
 		logrus.Error(err)
 
 		// Allow a retry if this digest verification error happened
@@ -263,6 +292,8 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 
 			return nil, 0, err
 		}
+		// Don't resume from the invalid content next time.
+		ld.truncateDownloadFile()
 		return nil, 0, xfer.DoNotRetry{Err: err}
 	}
 
@@ -270,12 +301,8 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 
 	logrus.Debugf("Downloaded %s to tempfile %s", ld.ID(), tmpFile.Name())
 
-	_, err = tmpFile.Seek(0, os.SEEK_SET)
-	if err != nil {
-		tmpFile.Close()
-		if err := os.Remove(tmpFile.Name()); err != nil {
-			logrus.Errorf("Failed to remove temp file: %s", tmpFile.Name())
-		}
+	if _, err := tmpFile.Seek(0, os.SEEK_SET); err != nil {
+		closeDownloadFile(tmpFile, true)
 		ld.tmpFile = nil
 		ld.verifier = nil
 		return nil, 0, xfer.DoNotRetry{Err: err}
@@ -286,22 +313,38 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 	ld.tmpFile = nil
 
 	return ioutils.NewReadCloserWrapper(tmpFile, func() error {
-		tmpFile.Close()
-		err := os.RemoveAll(tmpFile.Name())
-		if err != nil {
-			logrus.Errorf("Failed to remove temp file: %s", tmpFile.Name())
-		}
-		return err
+		return closeDownloadFile(tmpFile, true)
 	}), size, nil
 }
 
+// Close closes the download file, keeping it if it's a partial file so
+// that the download is resumed by the next pull.
 func (ld *v2LayerDescriptor) Close() {
 	if ld.tmpFile != nil {
-		ld.tmpFile.Close()
-		if err := os.RemoveAll(ld.tmpFile.Name()); err != nil {
-			logrus.Errorf("Failed to remove temp file: %s", ld.tmpFile.Name())
-		}
+		closeDownloadFile(ld.tmpFile, false)
+	}
+}
+
+// openDownloadFile opens the partial file of the layer in the download
+// directory, or a temporary file if there is no download directory.
+func (ld *v2LayerDescriptor) openDownloadFile() (*os.File, error) {
+	if ld.downloadDir == "" {
+		return createDownloadFile()
 	}
+	return openPartialDownload(ld.downloadDir, ld.digest)
+}
+
+// hashDownloadFile creates the verifier of the download with the first
+// offset bytes of the download file, leaving the file at offset.
+func (ld *v2LayerDescriptor) hashDownloadFile(offset int64) error {
+	ld.verifier = ld.digest.Verifier()
+	if _, err := ld.tmpFile.Seek(0, os.SEEK_SET); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(ld.verifier, ld.tmpFile, offset); err != nil {
+		return err
+	}
+	return nil
 }
 
 func (ld *v2LayerDescriptor) truncateDownloadFile() error {
@@ -356,21 +399,14 @@ func (p *v2Puller) pullV2Tag(ctx context.Context, ref reference.Named, os string
 		return false, fmt.Errorf("image manifest does not exist for tag or digest %q", tagOrDigest)
 	}
 
-	if m, ok := manifest.(*schema2.DeserializedManifest); ok {
-		var allowedMediatype bool
-		for _, t := range p.config.Schema2Types {
-			if m.Manifest.Config.MediaType == t {
-				allowedMediatype = true
-				break
-			}
-		}
-		if !allowedMediatype {
-			configClass := mediaTypeClasses[m.Manifest.Config.MediaType]
-			if configClass == "" {
-				configClass = "unknown"
-			}
-			return false, invalidManifestClassError{m.Manifest.Config.MediaType, configClass}
-		}
+	switch m := manifest.(type) {
+	case *schema2.DeserializedManifest:
+		err = p.checkConfigMediaType(m.Manifest.Config.MediaType)
+	case *ociManifest:
+		err = p.checkConfigMediaType(m.Config.MediaType)
+	}
+	if err != nil {
+		return false, err
 	}
 
 	// If manSvc.Get succeeded, we can be confident that the registry on
@@ -399,8 +435,18 @@ func (p *v2Puller) pullV2Tag(ctx context.Context, ref reference.Named, os string
 		if err != nil {
 			return false, err
 		}
+	case *ociManifest:
+		id, manifestDigest, err = p.pullOCI(ctx, ref, v, os)
+		if err != nil {
+			return false, err
+		}
 	case *manifestlist.DeserializedManifestList:
-		id, manifestDigest, err = p.pullManifestList(ctx, ref, v, os)
+		id, manifestDigest, err = p.pullManifestList(ctx, ref, v, v.Manifests, os)
+		if err != nil {
+			return false, err
+		}
+	case *ociIndex:
+		id, manifestDigest, err = p.pullManifestList(ctx, ref, v, v.manifestDescriptors(), os)
 		if err != nil {
 			return false, err
 		}
@@ -436,6 +482,21 @@ func (p *v2Puller) pullV2Tag(ctx context.Context, ref reference.Named, os string
 	return true, nil
 }
 
+// checkConfigMediaType checks that the config of a manifest is of a type
+// allowed by the pull, such as an image and not a plugin.
+func (p *v2Puller) checkConfigMediaType(mediaType string) error {
+	for _, t := range p.config.Schema2Types {
+		if mediaType == t {
+			return nil
+		}
+	}
+	configClass := mediaTypeClasses[mediaType]
+	if configClass == "" {
+		configClass = "unknown"
+	}
+	return invalidManifestClassError{mediaType, configClass}
+}
+
 func (p *v2Puller) pullSchema1(ctx context.Context, ref reference.Reference, unverifiedManifest *schema1.SignedManifest, requestedOS string) (id digest.Digest, manifestDigest digest.Digest, err error) {
 	var verifiedManifest *schema1.Manifest
 	verifiedManifest, err = verifySchema1Manifest(unverifiedManifest, ref)
@@ -483,6 +544,8 @@ func (p *v2Puller) pullSchema1(ctx context.Context, ref reference.Reference, unv
 			repoInfo:          p.repoInfo,
 			repo:              p.repo,
 			V2MetadataService: p.V2MetadataService,
+			downloadDir:       p.config.DownloadDir,
+			maxDownloadParts:  p.config.MaxDownloadParts,
 		}
 
 		descriptors = append(descriptors, layerDescriptor)
@@ -536,12 +599,21 @@ func (p *v2Puller) pullSchema1(ctx context.Context, ref reference.Reference, unv
 }
 
 func (p *v2Puller) pullSchema2(ctx context.Context, ref reference.Named, mfst *schema2.DeserializedManifest, requestedOS string) (id digest.Digest, manifestDigest digest.Digest, err error) {
+	return p.pullImageManifest(ctx, ref, mfst, mfst.Target(), mfst.Layers, requestedOS)
+}
+
+func (p *v2Puller) pullOCI(ctx context.Context, ref reference.Named, mfst *ociManifest, requestedOS string) (id digest.Digest, manifestDigest digest.Digest, err error) {
+	return p.pullImageManifest(ctx, ref, mfst, mfst.Target(), mfst.layers(), requestedOS)
+}
+
+// pullImageManifest pulls the image of a schema2 or OCI manifest, from the
+// descriptors of its config and its layers.
+func (p *v2Puller) pullImageManifest(ctx context.Context, ref reference.Named, mfst distribution.Manifest, target distribution.Descriptor, layers []distribution.Descriptor, requestedOS string) (id digest.Digest, manifestDigest digest.Digest, err error) {
 	manifestDigest, err = schema2ManifestDigest(ref, mfst)
 	if err != nil {
 		return "", "", err
 	}
 
-	target := mfst.Target()
 	if _, err := p.config.ImageStore.Get(target.Digest); err == nil {
 		// If the image already exists locally, no need to pull
 		// anything.
@@ -552,12 +624,14 @@ func (p *v2Puller) pullSchema2(ctx context.Context, ref reference.Named, mfst *s
 
 	// Note that the order of this loop is in the direction of bottom-most
 	// to top-most, so that the downloads slice gets ordered correctly.
-	for _, d := range mfst.Layers {
+	for _, d := range layers {
 		layerDescriptor := &v2LayerDescriptor{
 			digest:            d.Digest,
 			repo:              p.repo,
 			repoInfo:          p.repoInfo,
 			V2MetadataService: p.V2MetadataService,
+			downloadDir:       p.config.DownloadDir,
+			maxDownloadParts:  p.config.MaxDownloadParts,
 			src:               d,
 		}
 
@@ -713,17 +787,20 @@ func receiveConfig(s ImageConfigStore, configChan <-chan []byte, errChan <-chan
 	}
 }
 
-// pullManifestList handles "manifest lists" which point to various
-// platform-specific manifests.
-func (p *v2Puller) pullManifestList(ctx context.Context, ref reference.Named, mfstList *manifestlist.DeserializedManifestList, os string) (id digest.Digest, manifestListDigest digest.Digest, err error) {
+// pullManifestList handles "manifest lists" and OCI image indexes which
+// point to various platform-specific manifests.
+func (p *v2Puller) pullManifestList(ctx context.Context, ref reference.Named, mfstList distribution.Manifest, manifests []manifestlist.ManifestDescriptor, os string) (id digest.Digest, manifestListDigest digest.Digest, err error) {
 	manifestListDigest, err = schema2ManifestDigest(ref, mfstList)
 	if err != nil {
 		return "", "", err
 	}
 
-	logrus.Debugf("%s resolved to a manifestList object with %d entries; looking for a %s/%s match", ref, len(mfstList.Manifests), os, runtime.GOARCH)
+	logrus.Debugf("%s resolved to a manifestList object with %d entries; looking for a %s/%s match", ref, len(manifests), os, runtime.GOARCH)
 
-	manifestMatches := filterManifests(mfstList.Manifests, os)
+	manifestMatches := filterManifests(manifests, os)
+	if len(manifestMatches) == 0 {
+		manifestMatches = manifestsWithoutPlatform(manifests)
+	}
 
 	if len(manifestMatches) == 0 {
 		errMsg := fmt.Sprintf("no matching manifest for %s/%s in the manifest list entries", os, runtime.GOARCH)
@@ -762,6 +839,11 @@ func (p *v2Puller) pullManifestList(ctx context.Context, ref reference.Named, mf
 		if err != nil {
 			return "", "", err
 		}
+	case *ociManifest:
+		id, _, err = p.pullOCI(ctx, manifestRef, v, os)
+		if err != nil {
+			return "", "", err
+		}
 	default:
 		return "", "", errors.New("unsupported manifest format")
 	}
@@ -13,7 +13,6 @@ import (
 	"github.com/docker/distribution"
 	"github.com/docker/distribution/context"
 	"github.com/docker/distribution/manifest/manifestlist"
-	"github.com/docker/distribution/manifest/schema2"
 	"github.com/docker/distribution/registry/client/transport"
 	"github.com/docker/docker/pkg/system"
 	"github.com/sirupsen/logrus"
@@ -22,7 +21,7 @@ import (
 var _ distribution.Describable = &v2LayerDescriptor{}
 
 func (ld *v2LayerDescriptor) Descriptor() distribution.Descriptor {
-	if ld.src.MediaType == schema2.MediaTypeForeignLayer && len(ld.src.URLs) > 0 {
+	if isForeignLayer(ld.src.MediaType) && len(ld.src.URLs) > 0 {
 		return ld.src
 	}
 	return distribution.Descriptor{}
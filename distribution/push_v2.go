@@ -160,8 +160,13 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 		return err
 	}
 
-	// Try schema2 first
-	builder := schema2.NewManifestBuilder(p.repo.Blobs(ctx), p.config.ConfigMediaType, imgConfig)
+	var builder distribution.ManifestBuilder
+	if p.config.OCIManifest {
+		builder = newOCIManifestBuilder(p.repo.Blobs(ctx), imgConfig)
+	} else {
+		// Try schema2 first
+		builder = schema2.NewManifestBuilder(p.repo.Blobs(ctx), p.config.ConfigMediaType, imgConfig)
+	}
 	manifest, err := manifestFromBuilder(ctx, builder, descriptors)
 	if err != nil {
 		return err
@@ -174,6 +179,10 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 
 	putOptions := []distribution.ManifestServiceOption{distribution.WithTag(ref.Tag())}
 	if _, err = manSvc.Put(ctx, manifest, putOptions...); err != nil {
+		if p.config.OCIManifest {
+			logrus.Warnf("failed to upload OCI manifest: %v", err)
+			return err
+		}
 		if runtime.GOOS == "windows" || p.config.TrustKey == nil || p.config.RequireSchema2 {
 			logrus.Warnf("failed to upload schema2 manifest: %v", err)
 			return err
@@ -206,6 +215,11 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 		if err != nil {
 			return err
 		}
+	case *ociManifest:
+		_, canonicalManifest, err = v.Payload()
+		if err != nil {
+			return err
+		}
 	}
 
 	manifestDigest := digest.FromBytes(canonicalManifest)
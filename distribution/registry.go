@@ -9,6 +9,7 @@ import (
 	"github.com/docker/distribution"
 	"github.com/docker/distribution/manifest/schema2"
 	"github.com/docker/distribution/reference"
+	"github.com/docker/distribution/registry/api/v2"
 	"github.com/docker/distribution/registry/client"
 	"github.com/docker/distribution/registry/client/auth"
 	"github.com/docker/distribution/registry/client/transport"
@@ -16,12 +17,14 @@ import (
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/registry"
 	"github.com/docker/go-connections/sockets"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"golang.org/x/net/context"
 )
 
-// ImageTypes represents the schema2 config types for images
+// ImageTypes represents the schema2 and OCI config types for images
 var ImageTypes = []string{
 	schema2.MediaTypeImageConfig,
+	ocispec.MediaTypeImageConfig,
 	// Handle unexpected values from https://github.com/docker/distribution/issues/1621
 	// (see also https://github.com/docker/docker/issues/22378,
 	// https://github.com/docker/docker/issues/30083)
@@ -41,7 +44,7 @@ var mediaTypeClasses map[string]string
 
 func init() {
 	// initialize media type classes with all know types for
-	// plugin
+	// plugin and build cache
 	mediaTypeClasses = map[string]string{}
 	for _, t := range ImageTypes {
 		mediaTypeClasses[t] = "image"
@@ -49,6 +52,9 @@ func init() {
 	for _, t := range PluginTypes {
 		mediaTypeClasses[t] = "plugin"
 	}
+	for _, t := range BuildCacheTypes {
+		mediaTypeClasses[t] = "buildcache"
+	}
 }
 
 // NewV2Repository returns a repository (v2 only). It creates an HTTP transport
@@ -138,10 +144,26 @@ func NewV2Repository(ctx context.Context, repoInfo *registry.RepositoryInfo, end
 			confirmedV2: foundVersion,
 			transportOK: true,
 		}
+		return
+	}
+	// the URL was already parsed by client.NewRepository
+	ub, _ := v2.NewURLBuilderFromString(endpoint.URL.String(), false)
+	repo = &v2Repository{
+		Repository: repo,
+		client:     &http.Client{Transport: tr},
+		ub:         ub,
 	}
 	return
 }
 
+// v2Repository is a repository that can also download a range of a blob,
+// which the registry client doesn't support.
+type v2Repository struct {
+	distribution.Repository
+	client *http.Client
+	ub     *v2.URLBuilder
+}
+
 type existingTokenHandler struct {
 	token string
 }
@@ -41,15 +41,32 @@ func New(store image.Store) *ImageCache {
 // ImageCache is cache based on history objects. Requires initial set of images.
 type ImageCache struct {
 	sources         []*image.Image
+	fetchers        map[*image.Image]LayerFetcher
 	store           image.Store
 	localImageCache *LocalImageCache
 }
 
+// LayerFetcher makes the first n layers of the root filesystem of an image
+// available in the layer store. The returned function is called once the
+// layers are referenced by an image in the store.
+type LayerFetcher func(n int) (release func(), err error)
+
 // Populate adds an image to the cache (to be queried later)
 func (ic *ImageCache) Populate(image *image.Image) {
 	ic.sources = append(ic.sources, image)
 }
 
+// PopulateRemote adds an image that is not in the image store to the cache.
+// fetch is called to make its layers available before it is used by a cache
+// hit.
+func (ic *ImageCache) PopulateRemote(img *image.Image, fetch LayerFetcher) {
+	if ic.fetchers == nil {
+		ic.fetchers = make(map[*image.Image]LayerFetcher)
+	}
+	ic.fetchers[img] = fetch
+	ic.sources = append(ic.sources, img)
+}
+
 // GetCache returns the image id found in the cache
 func (ic *ImageCache) GetCache(parentID string, cfg *containertypes.Config) (string, error) {
 	imgID, err := ic.localImageCache.GetCache(parentID, cfg)
@@ -79,13 +96,27 @@ func (ic *ImageCache) GetCache(parentID string, cfg *containertypes.Config) (str
 			continue
 		}
 
+		if fetch, ok := ic.fetchers[target]; ok {
+			release, err := fetch(layerCountForHistoryIndex(target, lenHistory))
+			if err != nil {
+				return "", errors.Wrap(err, "failed to fetch layers of cache image")
+			}
+			defer release()
+		}
+
 		if len(target.History)-1 == lenHistory { // last
+			targetID := target.ID()
+			if _, ok := ic.fetchers[target]; ok {
+				if targetID, err = ic.store.Create(target.RawJSON()); err != nil {
+					return "", errors.Wrap(err, "failed to create cache image")
+				}
+			}
 			if parent != nil {
-				if err := ic.store.SetParent(target.ID(), parent.ID()); err != nil {
-					return "", errors.Wrapf(err, "failed to set parent for %v to %v", target.ID(), parent.ID())
+				if err := ic.store.SetParent(targetID, parent.ID()); err != nil {
+					return "", errors.Wrapf(err, "failed to set parent for %v to %v", targetID, parent.ID())
 				}
 			}
-			return target.ID().String(), nil
+			return targetID.String(), nil
 		}
 
 		imgID, err := ic.restoreCachedImage(parent, target, cfg)
@@ -172,6 +203,18 @@ func getLayerForHistoryIndex(image *image.Image, index int) layer.DiffID {
 	return image.RootFS.DiffIDs[layerIndex] // validate?
 }
 
+// layerCountForHistoryIndex returns the number of layers of image up to and
+// including the history entry at index
+func layerCountForHistoryIndex(image *image.Image, index int) int {
+	count := 0
+	for _, h := range image.History[:index+1] {
+		if !h.EmptyLayer {
+			count++
+		}
+	}
+	return count
+}
+
 func isValidConfig(cfg *containertypes.Config, h image.History) bool {
 	// todo: make this format better than join that loses data
 	return strings.Join(cfg.Cmd, " ") == h.CreatedBy
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLayerStore struct {
+	available map[layer.ChainID]bool
+}
+
+func (ls *fakeLayerStore) Get(chainID layer.ChainID) (layer.Layer, error) {
+	if !ls.available[chainID] {
+		return nil, errors.Errorf("layer %s does not exist", chainID)
+	}
+	return nil, nil
+}
+
+func (ls *fakeLayerStore) Release(layer.Layer) ([]layer.Metadata, error) {
+	return nil, nil
+}
+
+const remoteImageConfig = `{
+	"os": "linux",
+	"rootfs": {
+		"type": "layers",
+		"diff_ids": ["sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"]
+	},
+	"history": [
+		{"created": "2017-10-01T00:00:00Z", "created_by": "ADD foo /foo"},
+		{"created": "2017-10-01T00:00:01Z", "created_by": "CMD bar", "empty_layer": true}
+	]
+}`
+
+func TestImageCachePopulateRemote(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "image-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	fs, err := image.NewFSStoreBackend(tmpdir)
+	require.NoError(t, err)
+	ls := &fakeLayerStore{available: make(map[layer.ChainID]bool)}
+	store, err := image.NewImageStore(fs, runtime.GOOS, ls)
+	require.NoError(t, err)
+
+	target, err := image.NewFromJSON([]byte(remoteImageConfig))
+	require.NoError(t, err)
+
+	var fetched []int
+	released := 0
+	ic := New(store)
+	ic.PopulateRemote(target, func(n int) (func(), error) {
+		fetched = append(fetched, n)
+		ls.available[layer.CreateChainID(target.RootFS.DiffIDs[:n])] = true
+		return func() { released++ }, nil
+	})
+
+	id, err := ic.GetCache("", &container.Config{Cmd: []string{"ADD", "foo", "/foo"}})
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+	assert.Equal(t, []int{1}, fetched)
+	assert.Equal(t, 1, released)
+
+	id, err = ic.GetCache(id, &container.Config{Cmd: []string{"CMD", "bar"}})
+	require.NoError(t, err)
+	assert.Equal(t, digest.FromBytes([]byte(remoteImageConfig)).String(), id)
+	assert.Equal(t, []int{1, 1}, fetched)
+	assert.Equal(t, 2, released)
+
+	_, err = store.Get(image.ID(id))
+	assert.NoError(t, err)
+}
+
+func TestImageCachePopulateRemoteFetchError(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "image-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	fs, err := image.NewFSStoreBackend(tmpdir)
+	require.NoError(t, err)
+	store, err := image.NewImageStore(fs, runtime.GOOS, &fakeLayerStore{})
+	require.NoError(t, err)
+
+	target, err := image.NewFromJSON([]byte(remoteImageConfig))
+	require.NoError(t, err)
+
+	ic := New(store)
+	ic.PopulateRemote(target, func(n int) (func(), error) {
+		return nil, errors.New("registry unavailable")
+	})
+
+	_, err = ic.GetCache("", &container.Config{Cmd: []string{"ADD", "foo", "/foo"}})
+	assert.EqualError(t, err, "failed to fetch layers of cache image: registry unavailable")
+
+	id, err := ic.GetCache("", &container.Config{Cmd: []string{"RUN", "other"}})
+	require.NoError(t, err)
+	assert.Empty(t, id)
+}
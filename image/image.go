@@ -132,6 +132,9 @@ type ChildConfig struct {
 	DiffID          layer.DiffID
 	ContainerConfig *container.Config
 	Config          *container.Config
+	// Created is the creation time of the image, the current time is used
+	// if it is zero
+	Created time.Time
 }
 
 // NewChildImage creates a new Image as a child of this image.
@@ -152,6 +155,9 @@ func NewChildImage(img *Image, child ChildConfig, platform string) *Image {
 		child.Comment,
 		strings.Join(child.ContainerConfig.Cmd, " "),
 		isEmptyLayer)
+	if !child.Created.IsZero() {
+		imgHistory.Created = child.Created.UTC()
+	}
 
 	return &Image{
 		V1Image: V1Image{
@@ -201,9 +207,13 @@ func NewHistory(author, comment, createdBy string, isEmptyLayer bool) History {
 
 // Exporter provides interface for loading and saving images
 type Exporter interface {
-	Load(io.ReadCloser, io.Writer, bool) error
+	// Load loads the images of an archive. The ref names of an OCI image
+	// layout that are only a tag are tags of the given repository.
+	Load(io.ReadCloser, io.Writer, string, bool) error
 	// TODO: Load(net.Context, io.ReadCloser, <- chan StatusMessage) error
 	Save([]string, io.Writer) error
+	// SaveOCILayout saves the images as an OCI image layout
+	SaveOCILayout([]string, io.Writer) error
 }
 
 // NewFromJSON creates an Image configuration from json.
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/layer"
@@ -122,3 +123,17 @@ func TestNewChildImageFromImageWithRootFS(t *testing.T) {
 	// RootFS should be copied not mutated
 	assert.NotEqual(t, parent.RootFS.DiffIDs, newImage.RootFS.DiffIDs)
 }
+
+func TestNewChildImageWithCreated(t *testing.T) {
+	created := time.Unix(1500000000, 0)
+	childConfig := ChildConfig{
+		ContainerConfig: &container.Config{Cmd: []string{"echo", "foo"}},
+		Config:          &container.Config{},
+		Created:         created,
+	}
+
+	newImage := NewChildImage(&Image{}, childConfig, "platform")
+	assert.Equal(t, created.UTC(), newImage.Created)
+	require.Len(t, newImage.History, 1)
+	assert.Equal(t, created.UTC(), newImage.History[0].Created)
+}
@@ -24,10 +24,11 @@ import (
 	"github.com/docker/docker/pkg/symlink"
 	"github.com/docker/docker/pkg/system"
 	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sirupsen/logrus"
 )
 
-func (l *tarexporter) Load(inTar io.ReadCloser, outStream io.Writer, quiet bool) error {
+func (l *tarexporter) Load(inTar io.ReadCloser, outStream io.Writer, repository string, quiet bool) error {
 	var progressOutput progress.Output
 	if !quiet {
 		progressOutput = streamformatter.NewJSONProgressOutput(outStream, false)
@@ -43,7 +44,7 @@ func (l *tarexporter) Load(inTar io.ReadCloser, outStream io.Writer, quiet bool)
 	if err := chrootarchive.Untar(inTar, tmpDir, nil); err != nil {
 		return err
 	}
-	// read manifest, if no file then load in legacy mode
+	// read manifest, if no file then load an OCI image layout or in legacy mode
 	manifestPath, err := safePath(tmpDir, manifestFileName)
 	if err != nil {
 		return err
@@ -51,6 +52,9 @@ func (l *tarexporter) Load(inTar io.ReadCloser, outStream io.Writer, quiet bool)
 	manifestFile, err := os.Open(manifestPath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Join(tmpDir, ocispec.ImageLayoutFile)); err == nil {
+				return l.ociLoad(tmpDir, outStream, repository, progressOutput)
+			}
 			return l.legacyLoad(tmpDir, outStream, progressOutput)
 		}
 		return err
@@ -89,15 +93,9 @@ func (l *tarexporter) Load(inTar io.ReadCloser, outStream io.Writer, quiet bool)
 			return fmt.Errorf("invalid manifest, layers length mismatch: expected %d, got %d", expected, actual)
 		}
 
-		// On Windows, validate the platform, defaulting to windows if not present.
-		os := layer.OS(img.OS)
-		if runtime.GOOS == "windows" {
-			if os == "" {
-				os = "windows"
-			}
-			if (os != "windows") && (os != "linux") {
-				return fmt.Errorf("configuration for this image has an unsupported operating system: %s", os)
-			}
+		os, err := imageLayerOS(img)
+		if err != nil {
+			return err
 		}
 
 		for i, diffID := range img.RootFS.DiffIDs {
@@ -161,6 +159,21 @@ func (l *tarexporter) Load(inTar io.ReadCloser, outStream io.Writer, quiet bool)
 	return nil
 }
 
+// imageLayerOS returns the operating system of the layers of img. On Windows,
+// the platform is validated, defaulting to windows if not present.
+func imageLayerOS(img *image.Image) (layer.OS, error) {
+	os := layer.OS(img.OS)
+	if runtime.GOOS == "windows" {
+		if os == "" {
+			os = "windows"
+		}
+		if (os != "windows") && (os != "linux") {
+			return "", fmt.Errorf("configuration for this image has an unsupported operating system: %s", os)
+		}
+	}
+	return os, nil
+}
+
 func (l *tarexporter) setParentID(id, parentID image.ID) error {
 	img, err := l.is.Get(id)
 	if err != nil {
@@ -0,0 +1,454 @@
+package tarexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/pkg/system"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type ociSaveSession struct {
+	*tarexporter
+	outDir     string
+	images     map[image.ID]*imageDescriptor
+	savedBlobs map[digest.Digest]int64
+}
+
+// SaveOCILayout saves the images as an OCI image layout. The tags of the
+// images are the ref name annotations of their manifests in the index.
+func (l *tarexporter) SaveOCILayout(names []string, outStream io.Writer) error {
+	images, err := l.parseNames(names)
+	if err != nil {
+		return err
+	}
+
+	// Release all the image top layer references
+	defer l.releaseLayerReferences(images)
+	return (&ociSaveSession{tarexporter: l, images: images}).save(outStream)
+}
+
+func (s *ociSaveSession) save(outStream io.Writer) error {
+	s.savedBlobs = make(map[digest.Digest]int64)
+
+	tempDir, err := ioutil.TempDir("", "docker-export-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	s.outDir = tempDir
+
+	// save the images in a stable order
+	ids := make([]string, 0, len(s.images))
+	for id := range s.images {
+		ids = append(ids, id.String())
+	}
+	sort.Strings(ids)
+
+	index := ocispec.Index{Versioned: specs.Versioned{SchemaVersion: 2}}
+	for _, id := range ids {
+		imageDescr := s.images[image.ID(id)]
+		desc, err := s.saveImage(image.ID(id))
+		if err != nil {
+			return err
+		}
+		if len(imageDescr.refs) == 0 {
+			index.Manifests = append(index.Manifests, desc)
+		}
+		for _, ref := range imageDescr.refs {
+			refDesc := desc
+			refDesc.Annotations = map[string]string{ocispec.AnnotationRefName: ref.String()}
+			index.Manifests = append(index.Manifests, refDesc)
+		}
+		s.tarexporter.loggerImgEvent.LogImageEvent(id, id, "save")
+	}
+
+	if err := s.writeJSONFile(ocispec.ImageLayoutFile, ocispec.ImageLayout{Version: ocispec.ImageLayoutVersion}); err != nil {
+		return err
+	}
+	if err := s.writeJSONFile(ociIndexFileName, index); err != nil {
+		return err
+	}
+
+	fs, err := archive.Tar(tempDir, archive.Uncompressed)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	_, err = io.Copy(outStream, fs)
+	return err
+}
+
+// saveImage saves the config, layers and manifest of an image as blobs, and
+// returns the descriptor of the manifest
+func (s *ociSaveSession) saveImage(id image.ID) (ocispec.Descriptor, error) {
+	img := s.images[id].image
+	if len(img.RootFS.DiffIDs) == 0 {
+		return ocispec.Descriptor{}, fmt.Errorf("empty export - not implemented")
+	}
+
+	config, err := s.saveBlob(img.RawJSON())
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	config.MediaType = ocispec.MediaTypeImageConfig
+
+	manifest := ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    config,
+	}
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+	for _, diffID := range img.RootFS.DiffIDs {
+		rootFS.Append(diffID)
+		desc, err := s.saveLayer(rootFS.ChainID())
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		manifest.Layers = append(manifest.Layers, desc)
+	}
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc, err := s.saveBlob(manifestJSON)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc.MediaType = ocispec.MediaTypeImageManifest
+	desc.Platform = &ocispec.Platform{
+		Architecture: img.Architecture,
+		OS:           img.OperatingSystem(),
+	}
+	return desc, nil
+}
+
+// saveLayer saves the uncompressed tar stream of a layer as a blob. Foreign
+// layers are saved with their content too, as regular layers.
+func (s *ociSaveSession) saveLayer(id layer.ChainID) (ocispec.Descriptor, error) {
+	l, err := s.ls.Get(id)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer layer.ReleaseAndLog(s.ls, l)
+
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.Digest(l.DiffID()),
+	}
+	if size, exists := s.savedBlobs[desc.Digest]; exists {
+		desc.Size = size
+		return desc, nil
+	}
+
+	blobPath, err := s.blobPath(desc.Digest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	// Use system.CreateSequential rather than os.Create. This ensures sequential
+	// file access on Windows to avoid eating into MM standby list.
+	// On Linux, this equates to a regular os.Create.
+	tarFile, err := system.CreateSequential(blobPath)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer tarFile.Close()
+
+	arch, err := l.TarStream()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer arch.Close()
+
+	digester := digest.Canonical.Digester()
+	if desc.Size, err = io.Copy(io.MultiWriter(tarFile, digester.Hash()), arch); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if digester.Digest() != desc.Digest {
+		return ocispec.Descriptor{}, fmt.Errorf("layer %s was exported with a different digest %s", desc.Digest, digester.Digest())
+	}
+	if err := system.Chtimes(blobPath, time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	s.savedBlobs[desc.Digest] = desc.Size
+	return desc, nil
+}
+
+// saveBlob saves data as a blob, and returns its descriptor without media type
+func (s *ociSaveSession) saveBlob(data []byte) (ocispec.Descriptor, error) {
+	desc := ocispec.Descriptor{
+		Digest: digest.FromBytes(data),
+		Size:   int64(len(data)),
+	}
+	if _, exists := s.savedBlobs[desc.Digest]; exists {
+		return desc, nil
+	}
+	blobPath, err := s.blobPath(desc.Digest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := ioutil.WriteFile(blobPath, data, 0644); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := system.Chtimes(blobPath, time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	s.savedBlobs[desc.Digest] = desc.Size
+	return desc, nil
+}
+
+// blobPath returns the path of the blob dgst, creating its directory
+func (s *ociSaveSession) blobPath(dgst digest.Digest) (string, error) {
+	dir := filepath.Join(s.outDir, ociBlobsDir, dgst.Algorithm().String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dgst.Hex()), nil
+}
+
+func (s *ociSaveSession) writeJSONFile(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(s.outDir, name)
+	if err := ioutil.WriteFile(p, data, 0644); err != nil {
+		return err
+	}
+	return system.Chtimes(p, time.Unix(0, 0), time.Unix(0, 0))
+}
+
+// ociLoad loads the images of the OCI image layout extracted in tmpDir. The
+// references of the manifests of the index are set as tags of the images,
+// see ociImageReference.
+func (l *tarexporter) ociLoad(tmpDir string, outStream io.Writer, repository string, progressOutput progress.Output) error {
+	var repo reference.Named
+	if repository != "" {
+		named, err := reference.ParseNormalizedNamed(repository)
+		if err != nil {
+			return err
+		}
+		repo = reference.TrimNamed(named)
+	}
+	var layout ocispec.ImageLayout
+	if err := readJSONFile(tmpDir, ocispec.ImageLayoutFile, &layout); err != nil {
+		return err
+	}
+	if layout.Version != ocispec.ImageLayoutVersion {
+		return fmt.Errorf("unsupported OCI image layout version %q", layout.Version)
+	}
+	var index ocispec.Index
+	if err := readJSONFile(tmpDir, ociIndexFileName, &index); err != nil {
+		return err
+	}
+
+	var imageIDsStr string
+	var imageRefCount int
+	loaded := make(map[digest.Digest]image.ID)
+
+	for _, desc := range index.Manifests {
+		manifestDesc, err := ociImageManifest(tmpDir, desc)
+		if err != nil {
+			return err
+		}
+		imgID, ok := loaded[manifestDesc.Digest]
+		if !ok {
+			if imgID, err = l.ociLoadImage(tmpDir, manifestDesc, progressOutput); err != nil {
+				return err
+			}
+			loaded[manifestDesc.Digest] = imgID
+			imageIDsStr += fmt.Sprintf("Loaded image ID: %s\n", imgID)
+			l.loggerImgEvent.LogImageEvent(imgID.String(), imgID.String(), "load")
+		}
+
+		ref, err := ociImageReference(desc.Annotations, repo)
+		if err != nil {
+			fmt.Fprintf(outStream, "Ignoring %s of image %s\n", err, imgID)
+			continue
+		}
+		if ref == nil {
+			continue
+		}
+		l.setLoadedTag(ref, imgID.Digest(), outStream)
+		outStream.Write([]byte(fmt.Sprintf("Loaded image: %s\n", reference.FamiliarString(ref))))
+		imageRefCount++
+	}
+
+	if imageRefCount == 0 {
+		outStream.Write([]byte(imageIDsStr))
+	}
+	return nil
+}
+
+// containerdImageNameAnnotation is the annotation of the full image name set
+// by containerd in the index of the OCI image layouts it exports
+const containerdImageNameAnnotation = "io.containerd.image.name"
+
+var anchoredTagRegexp = regexp.MustCompile(`^` + reference.TagRegexp.String() + `$`)
+
+// ociImageReference returns the reference of an image in the index of an OCI
+// image layout, or nil if it has none. The full name set by containerd takes
+// precedence over the ref name annotation. The ref name is only a tag, as
+// written by skopeo, buildah or umoci, unless it contains a name. A tag is
+// a tag of repository.
+func ociImageReference(annotations map[string]string, repository reference.Named) (reference.NamedTagged, error) {
+	refName := annotations[containerdImageNameAnnotation]
+	if refName == "" {
+		refName = annotations[ocispec.AnnotationRefName]
+		if refName == "" {
+			return nil, nil
+		}
+		if anchoredTagRegexp.MatchString(refName) {
+			if repository == nil {
+				return nil, fmt.Errorf("ref name %s without repository", refName)
+			}
+			return reference.WithTag(repository, refName)
+		}
+	}
+	named, err := reference.ParseNormalizedNamed(refName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ref name %s", refName)
+	}
+	ref, ok := named.(reference.NamedTagged)
+	if !ok {
+		return nil, fmt.Errorf("ref name %s without tag", refName)
+	}
+	return ref, nil
+}
+
+// ociImageManifest returns the descriptor of the image manifest of desc. The
+// first manifest of an index with the platform of the daemon is selected.
+func ociImageManifest(dir string, desc ocispec.Descriptor) (ocispec.Descriptor, error) {
+	switch desc.MediaType {
+	case ocispec.MediaTypeImageManifest, schema2.MediaTypeManifest:
+		return desc, nil
+	case ocispec.MediaTypeImageIndex, manifestlist.MediaTypeManifestList:
+		var index ocispec.Index
+		if err := readJSONBlob(dir, desc, &index); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		for _, m := range index.Manifests {
+			if m.Platform != nil && (m.Platform.Architecture != runtime.GOARCH || checkCompatibleOS(m.Platform.OS) != nil) {
+				continue
+			}
+			return ociImageManifest(dir, m)
+		}
+		return ocispec.Descriptor{}, fmt.Errorf("no manifest for %s/%s in index %s", runtime.GOOS, runtime.GOARCH, desc.Digest)
+	}
+	return ocispec.Descriptor{}, fmt.Errorf("unsupported media type %s of manifest %s", desc.MediaType, desc.Digest)
+}
+
+func (l *tarexporter) ociLoadImage(dir string, desc ocispec.Descriptor, progressOutput progress.Output) (image.ID, error) {
+	var manifest ocispec.Manifest
+	if err := readJSONBlob(dir, desc, &manifest); err != nil {
+		return "", err
+	}
+	config, err := readBlob(dir, manifest.Config)
+	if err != nil {
+		return "", err
+	}
+	img, err := image.NewFromJSON(config)
+	if err != nil {
+		return "", err
+	}
+	if err := checkCompatibleOS(img.OS); err != nil {
+		return "", err
+	}
+	if expected, actual := len(manifest.Layers), len(img.RootFS.DiffIDs); expected != actual {
+		return "", fmt.Errorf("invalid manifest, layers length mismatch: expected %d, got %d", expected, actual)
+	}
+	layerOS, err := imageLayerOS(img)
+	if err != nil {
+		return "", err
+	}
+
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+	for i, diffID := range img.RootFS.DiffIDs {
+		r := rootFS
+		r.Append(diffID)
+		newLayer, err := l.ls.Get(r.ChainID())
+		if err != nil {
+			layerPath, err := blobPath(dir, manifest.Layers[i].Digest)
+			if err != nil {
+				return "", err
+			}
+			newLayer, err = l.loadLayer(layerPath, rootFS, diffID.String(), layerOS, distribution.Descriptor{}, progressOutput)
+			if err != nil {
+				return "", err
+			}
+		}
+		defer layer.ReleaseAndLog(l.ls, newLayer)
+		if expected, actual := diffID, newLayer.DiffID(); expected != actual {
+			return "", fmt.Errorf("invalid diffID for layer %d: expected %q, got %q", i, expected, actual)
+		}
+		rootFS.Append(diffID)
+	}
+
+	return l.is.Create(config)
+}
+
+// blobPath returns the path of the blob dgst of the OCI image layout in dir
+func blobPath(dir string, dgst digest.Digest) (string, error) {
+	if err := dgst.Validate(); err != nil {
+		return "", err
+	}
+	return safePath(dir, filepath.Join(ociBlobsDir, dgst.Algorithm().String(), dgst.Hex()))
+}
+
+// readBlob reads the blob of desc, and verifies its digest and size
+func readBlob(dir string, desc ocispec.Descriptor) ([]byte, error) {
+	p, err := blobPath(dir, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if desc.Digest.Algorithm().FromBytes(data) != desc.Digest || int64(len(data)) != desc.Size {
+		return nil, fmt.Errorf("invalid content for blob %s", desc.Digest)
+	}
+	return data, nil
+}
+
+func readJSONBlob(dir string, desc ocispec.Descriptor, v interface{}) error {
+	data, err := readBlob(dir, desc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func readJSONFile(dir, name string, v interface{}) error {
+	p, err := safePath(dir, name)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,72 @@
+package tarexport
+
+import (
+	"testing"
+
+	"github.com/docker/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestOCIImageReference(t *testing.T) {
+	repository, err := reference.ParseNormalizedNamed("example.com/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		repository  reference.Named
+		expected    string
+		expectedErr string
+	}{
+		{},
+		{
+			annotations: map[string]string{ocispec.AnnotationRefName: "latest"},
+			repository:  repository,
+			expected:    "example.com/app:latest",
+		},
+		{
+			annotations: map[string]string{ocispec.AnnotationRefName: "v1.0"},
+			expectedErr: "ref name v1.0 without repository",
+		},
+		{
+			annotations: map[string]string{ocispec.AnnotationRefName: "busybox:1.27"},
+			repository:  repository,
+			expected:    "docker.io/library/busybox:1.27",
+		},
+		{
+			annotations: map[string]string{
+				containerdImageNameAnnotation: "docker.io/library/busybox:latest",
+				ocispec.AnnotationRefName:     "latest",
+			},
+			expected: "docker.io/library/busybox:latest",
+		},
+		{
+			annotations: map[string]string{ocispec.AnnotationRefName: "example.com/app"},
+			expectedErr: "ref name example.com/app without tag",
+		},
+		{
+			annotations: map[string]string{ocispec.AnnotationRefName: "Invalid/Name:tag"},
+			expectedErr: "invalid ref name Invalid/Name:tag",
+		},
+	}
+	for _, tc := range testCases {
+		ref, err := ociImageReference(tc.annotations, tc.repository)
+		if tc.expectedErr != "" {
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Fatalf("Expected error %q for %v, got %v", tc.expectedErr, tc.annotations, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", tc.annotations, err)
+		}
+		actual := ""
+		if ref != nil {
+			actual = ref.String()
+		}
+		if actual != tc.expected {
+			t.Fatalf("Expected reference %q for %v, got %q", tc.expected, tc.annotations, actual)
+		}
+	}
+}
@@ -13,6 +13,8 @@ const (
 	legacyConfigFileName       = "json"
 	legacyVersionFileName      = "VERSION"
 	legacyRepositoriesFileName = "repositories"
+	ociIndexFileName           = "index.json"
+	ociBlobsDir                = "blobs"
 )
 
 type manifestItem struct {
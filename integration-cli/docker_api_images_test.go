@@ -1,6 +1,11 @@
 package main
 
 import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,6 +18,7 @@ import (
 	"github.com/docker/docker/integration-cli/cli/build"
 	"github.com/docker/docker/integration-cli/request"
 	"github.com/go-check/check"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"golang.org/x/net/context"
 )
 
@@ -75,6 +81,59 @@ func (s *DockerSuite) TestAPIImagesSaveAndLoad(c *check.C) {
 	c.Assert(strings.TrimSpace(string(inspectOut)), checker.Equals, id, check.Commentf("load did not work properly"))
 }
 
+func (s *DockerSuite) TestAPIImagesSaveAndLoadOCILayout(c *check.C) {
+	testRequires(c, Network, DaemonIsLinux)
+	buildImageSuccessfully(c, "saveandloadoci", build.WithDockerfile("FROM busybox\nENV FOO bar"))
+	id := getIDByName(c, "saveandloadoci")
+
+	res, body, err := request.Get("/images/get?names=saveandloadoci&format=oci")
+	c.Assert(err, checker.IsNil)
+	defer body.Close()
+	c.Assert(res.StatusCode, checker.Equals, http.StatusOK)
+	layout, err := ioutil.ReadAll(body)
+	c.Assert(err, checker.IsNil)
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(bytes.NewReader(layout))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, checker.IsNil)
+		content, err := ioutil.ReadAll(tr)
+		c.Assert(err, checker.IsNil)
+		files[hdr.Name] = content
+	}
+	c.Assert(string(files["oci-layout"]), checker.Equals, `{"imageLayoutVersion":"1.0.0"}`)
+	_, ok := files["manifest.json"]
+	c.Assert(ok, checker.False)
+	var index ocispec.Index
+	c.Assert(json.Unmarshal(files["index.json"], &index), checker.IsNil)
+	c.Assert(index.Manifests, checker.HasLen, 1)
+	c.Assert(index.Manifests[0].MediaType, checker.Equals, ocispec.MediaTypeImageManifest)
+	c.Assert(index.Manifests[0].Annotations[ocispec.AnnotationRefName], checker.Equals, "docker.io/library/saveandloadoci:latest")
+	_, ok = files["blobs/sha256/"+index.Manifests[0].Digest.Hex()]
+	c.Assert(ok, checker.True)
+
+	dockerCmd(c, "rmi", id)
+
+	res, loadBody, err := request.Post("/images/load", request.RawContent(ioutil.NopCloser(bytes.NewReader(layout))), request.ContentType("application/x-tar"))
+	c.Assert(err, checker.IsNil)
+	defer loadBody.Close()
+	c.Assert(res.StatusCode, checker.Equals, http.StatusOK)
+
+	inspectOut := cli.InspectCmd(c, "saveandloadoci", cli.Format(".Id")).Combined()
+	c.Assert(strings.TrimSpace(string(inspectOut)), checker.Equals, id, check.Commentf("load did not work properly"))
+}
+
+func (s *DockerSuite) TestAPIImagesSaveInvalidFormat(c *check.C) {
+	res, body, err := request.Get("/images/get?names=busybox&format=invalid")
+	c.Assert(err, checker.IsNil)
+	body.Close()
+	c.Assert(res.StatusCode, checker.Equals, http.StatusBadRequest)
+}
+
 func (s *DockerSuite) TestAPIImagesDelete(c *check.C) {
 	cli, err := client.NewEnvClient()
 	c.Assert(err, checker.IsNil)
@@ -445,6 +445,7 @@ func (s *DockerDaemonSuite) TestDaemonEvents(c *check.C) {
 		" live-restore=",
 		" max-concurrent-downloads=1, ",
 		" max-concurrent-uploads=5, ",
+		" max-download-parts=",
 		" name=" + daemonName,
 		" registry-mirrors=[",
 		" runtimes=",
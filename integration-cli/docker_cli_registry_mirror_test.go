@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/docker/docker/integration-cli/checker"
+	"github.com/docker/docker/integration-cli/registry"
+	"github.com/go-check/check"
+)
+
+// TestPullFromRegistryMirror pulls an image of a registry that doesn't exist
+// from its mirror, the local registry
+func (s *DockerRegistrySuite) TestPullFromRegistryMirror(c *check.C) {
+	repoName := fmt.Sprintf("%v/dockercli/busybox:latest", privateRegistryURL)
+	dockerCmd(c, "tag", "busybox", repoName)
+	dockerCmd(c, "push", repoName)
+
+	s.d.Start(c, "--registry-mirror", "registry.invalid=http://"+privateRegistryURL)
+
+	out, err := s.d.Cmd("pull", "registry.invalid/dockercli/busybox:latest")
+	c.Assert(err, checker.IsNil, check.Commentf(out))
+	out, err = s.d.Cmd("inspect", "registry.invalid/dockercli/busybox:latest")
+	c.Assert(err, checker.IsNil, check.Commentf(out))
+}
+
+// TestPullRegistryMirrorFallback ensures that the mirrors of a registry are
+// tried in order before the registry itself
+func (s *DockerRegistrySuite) TestPullRegistryMirrorFallback(c *check.C) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	newMock := func(name string) *registry.Mock {
+		reg, err := registry.NewMock(c)
+		c.Assert(err, check.IsNil)
+		reg.RegisterHandler("/v2/", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+			if r.URL.Path == "/v2/" {
+				return
+			}
+			mu.Lock()
+			requests = append(requests, name+" "+r.URL.Path)
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[{"code":"NAME_UNKNOWN","message":"repository name not known to registry"}]}`))
+		})
+		return reg
+	}
+	reg := newMock("registry")
+	defer reg.Close()
+	mirror1 := newMock("mirror1")
+	defer mirror1.Close()
+	mirror2 := newMock("mirror2")
+	defer mirror2.Close()
+
+	s.d.Start(c,
+		"--insecure-registry", reg.URL(),
+		"--registry-mirror", reg.URL()+"=http://"+mirror1.URL(),
+		"--registry-mirror", reg.URL()+"=http://"+mirror2.URL())
+
+	out, err := s.d.Cmd("pull", reg.URL()+"/busybox:latest")
+	c.Assert(err, checker.NotNil, check.Commentf(out))
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(requests, checker.DeepEquals, []string{
+		"mirror1 /v2/busybox/manifests/latest",
+		"mirror2 /v2/busybox/manifests/latest",
+		"registry /v2/busybox/manifests/latest",
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/idtools"
@@ -296,6 +297,56 @@ func ReplaceFileTarWrapper(inputTarStream io.ReadCloser, mods map[string]TarModi
 	return pipeReader
 }
 
+// ClampTimesTarWrapper converts inputTarStream to a new tar stream in which the
+// modification, access and change times of the entries that are later than t
+// are set to t.
+func ClampTimesTarWrapper(inputTarStream io.ReadCloser, t time.Time) io.ReadCloser {
+	pipeReader, pipeWriter := io.Pipe()
+
+	go func() {
+		tarReader := tar.NewReader(inputTarStream)
+		tarWriter := tar.NewWriter(pipeWriter)
+		defer inputTarStream.Close()
+
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+
+			header.ModTime = clampTime(header.ModTime, t)
+			header.AccessTime = clampTime(header.AccessTime, t)
+			header.ChangeTime = clampTime(header.ChangeTime, t)
+			if err := tarWriter.WriteHeader(header); err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+			if _, err := pools.Copy(tarWriter, tarReader); err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+		}
+
+		if err := tarWriter.Close(); err != nil {
+			pipeWriter.CloseWithError(err)
+			return
+		}
+		pipeWriter.Close()
+	}()
+	return pipeReader
+}
+
+func clampTime(tm, max time.Time) time.Time {
+	if tm.After(max) {
+		return max
+	}
+	return tm
+}
+
 // Extension returns the extension of a file that uses the specified compression algorithm.
 func (compression *Compression) Extension() string {
 	switch *compression {
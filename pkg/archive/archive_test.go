@@ -1192,6 +1192,49 @@ func TestTempArchiveCloseMultipleTimes(t *testing.T) {
 	}
 }
 
+func TestClampTimesTarWrapper(t *testing.T) {
+	epoch := time.Unix(1500000000, 0)
+	before := time.Unix(1400000000, 0)
+	after := time.Unix(1600000000, 0)
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: after}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "dir/old", Typeflag: tar.TypeReg, Mode: 0644, Size: 3, ModTime: before}))
+	_, err := tw.Write([]byte("old"))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "dir/new", Typeflag: tar.TypeReg, Mode: 0644, Size: 3, ModTime: after, AccessTime: after, ChangeTime: after}))
+	_, err = tw.Write([]byte("new"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	result := ClampTimesTarWrapper(ioutil.NopCloser(buf), epoch)
+	defer result.Close()
+	tr := tar.NewReader(result)
+	expected := []struct {
+		name    string
+		modTime time.Time
+		content string
+	}{
+		{name: "dir/", modTime: epoch},
+		{name: "dir/old", modTime: before, content: "old"},
+		{name: "dir/new", modTime: epoch, content: "new"},
+	}
+	for _, e := range expected {
+		header, err := tr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, e.name, header.Name)
+		assert.True(t, e.modTime.Equal(header.ModTime), "%s: %s", e.name, header.ModTime)
+		assert.False(t, header.AccessTime.After(epoch), e.name)
+		assert.False(t, header.ChangeTime.After(epoch), e.name)
+		content, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		assert.Equal(t, e.content, string(content))
+	}
+	_, err = tr.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
 func TestReplaceFileTarWrapper(t *testing.T) {
 	filesInArchive := 20
 	testcases := []struct {
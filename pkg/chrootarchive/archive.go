@@ -22,6 +22,15 @@ func NewArchiver(idMappings *idtools.IDMappings) *archive.Archiver {
 	}
 }
 
+// Tar tars the requested path while chrooted to the specified root, so that
+// symlinks in the archived tree cannot point outside of root.
+func Tar(srcPath string, options *archive.TarOptions, root string) (io.ReadCloser, error) {
+	if options == nil {
+		options = &archive.TarOptions{}
+	}
+	return invokePack(srcPath, options, root)
+}
+
 // Untar reads a stream of bytes from `archive`, parses it as a tar archive,
 // and unpacks it into the directory at `dest`.
 // The archive may be compressed with one of the following algorithms:
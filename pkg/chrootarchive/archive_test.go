@@ -105,6 +105,49 @@ func TestChrootUntarWithHugeExcludesList(t *testing.T) {
 	}
 }
 
+func TestChrootTarSymlinkOutsideRoot(t *testing.T) {
+	// TODO Windows: Windows doesn't support chroot
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows doesn't support chroot")
+	}
+	tmpdir, err := ioutil.TempDir("", "docker-TestChrootTarSymlinkOutsideRoot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	root := filepath.Join(tmpdir, "root")
+	outside := filepath.Join(tmpdir, "outside")
+	for _, dir := range []string{root, outside} {
+		if err := system.MkdirAll(dir, 0700, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "toto"), []byte("hello toto"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := Tar(root, &archive.TarOptions{IncludeFiles: []string{"toto", "link/secret"}}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(tmpdir, "dest")
+	if err := Untar(stream, dest, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := compareFiles(filepath.Join(root, "toto"), filepath.Join(dest, "toto")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "link", "secret")); !os.IsNotExist(err) {
+		t.Fatalf("expected the file outside of the root not to be archived, got: %v", err)
+	}
+}
+
 func TestChrootUntarEmptyArchive(t *testing.T) {
 	tmpdir, err := ioutil.TempDir("", "docker-TestChrootUntarEmptyArchive")
 	if err != nil {
@@ -0,0 +1,76 @@
+// +build linux,cgo
+
+// Package nsenter makes a process enter the network and mount namespaces of
+// another process before the Go runtime starts, so that all of its threads
+// are in these namespaces. The namespaces are entered when the process is
+// started with the pid of the other process in the environment variable
+// named by PidEnv, typically when re-executing the daemon with
+// pkg/reexec.
+package nsenter
+
+/*
+#define _GNU_SOURCE
+#include <fcntl.h>
+#include <sched.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <unistd.h>
+
+static int entered;
+
+static int open_ns(const char *pid, const char *ns) {
+	char path[64];
+	int fd;
+
+	snprintf(path, sizeof(path), "/proc/%s/ns/%s", pid, ns);
+	fd = open(path, O_RDONLY | O_CLOEXEC);
+	if (fd < 0) {
+		fprintf(stderr, "nsenter: error opening %s: ", path);
+		perror("");
+		exit(1);
+	}
+	return fd;
+}
+
+static void enter_ns(int fd, int nstype, const char *ns) {
+	if (setns(fd, nstype) < 0) {
+		fprintf(stderr, "nsenter: error entering %s namespace: ", ns);
+		perror("");
+		exit(1);
+	}
+	close(fd);
+}
+
+// nsenter runs before the Go runtime starts any thread, which is required
+// to enter a mount namespace.
+__attribute__((constructor)) static void nsenter(void) {
+	const char *pid = getenv("_DOCKER_NSENTER_PID");
+	int netfd, mntfd;
+
+	if (pid == NULL || *pid == '\0') {
+		return;
+	}
+	// open both namespaces first, the /proc of the mount namespace may not
+	// show the process
+	netfd = open_ns(pid, "net");
+	mntfd = open_ns(pid, "mnt");
+	enter_ns(netfd, CLONE_NEWNET, "network");
+	enter_ns(mntfd, CLONE_NEWNS, "mount");
+	entered = 1;
+}
+
+static int nsenter_entered(void) {
+	return entered;
+}
+*/
+import "C"
+
+// PidEnv is the environment variable holding the pid of the process whose
+// namespaces are entered.
+const PidEnv = "_DOCKER_NSENTER_PID"
+
+// Entered returns whether the process entered the namespaces of the
+// process named by PidEnv.
+func Entered() bool {
+	return C.nsenter_entered() != 0
+}
@@ -0,0 +1,13 @@
+// +build !linux !cgo
+
+package nsenter
+
+// PidEnv is the environment variable holding the pid of the process whose
+// namespaces are entered.
+const PidEnv = "_DOCKER_NSENTER_PID"
+
+// Entered always returns false, namespaces can only be entered on linux
+// with cgo.
+func Entered() bool {
+	return false
+}
@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -175,6 +176,34 @@ func TestNewClientWithTimeout(t *testing.T) {
 	}
 }
 
+func TestCallWithOptionsContext(t *testing.T) {
+	addr := setupRemotePluginServer()
+	defer teardownRemotePluginServer()
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+	mux.HandleFunc("/Test.Echo", func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	c, _ := NewClient(addr, &tlsconfig.Options{InsecureSkipVerify: true})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.CallWithOptions("Test.Echo", nil, nil, WithRequestContext(ctx))
+	}()
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatal("Expected the canceled call to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Call did not return after its context was canceled")
+	}
+}
+
 func TestClientStream(t *testing.T) {
 	addr := setupRemotePluginServer()
 	defer teardownRemotePluginServer()
@@ -1,10 +1,12 @@
 package registry
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -16,19 +18,62 @@ import (
 
 // ServiceOptions holds command line options.
 type ServiceOptions struct {
-	AllowNondistributableArtifacts []string `json:"allow-nondistributable-artifacts,omitempty"`
-	Mirrors                        []string `json:"registry-mirrors,omitempty"`
-	InsecureRegistries             []string `json:"insecure-registries,omitempty"`
+	AllowNondistributableArtifacts []string        `json:"allow-nondistributable-artifacts,omitempty"`
+	Mirrors                        RegistryMirrors `json:"registry-mirrors,omitempty"`
+	InsecureRegistries             []string        `json:"insecure-registries,omitempty"`
 
 	// V2Only controls access to legacy registries.  If it is set to true via the
 	// command line flag the daemon will not attempt to contact v1 legacy registries
 	V2Only bool `json:"disable-legacy-registry,omitempty"`
 }
 
+// RegistryMirrors are the mirrors of registries. The mirrors of a registry
+// other than the official index are prefixed with the registry and "=", like
+// "quay.io=https://quay-mirror.example.com".
+//
+// In the configuration file, the mirrors are either a list, or an object with
+// the mirrors of each registry:
+//
+//	"registry-mirrors": {
+//	    "docker.io": ["https://mirror.example.com"],
+//	    "quay.io": ["https://quay-mirror.example.com"]
+//	}
+type RegistryMirrors []string
+
+// UnmarshalJSON decodes a list of mirrors or an object with the mirrors of
+// each registry
+func (m *RegistryMirrors) UnmarshalJSON(data []byte) error {
+	var mirrors []string
+	if err := json.Unmarshal(data, &mirrors); err == nil {
+		*m = mirrors
+		return nil
+	}
+	var byRegistry map[string][]string
+	if err := json.Unmarshal(data, &byRegistry); err != nil {
+		return errors.New("registry-mirrors must be a list of mirrors or an object with the mirrors of each registry")
+	}
+	registries := make([]string, 0, len(byRegistry))
+	for r := range byRegistry {
+		registries = append(registries, r)
+	}
+	sort.Strings(registries)
+	mirrors = []string{}
+	for _, r := range registries {
+		for _, mirror := range byRegistry[r] {
+			mirrors = append(mirrors, r+"="+mirror)
+		}
+	}
+	*m = mirrors
+	return nil
+}
+
 // serviceConfig holds daemon configuration for the registry service.
 type serviceConfig struct {
 	registrytypes.ServiceConfig
 	V2Only bool
+	// RegistryMirrors are the mirrors of the registries other than the
+	// official index, by registry
+	RegistryMirrors map[string][]string
 }
 
 var (
@@ -76,8 +121,6 @@ func newServiceConfig(options ServiceOptions) (*serviceConfig, error) {
 		ServiceConfig: registrytypes.ServiceConfig{
 			InsecureRegistryCIDRs: make([]*registrytypes.NetIPNet, 0),
 			IndexConfigs:          make(map[string]*registrytypes.IndexInfo),
-			// Hack: Bypass setting the mirrors to IndexConfigs since they are going away
-			// and Mirrors are only for the official registry anyways.
 		},
 		V2Only: options.V2Only,
 	}
@@ -136,21 +179,38 @@ func (config *serviceConfig) LoadAllowNondistributableArtifacts(registries []str
 func (config *serviceConfig) LoadMirrors(mirrors []string) error {
 	mMap := map[string]struct{}{}
 	unique := []string{}
+	registryMirrors := map[string][]string{}
 
 	for _, mirror := range mirrors {
 		m, err := ValidateMirror(mirror)
 		if err != nil {
 			return err
 		}
-		if _, exist := mMap[m]; !exist {
-			mMap[m] = struct{}{}
+		if _, exist := mMap[m]; exist {
+			continue
+		}
+		mMap[m] = struct{}{}
+		if registry, uri := splitMirror(m); registry != "" {
+			registryMirrors[registry] = append(registryMirrors[registry], uri)
+		} else {
 			unique = append(unique, m)
 		}
 	}
 
 	config.Mirrors = unique
-
-	// Configure public registry since mirrors may have changed.
+	config.RegistryMirrors = registryMirrors
+
+	// Configure the indexes since mirrors may have changed.
+	for name, index := range config.IndexConfigs {
+		if !index.Official {
+			config.IndexConfigs[name] = &registrytypes.IndexInfo{
+				Name:     index.Name,
+				Mirrors:  config.mirrorsOf(name),
+				Secure:   index.Secure,
+				Official: false,
+			}
+		}
+	}
 	config.IndexConfigs[IndexName] = &registrytypes.IndexInfo{
 		Name:     IndexName,
 		Mirrors:  config.Mirrors,
@@ -161,6 +221,12 @@ func (config *serviceConfig) LoadMirrors(mirrors []string) error {
 	return nil
 }
 
+// mirrorsOf returns the mirrors of the registry indexName, other than the
+// official index
+func (config *serviceConfig) mirrorsOf(indexName string) []string {
+	return append(make([]string, 0), config.RegistryMirrors[indexName]...)
+}
+
 // LoadInsecureRegistries loads insecure registries to config
 func (config *serviceConfig) LoadInsecureRegistries(registries []string) error {
 	// Localhost is by default considered as an insecure registry
@@ -223,7 +289,7 @@ skip:
 			// Assume `host:port` if not CIDR.
 			config.IndexConfigs[r] = &registrytypes.IndexInfo{
 				Name:     r,
-				Mirrors:  make([]string, 0),
+				Mirrors:  config.mirrorsOf(r),
 				Secure:   false,
 				Official: false,
 			}
@@ -316,8 +382,24 @@ func isCIDRMatch(cidrs []*registrytypes.NetIPNet, URLHost string) bool {
 	return false
 }
 
-// ValidateMirror validates an HTTP(S) registry mirror
+// ValidateMirror validates an HTTP(S) registry mirror. The mirror of a
+// registry other than the official index is prefixed with the registry and
+// "=", like "quay.io=https://quay-mirror.example.com".
 func ValidateMirror(val string) (string, error) {
+	registry, val := splitMirror(val)
+	if registry != "" {
+		name, err := ValidateIndexName(registry)
+		if err != nil {
+			return "", fmt.Errorf("invalid mirror: %v", err)
+		}
+		if err := validateHostPort(name); err != nil {
+			return "", fmt.Errorf("invalid mirror: registry %s is not valid: %v", registry, err)
+		}
+		registry = name + "="
+		if name == IndexName {
+			registry = ""
+		}
+	}
 	uri, err := url.Parse(val)
 	if err != nil {
 		return "", fmt.Errorf("invalid mirror: %q is not a valid URI", val)
@@ -333,7 +415,16 @@ func ValidateMirror(val string) (string, error) {
 		uri.User = url.UserPassword(uri.User.Username(), "xxxxx")
 		return "", fmt.Errorf("invalid mirror: username/password not allowed in URI %q", uri)
 	}
-	return strings.TrimSuffix(val, "/") + "/", nil
+	return registry + strings.TrimSuffix(val, "/") + "/", nil
+}
+
+// splitMirror splits a mirror into the registry it mirrors, empty for the
+// official index, and its URI
+func splitMirror(val string) (string, string) {
+	if i := strings.Index(val, "="); i > 0 && !strings.Contains(val[:i], "://") {
+		return val[:i], val[i+1:]
+	}
+	return "", val
 }
 
 // ValidateIndexName validates an index name.
@@ -396,7 +487,7 @@ func newIndexInfo(config *serviceConfig, indexName string) (*registrytypes.Index
 	// Construct a non-configured index info.
 	index := &registrytypes.IndexInfo{
 		Name:     indexName,
-		Mirrors:  make([]string, 0),
+		Mirrors:  config.mirrorsOf(indexName),
 		Official: false,
 	}
 	index.Secure = isSecureIndex(config, indexName)
@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"encoding/json"
 	"reflect"
 	"sort"
 	"strings"
@@ -140,6 +141,9 @@ func TestValidateMirror(t *testing.T) {
 		"https://127.0.0.1",
 		"http://127.0.0.1:5000",
 		"https://127.0.0.1:5000",
+		"quay.io=https://mirror-1.com",
+		"localhost:5000=http://127.0.0.1:5001",
+		"docker.io=https://mirror-1.com",
 	}
 
 	invalid := []string{
@@ -156,6 +160,10 @@ func TestValidateMirror(t *testing.T) {
 		"https://mirror-1.com/v1/",
 		"https://mirror-1.com/v1/#",
 		"https://mirror-1.com?q",
+		"quay.io=",
+		"quay.io=ftp://mirror-1.com",
+		"quay.io:99999=https://mirror-1.com",
+		"-quay.io=https://mirror-1.com",
 	}
 
 	for _, address := range valid {
@@ -171,6 +179,68 @@ func TestValidateMirror(t *testing.T) {
 	}
 }
 
+func TestValidateMirrorRegistry(t *testing.T) {
+	testCases := []struct {
+		mirror string
+		expect string
+	}{
+		{"https://mirror-1.com", "https://mirror-1.com/"},
+		{"https://mirror-1.com/", "https://mirror-1.com/"},
+		{"quay.io=https://mirror-1.com", "quay.io=https://mirror-1.com/"},
+		{"localhost:5000=http://127.0.0.1:5001/", "localhost:5000=http://127.0.0.1:5001/"},
+		{"docker.io=https://mirror-1.com", "https://mirror-1.com/"},
+		{"index.docker.io=https://mirror-1.com", "https://mirror-1.com/"},
+	}
+	for _, testCase := range testCases {
+		ret, err := ValidateMirror(testCase.mirror)
+		assert.NoError(t, err, testCase.mirror)
+		assert.Equal(t, testCase.expect, ret, testCase.mirror)
+	}
+}
+
+func TestRegistryMirrorsUnmarshalJSON(t *testing.T) {
+	var options ServiceOptions
+	err := json.Unmarshal([]byte(`{"registry-mirrors": ["https://mirror-1.com"]}`), &options)
+	assert.NoError(t, err)
+	assert.Equal(t, RegistryMirrors{"https://mirror-1.com"}, options.Mirrors)
+
+	err = json.Unmarshal([]byte(`{"registry-mirrors": {"quay.io": ["https://mirror-2.com", "https://mirror-3.com"], "docker.io": ["https://mirror-1.com"]}}`), &options)
+	assert.NoError(t, err)
+	assert.Equal(t, RegistryMirrors{"docker.io=https://mirror-1.com", "quay.io=https://mirror-2.com", "quay.io=https://mirror-3.com"}, options.Mirrors)
+
+	err = json.Unmarshal([]byte(`{"registry-mirrors": "https://mirror-1.com"}`), &options)
+	assert.EqualError(t, err, "registry-mirrors must be a list of mirrors or an object with the mirrors of each registry")
+}
+
+func TestLoadRegistryMirrors(t *testing.T) {
+	config, err := newServiceConfig(ServiceOptions{
+		Mirrors: []string{
+			"https://mirror-1.com",
+			"quay.io=https://mirror-2.com",
+			"quay.io=https://mirror-3.com",
+			"quay.io=https://mirror-2.com/",
+			"example.com:5000=http://mirror-4.com",
+		},
+		InsecureRegistries: []string{"example.com:5000"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://mirror-1.com/"}, config.Mirrors)
+	assert.Equal(t, map[string][]string{
+		"quay.io":          {"https://mirror-2.com/", "https://mirror-3.com/"},
+		"example.com:5000": {"http://mirror-4.com/"},
+	}, config.RegistryMirrors)
+	assert.Equal(t, []string{"https://mirror-1.com/"}, config.IndexConfigs[IndexName].Mirrors)
+	assert.Equal(t, []string{"http://mirror-4.com/"}, config.IndexConfigs["example.com:5000"].Mirrors)
+
+	index, err := newIndexInfo(config, "quay.io")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://mirror-2.com/", "https://mirror-3.com/"}, index.Mirrors)
+
+	assert.NoError(t, config.LoadMirrors(nil))
+	assert.Empty(t, config.RegistryMirrors)
+	assert.Empty(t, config.IndexConfigs["example.com:5000"].Mirrors)
+}
+
 func TestLoadInsecureRegistries(t *testing.T) {
 	testCases := []struct {
 		registries []string
@@ -282,6 +352,12 @@ func TestNewServiceConfig(t *testing.T) {
 			},
 			"",
 		},
+		{
+			ServiceOptions{
+				Mirrors: []string{"example.com:99999=http://example.com:5000"},
+			},
+			`invalid mirror: registry example.com:99999 is not valid: invalid port "99999"`,
+		},
 		{
 			ServiceOptions{
 				InsecureRegistries: []string{"[fe80::]/64"},
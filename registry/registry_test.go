@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -698,6 +699,47 @@ func TestMirrorEndpointLookup(t *testing.T) {
 	}
 }
 
+func TestRegistryMirrorEndpointLookup(t *testing.T) {
+	cfg, err := makeServiceConfig([]string{
+		"https://my.mirror",
+		"example.com=https://mirror-1.example.com",
+		"example.com=http://mirror-2.example.com",
+	}, []string{"example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := DefaultService{config: cfg}
+
+	pullAPIEndpoints, err := s.LookupPullEndpoints("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, endpoint := range pullAPIEndpoints {
+		if endpoint.Version != APIVersion2 {
+			continue
+		}
+		urls = append(urls, endpoint.URL.String())
+		if endpoint.Mirror != (endpoint.URL.Host != "example.com") {
+			t.Fatalf("Unexpected Mirror %t for endpoint %s", endpoint.Mirror, endpoint.URL)
+		}
+	}
+	expected := []string{"https://mirror-1.example.com/", "http://mirror-2.example.com/", "https://example.com", "http://example.com"}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Fatalf("Expected pull endpoints %v, got %v", expected, urls)
+	}
+
+	pushAPIEndpoints, err := s.LookupPushEndpoints("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range pushAPIEndpoints {
+		if endpoint.Mirror {
+			t.Fatalf("Push endpoint should not contain mirror %s", endpoint.URL)
+		}
+	}
+}
+
 func TestPushRegistryTag(t *testing.T) {
 	r := spawnTestRegistrySession(t)
 	repoRef, err := reference.ParseNormalizedNamed(REPO)
@@ -23,6 +23,7 @@ var ErrRestartCanceled = errors.New("restart canceled")
 type RestartManager interface {
 	Cancel() error
 	ShouldRestart(exitCode uint32, hasBeenManuallyStopped bool, executionDuration time.Duration) (bool, chan error, error)
+	ShouldRestartUnhealthy() bool
 }
 
 type restartManager struct {
@@ -87,9 +88,8 @@ func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped
 		restart = true
 	case rm.policy.IsUnlessStopped() && !hasBeenManuallyStopped:
 		restart = true
-	case rm.policy.IsOnFailure():
-		// the default value of 0 for MaximumRetryCount means that we will not enforce a maximum count
-		if max := rm.policy.MaximumRetryCount; max == 0 || rm.restartCount < max {
+	case rm.policy.IsOnFailure(), rm.policy.IsOnUnhealthy():
+		if rm.canRetry() {
 			restart = exitCode != 0
 		}
 	}
@@ -122,6 +122,25 @@ func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped
 	return true, ch, nil
 }
 
+// ShouldRestartUnhealthy returns whether a container that has become
+// unhealthy should be killed so that it is restarted by ShouldRestart.
+func (rm *restartManager) ShouldRestartUnhealthy() bool {
+	rm.Lock()
+	defer rm.Unlock()
+	if rm.canceled || rm.active || !rm.policy.IsOnUnhealthy() {
+		return false
+	}
+	return rm.canRetry()
+}
+
+// canRetry returns whether the maximum retry count of the policy has not been
+// reached yet. Must be called with the lock held.
+func (rm *restartManager) canRetry() bool {
+	// the default value of 0 for MaximumRetryCount means that we will not enforce a maximum count
+	max := rm.policy.MaximumRetryCount
+	return max == 0 || rm.restartCount < max
+}
+
 func (rm *restartManager) Cancel() error {
 	rm.Do(func() {
 		rm.Lock()
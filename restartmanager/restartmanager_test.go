@@ -34,3 +34,39 @@ func TestRestartManagerTimeoutReset(t *testing.T) {
 		t.Fatalf("restart manager should have a timeout of 100 ms but has %s", rm.timeout)
 	}
 }
+
+func TestRestartManagerOnUnhealthy(t *testing.T) {
+	rm := New(container.RestartPolicy{Name: "on-unhealthy", MaximumRetryCount: 1}, 0).(*restartManager)
+	if !rm.ShouldRestartUnhealthy() {
+		t.Fatal("unhealthy container should be restarted")
+	}
+	should, _, err := rm.ShouldRestart(0, false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if should {
+		t.Fatal("container exiting with a zero exit code should not be restarted")
+	}
+	should, wait, err := rm.ShouldRestart(137, true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !should {
+		t.Fatal("container should be restarted")
+	}
+	if err := <-wait; err != nil {
+		t.Fatal(err)
+	}
+	if rm.ShouldRestartUnhealthy() {
+		t.Fatal("unhealthy container should not be restarted once the maximum retry count is reached")
+	}
+}
+
+func TestRestartManagerUnhealthyPolicy(t *testing.T) {
+	for _, name := range []string{"", "no", "always", "unless-stopped", "on-failure"} {
+		rm := New(container.RestartPolicy{Name: name}, 0)
+		if rm.ShouldRestartUnhealthy() {
+			t.Fatalf("unhealthy container should not be restarted with restart policy %q", name)
+		}
+	}
+}
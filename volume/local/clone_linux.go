@@ -0,0 +1,10 @@
+package local
+
+import "github.com/docker/docker/daemon/graphdriver/copy"
+
+// copyVolumeData copies the content of the src directory into dst. Files
+// are cloned if the filesystem supports reflinks (e.g. btrfs or xfs), and
+// copied otherwise.
+func copyVolumeData(src, dst string) error {
+	return copy.DirCopy(src, dst, copy.Content)
+}
@@ -0,0 +1,10 @@
+// +build !linux
+
+package local
+
+import "github.com/docker/docker/pkg/chrootarchive"
+
+// copyVolumeData copies the content of the src directory into dst.
+func copyVolumeData(src, dst string) error {
+	return chrootarchive.NewArchiver(nil).CopyWithTar(src, dst)
+}
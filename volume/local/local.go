@@ -13,6 +13,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/docker/docker/daemon/graphdriver/quota"
 	"github.com/docker/docker/daemon/names"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/mount"
@@ -60,6 +61,16 @@ func New(scope string, rootIDs idtools.IDPair) (*Root, error) {
 		rootIDs: rootIDs,
 	}
 
+	// Try to enable project quota support for the `size` option, which is
+	// only available if the volumes directory is on xfs mounted with the
+	// 'pquota' option. NewControl checks the backing filesystem before
+	// touching the volumes directory.
+	if quotaCtl, err := quota.NewControl(rootDirectory); err == nil {
+		r.quotaCtl = quotaCtl
+	} else {
+		logrus.Debugf("project quota not supported for local volumes: %v", err)
+	}
+
 	dirs, err := ioutil.ReadDir(rootDirectory)
 	if err != nil {
 		return nil, err
@@ -116,6 +127,8 @@ type Root struct {
 	path    string
 	volumes map[string]*localVolume
 	rootIDs idtools.IDPair
+	// quotaCtl is nil if project quotas are not supported
+	quotaCtl *quota.Control
 }
 
 // List lists all the volumes
@@ -149,6 +162,16 @@ func (e alreadyExistsError) Error() string {
 
 func (e alreadyExistsError) Conflict() {}
 
+type volumeInUseError struct {
+	name string
+}
+
+func (e volumeInUseError) Error() string {
+	return "local volume " + e.name + " has active mounts"
+}
+
+func (e volumeInUseError) Conflict() {}
+
 type systemError struct {
 	err error
 }
@@ -163,6 +186,38 @@ func (e systemError) Cause() error {
 	return e.err
 }
 
+// Clone creates a new volume with the given options and copies the data of
+// the source volume into it. The data is copied without holding the lock
+// of the driver, as copying large volumes can take a long time.
+func (r *Root) Clone(name, from string, opts map[string]string) (volume.Volume, error) {
+	r.m.Lock()
+	src, srcExists := r.volumes[from]
+	_, exists := r.volumes[name]
+	r.m.Unlock()
+
+	if !srcExists {
+		return nil, errors.Wrapf(ErrNotFound, "error while cloning volume %s", from)
+	}
+	if exists {
+		return nil, alreadyExistsError{filepath.Dir(r.DataPath(name))}
+	}
+	if src.needsMount() {
+		return nil, validationError(fmt.Sprintf("volume %s is backed by a mount and cannot be cloned", from))
+	}
+
+	v, err := r.Create(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := copyVolumeData(src.Path(), v.Path()); err != nil {
+		if rmErr := r.Remove(v); rmErr != nil {
+			logrus.WithError(rmErr).WithField("volume", name).Warn("error removing volume after failed clone")
+		}
+		return nil, errors.Wrapf(systemError{err}, "error while cloning volume %s", from)
+	}
+	return v, nil
+}
+
 // Create creates a new volume.Volume with the provided name, creating
 // the underlying directory tree required for this volume in the
 // process.
@@ -180,17 +235,18 @@ func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error
 	}
 
 	path := r.DataPath(name)
-	if err := idtools.MkdirAllAndChown(path, 0755, r.rootIDs); err != nil {
+	volumePath := filepath.Dir(path)
+	if err := idtools.MkdirAllAndChown(volumePath, 0755, r.rootIDs); err != nil {
 		if os.IsExist(err) {
-			return nil, alreadyExistsError{filepath.Dir(path)}
+			return nil, alreadyExistsError{volumePath}
 		}
-		return nil, errors.Wrapf(systemError{err}, "error while creating volume path '%s'", path)
+		return nil, errors.Wrapf(systemError{err}, "error while creating volume path '%s'", volumePath)
 	}
 
 	var err error
 	defer func() {
 		if err != nil {
-			os.RemoveAll(filepath.Dir(path))
+			os.RemoveAll(volumePath)
 		}
 	}()
 
@@ -204,20 +260,82 @@ func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error
 		if err = setOpts(v, opts); err != nil {
 			return nil, err
 		}
-		var b []byte
-		b, err = json.Marshal(v.opts)
-		if err != nil {
+		if err = saveOpts(v); err != nil {
 			return nil, err
 		}
-		if err = ioutil.WriteFile(filepath.Join(filepath.Dir(path), "opts.json"), b, 600); err != nil {
-			return nil, errors.Wrap(systemError{err}, "error while persisting volume options")
-		}
+	}
+
+	// The quota is set before the data directory is created, so that the
+	// data directory inherits the project id of the volume directory.
+	if err = r.setQuota(v, volumePath); err != nil {
+		return nil, err
+	}
+	if err = idtools.MkdirAllAndChown(path, 0755, r.rootIDs); err != nil {
+		return nil, errors.Wrapf(systemError{err}, "error while creating volume path '%s'", path)
 	}
 
 	r.volumes[name] = v
 	return v, nil
 }
 
+// saveOpts persists the options of the volume, so that they are restored
+// when the driver is initialized again.
+func saveOpts(v *localVolume) error {
+	optsFilePath := filepath.Join(filepath.Dir(v.path), "opts.json")
+	if v.opts == nil {
+		if err := os.Remove(optsFilePath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(systemError{err}, "error while persisting volume options")
+		}
+		return nil
+	}
+	b, err := json.Marshal(v.opts)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(optsFilePath, b, 600); err != nil {
+		return errors.Wrap(systemError{err}, "error while persisting volume options")
+	}
+	return nil
+}
+
+// Update replaces the options of the given volume. The options of a volume
+// can only be changed while it is not mounted.
+func (r *Root) Update(v volume.Volume, opts map[string]string) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	lv, ok := v.(*localVolume)
+	if !ok {
+		return systemError{errors.Errorf("unknown volume type %T", v)}
+	}
+
+	lv.m.Lock()
+	defer lv.m.Unlock()
+
+	if lv.active.count > 0 {
+		return volumeInUseError{lv.name}
+	}
+
+	updated := &localVolume{
+		driverName: lv.driverName,
+		name:       lv.name,
+		path:       lv.path,
+	}
+	if len(opts) != 0 {
+		if err := setOpts(updated, opts); err != nil {
+			return err
+		}
+	}
+	if err := r.updateQuota(lv, updated); err != nil {
+		return err
+	}
+	if err := saveOpts(updated); err != nil {
+		return err
+	}
+	lv.opts = updated.opts
+	return nil
+}
+
 // Remove removes the specified volume and all underlying data. If the
 // given volume does not belong to this driver and an error is
 // returned. The volume is reference counted, if all references are
@@ -344,15 +462,15 @@ func (v *localVolume) CachedPath() string {
 func (v *localVolume) Mount(id string) (string, error) {
 	v.m.Lock()
 	defer v.m.Unlock()
-	if v.opts != nil {
-		if !v.active.mounted {
-			if err := v.mount(); err != nil {
-				return "", systemError{err}
-			}
-			v.active.mounted = true
+	if v.needsMount() && !v.active.mounted {
+		if err := v.mount(); err != nil {
+			return "", systemError{err}
 		}
-		v.active.count++
+		v.active.mounted = true
 	}
+	// the mounts are counted for all volumes, so that the options of a
+	// volume in use can't be changed
+	v.active.count++
 	return v.path, nil
 }
 
@@ -366,7 +484,7 @@ func (v *localVolume) Unmount(id string) error {
 	// Essentially docker doesn't care if this fails, it will send an error, but
 	// ultimately there's nothing that can be done. If we don't decrement the count
 	// this volume can never be removed until a daemon restart occurs.
-	if v.opts != nil {
+	if v.active.count > 0 {
 		v.active.count--
 	}
 
@@ -378,7 +496,7 @@ func (v *localVolume) Unmount(id string) error {
 }
 
 func (v *localVolume) unmount() error {
-	if v.opts != nil {
+	if v.needsMount() {
 		if err := mount.Unmount(v.path); err != nil {
 			if mounted, mErr := mount.Mounted(v.path); mounted || mErr != nil {
 				return errors.Wrapf(systemError{err}, "error while unmounting volume path '%s'", v.path)
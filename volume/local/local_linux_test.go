@@ -0,0 +1,136 @@
+// +build linux
+
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/quota"
+	"github.com/docker/docker/daemon/graphdriver/vfs"
+	"github.com/docker/docker/pkg/idtools"
+	"golang.org/x/sys/unix"
+)
+
+// mountTestXFS mounts a loopback xfs filesystem with project quotas enabled
+// and returns its mountpoint, or skips the test if it is not possible.
+func mountTestXFS(t *testing.T) (string, func()) {
+	if os.Getuid() != 0 {
+		t.Skip("mounting a filesystem requires root")
+	}
+	if _, err := exec.LookPath("mkfs.xfs"); err != nil {
+		t.Skipf("mkfs.xfs not installed: %v", err)
+	}
+
+	// create a sparse image
+	imageFile, err := ioutil.TempFile("", "local-volume-xfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imageFileName := imageFile.Name()
+	err = imageFile.Truncate(300 * 1024 * 1024)
+	imageFile.Close()
+	if err != nil {
+		os.Remove(imageFileName)
+		t.Fatal(err)
+	}
+
+	mountpoint, err := ioutil.TempDir("", "local-volume-xfs-mountpoint")
+	if err != nil {
+		os.Remove(imageFileName)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		os.RemoveAll(mountpoint)
+		os.Remove(imageFileName)
+	}
+
+	if out, err := exec.Command("mkfs.xfs", imageFileName).CombinedOutput(); err != nil {
+		cleanup()
+		t.Fatalf("error formatting image: %v: %s", err, out)
+	}
+	// for ease of setting up loopback device, we use os/exec rather than unix.Mount
+	if out, err := exec.Command("mount", "-o", "loop,pquota", imageFileName, mountpoint).CombinedOutput(); err != nil {
+		cleanup()
+		t.Skipf("skipping the test because mount failed: %v: %s", err, out)
+	}
+	return mountpoint, func() {
+		if err := unix.Unmount(mountpoint, 0); err != nil {
+			t.Error(err)
+		}
+		cleanup()
+	}
+}
+
+func TestQuotaSharedWithGraphDriver(t *testing.T) {
+	mountpoint, cleanup := mountTestXFS(t)
+	defer cleanup()
+
+	r, err := New(filepath.Join(mountpoint, "volumes"), idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.quotaCtl == nil {
+		t.Skip("project quotas are not supported")
+	}
+	if _, err := r.Create("test", map[string]string{"size": "10m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a vfs layer on the same filesystem gets a project id of its own
+	vfsHome := filepath.Join(mountpoint, "vfs")
+	driver, err := vfs.Init(vfsHome, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Cleanup()
+	if err := driver.CreateReadWrite("layer", "", &graphdriver.CreateOpts{StorageOpt: map[string]string{"size": "20m"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var q quota.Quota
+	if err := r.quotaCtl.GetQuota(filepath.Join(r.path, "test"), &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Size != 10*1024*1024 {
+		t.Fatalf("expected the volume quota to be 10m, got %d", q.Size)
+	}
+
+	layerCtl, err := quota.NewControl(filepath.Join(vfsHome, "dir"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := layerCtl.GetQuota(filepath.Join(vfsHome, "dir", "layer"), &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Size != 20*1024*1024 {
+		t.Fatalf("expected the layer quota to be 20m, got %d", q.Size)
+	}
+}
+
+func TestNoQuotaControlOutsideXFS(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "local-volume-test-noquota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	if fsMagic, err := graphdriver.GetFSMagic(rootDir); err != nil || fsMagic == graphdriver.FsMagicXfs {
+		t.Skip("the temporary directory is on xfs")
+	}
+
+	r, err := New(rootDir, idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.quotaCtl != nil {
+		t.Fatal("expected no quota support outside of xfs")
+	}
+	if _, err := os.Stat(filepath.Join(r.path, "backingFsBlockDev")); !os.IsNotExist(err) {
+		t.Fatalf("expected no backing filesystem device node, got: %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/docker/docker/daemon/graphdriver/quota"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/mount"
 )
@@ -286,6 +287,197 @@ func TestCreateWithOpts(t *testing.T) {
 	}
 }
 
+func TestCreateWithSize(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	rootDir, err := ioutil.TempDir("", "local-volume-test-size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	r, err := New(rootDir, idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Create("test", map[string]string{"size": "notasize"}); err == nil {
+		t.Fatal("expected invalid size to cause error")
+	}
+	if _, err := r.Create("test", map[string]string{"size": "10m", "device": "tmpfs", "type": "tmpfs"}); err == nil {
+		t.Fatal("expected size with mount options to cause error")
+	}
+
+	vol, err := r.Create("test", map[string]string{"size": "10m"})
+	if r.quotaCtl == nil {
+		if err == nil {
+			t.Fatal("expected size to cause error without project quota support")
+		}
+		if _, err := os.Stat(filepath.Join(rootDir, volumesPathName, "test")); !os.IsNotExist(err) {
+			t.Fatalf("expected volume directory to be cleaned up, got: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vol.(*localVolume)
+	if v.needsMount() {
+		t.Fatal("expected volume with only a size to not need a mount")
+	}
+
+	var q quota.Quota
+	if err := r.quotaCtl.GetQuota(filepath.Dir(v.path), &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Size != 10*1024*1024 {
+		t.Fatalf("expected quota of 10m, got: %d", q.Size)
+	}
+	if _, err := v.Mount("1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Unmount("1234"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateOpts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	rootDir, err := ioutil.TempDir("", "local-volume-test-update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	r, err := New(rootDir, idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol, err := r.Create("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vol.(*localVolume)
+
+	if err := r.Update(v, map[string]string{"invalidopt": "notsupported"}); err == nil {
+		t.Fatal("expected invalid opt to cause error")
+	}
+	if err := r.Update(v, map[string]string{"size": "10m"}); err == nil {
+		t.Fatal("expected adding a size to cause error")
+	}
+
+	if _, err := v.Mount("1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Update(v, nil); err == nil {
+		t.Fatal("expected update of volume in use to cause error")
+	}
+	if err := v.Unmount("1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := map[string]string{"device": "tmpfs", "type": "tmpfs", "o": "size=1m"}
+	if err := r.Update(v, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !v.needsMount() || v.opts.MountType != "tmpfs" {
+		t.Fatalf("expected volume options to be updated, got: %v", v.opts)
+	}
+
+	if _, err := v.Mount("1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Update(v, nil); err == nil {
+		t.Fatal("expected update of mounted volume to cause error")
+	}
+	if err := v.Unmount("1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the options are restored on restart
+	r, err = New(rootDir, idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v.opts, r.volumes["test"].opts) {
+		t.Fatal("missing volume options on restart")
+	}
+
+	if err := r.Update(r.volumes["test"], nil); err != nil {
+		t.Fatal(err)
+	}
+	r, err = New(rootDir, idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.volumes["test"].opts != nil {
+		t.Fatalf("expected opts to be nil, got: %v", r.volumes["test"].opts)
+	}
+}
+
+func TestClone(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "local-volume-test-clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	r, err := New(rootDir, idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Clone("clone", "src", nil); err == nil {
+		t.Fatal("expected missing source volume to cause error")
+	}
+
+	src, err := r.Create("src", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(src.Path(), "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src.Path(), "dir", "file"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	clone, err := r.Clone("clone", "src", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.(*localVolume).opts != nil {
+		t.Fatalf("expected clone opts to be nil, got: %v", clone.(*localVolume).opts)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(clone.Path(), "dir", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "data" {
+		t.Fatalf("expected cloned file content to be %q, got %q", "data", b)
+	}
+
+	// the clone is independent from the source
+	if err := ioutil.WriteFile(filepath.Join(clone.Path(), "dir", "file"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(src.Path(), "dir", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "data" {
+		t.Fatalf("expected source file to be unchanged, got %q", b)
+	}
+
+	if _, err := r.Clone("clone", "src", nil); err == nil {
+		t.Fatal("expected cloning into an existing volume to cause error")
+	}
+}
+
 func TestRealodNoOpts(t *testing.T) {
 	rootDir, err := ioutil.TempDir("", "volume-test-reload-no-opts")
 	if err != nil {
@@ -16,7 +16,9 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/docker/docker/daemon/graphdriver/quota"
 	"github.com/docker/docker/pkg/mount"
+	units "github.com/docker/go-units"
 )
 
 var (
@@ -26,6 +28,7 @@ var (
 		"type":   true, // specify the filesystem type for mount, e.g. nfs
 		"o":      true, // generic mount options
 		"device": true, // device to mount from
+		"size":   true, // quota size limit, requires project quota support
 	}
 )
 
@@ -33,10 +36,11 @@ type optsConfig struct {
 	MountType   string
 	MountOpts   string
 	MountDevice string
+	Quota       quota.Quota
 }
 
 func (o *optsConfig) String() string {
-	return fmt.Sprintf("type='%s' device='%s' o='%s'", o.MountType, o.MountDevice, o.MountOpts)
+	return fmt.Sprintf("type='%s' device='%s' o='%s' size='%d'", o.MountType, o.MountDevice, o.MountOpts, o.Quota.Size)
 }
 
 // scopedPath verifies that the path where the volume is located
@@ -68,9 +72,61 @@ func setOpts(v *localVolume, opts map[string]string) error {
 		MountOpts:   opts["o"],
 		MountDevice: opts["device"],
 	}
+	if val, ok := opts["size"]; ok {
+		if v.needsMount() {
+			return validationError("the size option cannot be used with mount options")
+		}
+		size, err := units.RAMInBytes(val)
+		if err != nil {
+			return validationError(fmt.Sprintf("invalid size %q: %v", val, err))
+		}
+		v.opts.Quota.Size = uint64(size)
+	}
 	return nil
 }
 
+// needsMount returns true if the volume is backed by a mount that is set up
+// when the volume is mounted.
+func (v *localVolume) needsMount() bool {
+	if v.opts == nil {
+		return false
+	}
+	return v.opts.MountDevice != "" || v.opts.MountType != "" || v.opts.MountOpts != ""
+}
+
+// setQuota sets the quota requested by the options of the volume on the
+// given directory.
+func (r *Root) setQuota(v *localVolume, dir string) error {
+	if v.opts == nil || v.opts.Quota.Size == 0 {
+		return nil
+	}
+	if r.quotaCtl == nil {
+		return validationError("the size option is supported only for volumes over xfs with 'pquota' mount option")
+	}
+	return errors.Wrap(r.quotaCtl.SetQuota(dir, v.opts.Quota), "error while setting volume quota")
+}
+
+// updateQuota applies the size of the updated volume to the quota of the
+// volume. The size can only be changed on volumes which were created with a
+// size, as the data of other volumes is not accounted to a quota project.
+func (r *Root) updateQuota(v, updated *localVolume) error {
+	var size uint64
+	if updated.opts != nil {
+		size = updated.opts.Quota.Size
+	}
+	if v.opts == nil || v.opts.Quota.Size == 0 {
+		if size == 0 {
+			return nil
+		}
+		return validationError("the size option can only be changed on volumes created with a size")
+	}
+	if r.quotaCtl == nil {
+		return validationError("the size option is supported only for volumes over xfs with 'pquota' mount option")
+	}
+	err := r.quotaCtl.SetQuota(filepath.Dir(v.path), quota.Quota{Size: size})
+	return errors.Wrap(err, "error while setting volume quota")
+}
+
 func (v *localVolume) mount() error {
 	if v.opts.MountDevice == "" {
 		return fmt.Errorf("missing device in volume options")
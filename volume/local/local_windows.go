@@ -32,6 +32,18 @@ func setOpts(v *localVolume, opts map[string]string) error {
 	return nil
 }
 
+func (v *localVolume) needsMount() bool {
+	return false
+}
+
+func (r *Root) setQuota(v *localVolume, dir string) error {
+	return nil
+}
+
+func (r *Root) updateQuota(v, updated *localVolume) error {
+	return nil
+}
+
 func (v *localVolume) mount() error {
 	return nil
 }
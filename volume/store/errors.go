@@ -11,6 +11,8 @@ const (
 	errNoSuchVolume notFoundError = "no such volume"
 	// errNameConflict is a typed error returned on create when a volume exists with the given name, but for a different driver
 	errNameConflict conflictError = "volume name must be unique"
+	// errUpdateNotSupported is a typed error returned when trying to change the options of a volume whose driver does not support it
+	errUpdateNotSupported notImplementedError = "volume driver does not support updating volume options"
 )
 
 type conflictError string
@@ -28,6 +30,14 @@ func (e notFoundError) Error() string {
 
 func (notFoundError) NotFound() {}
 
+type notImplementedError string
+
+func (e notImplementedError) Error() string {
+	return string(e)
+}
+
+func (notImplementedError) NotImplemented() {}
+
 // OpErr is the error type returned by functions in the store package. It describes
 // the operation, volume name, and error.
 type OpErr struct {
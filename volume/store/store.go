@@ -269,8 +269,23 @@ func (s *VolumeStore) list() ([]volume.Volume, []string, error) {
 
 // CreateWithRef creates a volume with the given name and driver and stores the ref
 // This ensures there's no race between creating a volume and then storing a reference.
+//
+// If the volume is created as a copy of another volume by a driver which
+// supports it, a reference is held on the source volume while the volume is
+// created, so that the source can't be removed in the meantime. The
+// reference is taken before the lock on the name of the new volume, so that
+// locks are never nested.
 func (s *VolumeStore) CreateWithRef(name, driverName, ref string, opts, labels map[string]string) (volume.Volume, error) {
 	name = normalizeVolumeName(name)
+	if from := opts[volume.CloneFromOption]; from != "" && supportsClone(driverName) {
+		cloneRef := "clone:" + name
+		src, err := s.GetWithRef(from, driverName, cloneRef)
+		if err != nil {
+			return nil, &OpErr{Err: errors.Wrap(err, "error looking up source volume"), Name: name, Op: "create"}
+		}
+		defer s.Dereference(src, cloneRef)
+	}
+
 	s.locks.Lock(name)
 	defer s.locks.Unlock(name)
 
@@ -286,6 +301,28 @@ func (s *VolumeStore) CreateWithRef(name, driverName, ref string, opts, labels m
 	return v, nil
 }
 
+// supportsClone returns whether the driver can create volumes as a copy of
+// another volume. Other drivers are passed the volume.CloneFromOption option
+// as is.
+func supportsClone(driverName string) bool {
+	vd, err := volumedrivers.GetDriver(driverName)
+	if err != nil {
+		return false
+	}
+	_, ok := vd.(volume.CloneDriver)
+	return ok
+}
+
+func withoutOption(opts map[string]string, key string) map[string]string {
+	filtered := make(map[string]string, len(opts))
+	for k, v := range opts {
+		if k != key {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
 // Create creates a volume with the given name and driver.
 // This is just like CreateWithRef() except we don't store a reference while holding the lock.
 func (s *VolumeStore) Create(name, driverName string, opts, labels map[string]string) (volume.Volume, error) {
@@ -407,7 +444,14 @@ func (s *VolumeStore) create(name, driverName string, opts, labels map[string]st
 	if v, _ := vd.Get(name); v != nil {
 		return v, nil
 	}
-	v, err = vd.Create(name, opts)
+	if cd, ok := vd.(volume.CloneDriver); ok && opts[volume.CloneFromOption] != "" {
+		from := opts[volume.CloneFromOption]
+		// the source volume is not an option of the new volume
+		opts = withoutOption(opts, volume.CloneFromOption)
+		v, err = cd.Clone(name, from, opts)
+	} else {
+		v, err = vd.Create(name, opts)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -558,6 +602,61 @@ func lookupVolume(driverName, volumeName string) (volume.Volume, error) {
 	return v, nil
 }
 
+// Update changes the labels and the driver options of the volume with the
+// given name. Nil labels or options are left unchanged. Options can only be
+// changed if the driver implements volume.UpdateDriver, and only while the
+// volume is not in use.
+func (s *VolumeStore) Update(name string, opts, labels map[string]string) (volume.Volume, error) {
+	name = normalizeVolumeName(name)
+	s.locks.Lock(name)
+	defer s.locks.Unlock(name)
+
+	v, err := s.getVolume(name)
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "update"}
+	}
+
+	vd, err := volumedrivers.GetDriver(v.DriverName())
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: v.DriverName(), Op: "update"}
+	}
+
+	meta, err := s.getMeta(name)
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "update"}
+	}
+	meta.Name = name
+	meta.Driver = vd.Name()
+
+	if opts != nil {
+		if s.hasRef(name) {
+			return nil, &OpErr{Err: errVolumeInUse, Name: name, Op: "update", Refs: s.getRefs(name)}
+		}
+		ud, ok := vd.(volume.UpdateDriver)
+		if !ok {
+			return nil, &OpErr{Err: errUpdateNotSupported, Name: name, Op: "update"}
+		}
+		logrus.Debugf("Updating volume options: driver %s, name %s", vd.Name(), name)
+		if err := ud.Update(unwrapVolume(v), opts); err != nil {
+			return nil, &OpErr{Err: err, Name: name, Op: "update"}
+		}
+		meta.Options = opts
+	}
+	if labels != nil {
+		meta.Labels = labels
+	}
+
+	if err := s.setMeta(name, meta); err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "update"}
+	}
+	s.globalLock.Lock()
+	s.labels[name] = meta.Labels
+	s.options[name] = meta.Options
+	s.globalLock.Unlock()
+
+	return volumeWrapper{unwrapVolume(v), meta.Labels, vd.Scope(), meta.Options}, nil
+}
+
 // Remove removes the requested volume. A volume is not removed if it has any refs
 func (s *VolumeStore) Remove(v volume.Volume) error {
 	name := normalizeVolumeName(v.Name())
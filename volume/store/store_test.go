@@ -4,9 +4,11 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/docker/docker/volume"
 	"github.com/docker/docker/volume/drivers"
 	volumetestutils "github.com/docker/docker/volume/testutils"
 )
@@ -232,3 +234,119 @@ func TestDerefMultipleOfSameRef(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestUpdate(t *testing.T) {
+	volumedrivers.Register(volumetestutils.NewFakeDriver("fake"), "fake")
+	defer volumedrivers.Unregister("fake")
+	dir, err := ioutil.TempDir("", "test-update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Update("fake1", nil, map[string]string{"a": "b"}); !IsNotExist(err) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+
+	if _, err := s.CreateWithRef("fake1", "fake", "fake", map[string]string{"opt": "1"}, map[string]string{"a": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := s.Update("fake1", nil, map[string]string{"a": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dv := v.(volume.DetailedVolume)
+	if dv.Labels()["a"] != "2" {
+		t.Fatalf("Expected updated label, got %v", dv.Labels())
+	}
+	if dv.Options()["opt"] != "1" {
+		t.Fatalf("Expected options to be unchanged, got %v", dv.Options())
+	}
+
+	// the labels are persisted
+	s.Shutdown()
+	s, err = New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+	v, err = s.Get("fake1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := v.(volume.DetailedVolume).Labels(); labels["a"] != "2" {
+		t.Fatalf("Expected updated label after restart, got %v", labels)
+	}
+
+	if _, err := s.CreateWithRef("fake2", "fake", "fake", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Update("fake2", map[string]string{"opt": "2"}, nil); !IsInUse(err) {
+		t.Fatalf("Expected ErrVolumeInUse error, got %v", err)
+	}
+	if _, err := s.Update("fake1", map[string]string{"opt": "2"}, nil); !isErr(err, errUpdateNotSupported) {
+		t.Fatalf("Expected update not supported error, got %v", err)
+	}
+}
+
+func TestCreateFrom(t *testing.T) {
+	volumedrivers.Register(volumetestutils.NewFakeCloneDriver("fake"), "fake")
+	defer volumedrivers.Unregister("fake")
+	volumedrivers.Register(volumetestutils.NewFakeDriver("noclone"), "noclone")
+	defer volumedrivers.Unregister("noclone")
+	dir, err := ioutil.TempDir("", "test-create-from")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Create("fake2", "fake", map[string]string{volume.CloneFromOption: "fake1"}, nil); err == nil {
+		t.Fatal("Expected missing source volume to cause an error")
+	}
+
+	src, err := s.Create("fake1", "fake", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Create("fake2", "fake", map[string]string{volume.CloneFromOption: "fake1", "opt": "1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the source volume is not saved as an option of the volume
+	if opts := v.(volume.DetailedVolume).Options(); !reflect.DeepEqual(opts, map[string]string{"opt": "1"}) {
+		t.Fatalf("Expected options to only hold opt, got %v", opts)
+	}
+	meta, err := s.getMeta("fake2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(meta.Options, map[string]string{"opt": "1"}) {
+		t.Fatalf("Expected saved options to only hold opt, got %v", meta.Options)
+	}
+
+	// drivers which don't support cloning get the option as is
+	v, err = s.Create("noclone", "noclone", map[string]string{volume.CloneFromOption: "missing"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts := v.(volume.DetailedVolume).Options(); opts[volume.CloneFromOption] != "missing" {
+		t.Fatalf("Expected the from option to be kept, got %v", opts)
+	}
+
+	// the reference on the source volume is released once the volume is created
+	if refs := s.Refs(src); len(refs) != 0 {
+		t.Fatalf("Expected no references on the source volume, got %v", refs)
+	}
+	if err := s.Remove(src); err != nil {
+		t.Fatal(err)
+	}
+}
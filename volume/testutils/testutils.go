@@ -121,3 +121,22 @@ func (d *FakeDriver) Get(name string) (volume.Volume, error) {
 func (*FakeDriver) Scope() string {
 	return "local"
 }
+
+// FakeCloneDriver is a FakeDriver which can create volumes as a copy of
+// another volume
+type FakeCloneDriver struct {
+	*FakeDriver
+}
+
+// NewFakeCloneDriver creates a new FakeCloneDriver with the specified name
+func NewFakeCloneDriver(name string) volume.Driver {
+	return &FakeCloneDriver{NewFakeDriver(name).(*FakeDriver)}
+}
+
+// Clone initializes a fake volume, if the source volume exists
+func (d *FakeCloneDriver) Clone(name, from string, opts map[string]string) (volume.Volume, error) {
+	if _, exists := d.vols[from]; !exists {
+		return nil, fmt.Errorf("no such volume")
+	}
+	return d.Create(name, opts)
+}
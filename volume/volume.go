@@ -18,6 +18,12 @@ import (
 // implemented in the local package.
 const DefaultDriverName = "local"
 
+// CloneFromOption is the name of the volume option which requests a volume
+// to be created as a copy of an existing volume of the same driver. It is
+// only handled for drivers implementing CloneDriver, and passed to other
+// drivers as is.
+const CloneFromOption = "from"
+
 // Scopes define if a volume has is cluster-wide (global) or local only.
 // Scopes are returned by the volume driver when it is queried for capabilities and then set on a volume
 const (
@@ -42,6 +48,23 @@ type Driver interface {
 	Scope() string
 }
 
+// UpdateDriver is implemented by drivers which can change the options of
+// existing volumes.
+type UpdateDriver interface {
+	Driver
+	// Update replaces the options of the volume with the given options.
+	Update(vol Volume, opts map[string]string) error
+}
+
+// CloneDriver is implemented by drivers which can create a volume as a copy
+// of an existing volume.
+type CloneDriver interface {
+	Driver
+	// Clone makes a new volume with the given name and options, holding a
+	// copy of the data of the volume named from.
+	Clone(name, from string, opts map[string]string) (Volume, error)
+}
+
 // Capability defines a set of capabilities that a driver is able to handle.
 type Capability struct {
 	// Scope is the scope of the driver, `global` or `local`